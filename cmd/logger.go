@@ -2,26 +2,327 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/manyids2/go-tools/tui/models/logger"
 	"github.com/spf13/cobra"
 )
 
-var datadir, fileext string
+var datadir string
+var fileexts, includes, excludes []string
+var recursive bool
+var follow, merge, metrics bool
+var metricKeys, metricPatterns []string
+var metricX string
+var output, query string
+var useIndex bool
 
 // loggerCmd represents the logger command
 var loggerCmd = &cobra.Command{
 	Use:   "logger",
-	Short: "",
-	Long:  ``,
-	Run: func(cmd *cobra.Command, args []string) {
-		m := logger.New(datadir, fileext)
-		go m.SetLogFiles()
-		<-m.Loaded
-		fmt.Println(m)
+	Short: "Query, merge and follow log files",
+	Long: `Discover log files under a directory, parse their lines into records and
+print them, optionally filtered by a query such as
+
+  level>=warn and time>=-2h and msg~"out of memory"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Numeric series found in the records
+		var e *logger.Extractor
+		if metrics {
+			var err error
+			if e, err = logger.NewExtractor(metricKeys, metricPatterns, metricX); err != nil {
+				return err
+			}
+		}
+
+		var m *logger.Logger
+		var q *logger.Query
+		if merge {
+			var idx *logger.Index
+			var err error
+			if m, q, idx, err = openLogger(datadir); err != nil {
+				return err
+			}
+			if err := streamMerged(os.Stdout, m, q, idx, e); err != nil {
+				return err
+			}
+		} else {
+			var err error
+			if m, q, err = loadLogger(datadir, false); err != nil {
+				return err
+			}
+			if e != nil {
+				m.SetMetrics(e)
+			}
+			if err := writeLogger(os.Stdout, m); err != nil {
+				return err
+			}
+		}
+
+		// Stream appended lines until interrupted
+		if follow {
+			f := logger.NewFollower(m)
+			records := f.Subscribe()
+			go f.Run()
+			for r := range records {
+				if q.Match(r) {
+					writeRecord(os.Stdout, m, r)
+				}
+			}
+		}
+		return nil
 	},
 }
 
+// openLogger discovers files under dir and parses --query, updating the
+// index when --index is set
+func openLogger(dir string) (*logger.Logger, *logger.Query, *logger.Index, error) {
+	if err := checkOutput(output); err != nil {
+		return nil, nil, nil, err
+	}
+	q, err := logger.ParseQuery(query)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	m := discoverLogger(dir)
+	if !useIndex {
+		return m, q, nil, nil
+	}
+	idx, err := m.UpdateIndex()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return m, q, idx, nil
+}
+
+// loadLogger discovers files under dir and loads the records matching
+// --query, as one timeline when merged is set
+func loadLogger(dir string, merged bool) (*logger.Logger, *logger.Query, error) {
+	m, q, idx, err := openLogger(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// One timeline across files, or file by file
+	if merged {
+		mergeLogger(m, q, idx, func(r logger.Record) bool {
+			m.Records = append(m.Records, r)
+			return true
+		})
+	} else if idx != nil {
+		m.SetRecordsIndexed(idx, q)
+	} else {
+		m.SetRecords()
+		m.Records = q.Filter(m.Records)
+	}
+	return m, q, nil
+}
+
+// mergeLogger calls fn with the records matching q as one timeline, using
+// idx to skip lines when it is set
+func mergeLogger(m *logger.Logger, q *logger.Query, idx *logger.Index, fn func(logger.Record) bool) {
+	if idx != nil {
+		m.MergeIndexed(idx, q, fn)
+		return
+	}
+	err := m.Merge(func(r logger.Record) bool {
+		if q.Match(r) {
+			return fn(r)
+		}
+		return true
+	})
+	if err != nil {
+		log.Println("Could not merge log files: ", err)
+	}
+}
+
+// Rows of a streamed table aligned together
+const tableBlock = 256
+
+// streamMerged writes the merged records as they are read, without holding
+// them in memory. Structured output is one document per record, then one
+// per metric.
+func streamMerged(w io.Writer, m *logger.Logger, q *logger.Query, idx *logger.Index, e *logger.Extractor) error {
+	var b *logger.SeriesBuilder
+	if e != nil {
+		b = e.NewSeriesBuilder()
+	}
+
+	switch output {
+	case outputJSON, outputYAML:
+		mergeLogger(m, q, idx, func(r logger.Record) bool {
+			writeLine(w, output, r)
+			if b != nil {
+				b.Add(r)
+			}
+			return true
+		})
+		if b != nil {
+			for _, s := range b.Series() {
+				writeLine(w, output, s)
+			}
+		}
+		return nil
+	case outputTable:
+		t := newTable(w)
+		writeFileTable(t, m)
+		fmt.Fprintln(t)
+		fmt.Fprintln(t, "TIME\tLEVEL\tFILE\tLINE\tSOURCE\tMESSAGE")
+		rows := 0
+		mergeLogger(m, q, idx, func(r logger.Record) bool {
+			writeRecordRow(t, m, r)
+			if b != nil {
+				b.Add(r)
+			}
+			if rows++; rows%tableBlock == 0 {
+				t.Flush()
+			}
+			return true
+		})
+		if b != nil {
+			writeMetricTable(t, b.Series())
+		}
+		return t.Flush()
+	default:
+		fmt.Fprintln(w, m)
+		mergeLogger(m, q, idx, func(r logger.Record) bool {
+			writeRecord(w, m, r)
+			if b != nil {
+				b.Add(r)
+			}
+			return true
+		})
+		if b != nil {
+			for _, s := range b.Series() {
+				fmt.Fprintln(w, s)
+			}
+		}
+		return nil
+	}
+}
+
+// discoverLogger finds the log files under dir selected by the flags
+func discoverLogger(dir string) *logger.Logger {
+	m := logger.New(dir, fileexts)
+	m.Recursive = recursive
+	m.Include = includes
+	m.Exclude = excludes
+	go m.SetLogFiles()
+	<-m.Loaded
+	return m
+}
+
+// Everything found by the logger command
+type loggerOutput struct {
+	Datadir string           `json:"datadir" yaml:"datadir"`
+	Query   string           `json:"query,omitempty" yaml:"query,omitempty"`
+	Files   []logger.LogFile `json:"files" yaml:"files"`
+	Records []logger.Record  `json:"records" yaml:"records"`
+	Metrics []logger.Series  `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+}
+
+func writeLogger(w io.Writer, m *logger.Logger) error {
+	switch output {
+	case outputJSON, outputYAML:
+		return writeStructured(w, output, loggerOutput{
+			Datadir: m.Datadir,
+			Query:   query,
+			Files:   m.LogFiles,
+			Records: m.Records,
+			Metrics: m.Metrics,
+		})
+	case outputTable:
+		t := newTable(w)
+		writeFileTable(t, m)
+		fmt.Fprintln(t)
+		fmt.Fprintln(t, "TIME\tLEVEL\tFILE\tLINE\tSOURCE\tMESSAGE")
+		for _, r := range m.Records {
+			writeRecordRow(t, m, r)
+		}
+		writeMetricTable(t, m.Metrics)
+		return t.Flush()
+	default:
+		fmt.Fprintln(w, m)
+		for _, r := range m.Records {
+			writeRecord(w, m, r)
+		}
+		for _, s := range m.Metrics {
+			fmt.Fprintln(w, s)
+		}
+		return nil
+	}
+}
+
+func writeFileTable(w io.Writer, m *logger.Logger) {
+	fmt.Fprintln(w, "SIZE\tMODIFIED\tPATH")
+	for _, f := range m.LogFiles {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", f.Size, f.ModTime.Format(time.RFC3339), f.Path)
+	}
+}
+
+func writeMetricTable(w io.Writer, series []logger.Series) {
+	if len(series) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "METRIC\tPOINTS\tLAST\tMIN\tMAX")
+	for _, s := range series {
+		lo, hi := s.Range()
+		last := s.Points[len(s.Points)-1].Value
+		fmt.Fprintf(w, "%s\t%d\t%g\t%g\t%g\n", s.Name, len(s.Points), last, lo, hi)
+	}
+}
+
+// writeRecord prints one record in the current output format
+func writeRecord(w io.Writer, m *logger.Logger, r logger.Record) {
+	switch output {
+	case outputJSON, outputYAML:
+		writeLine(w, output, r)
+	case outputTable:
+		t := newTable(w)
+		writeRecordRow(t, m, r)
+		t.Flush()
+	default:
+		if merge {
+			fmt.Fprintf(w, "[%s] %s\n", relPath(m, r.File), r)
+		} else {
+			fmt.Fprintln(w, r)
+		}
+	}
+}
+
+func writeRecordRow(w io.Writer, m *logger.Logger, r logger.Record) {
+	ts := "-"
+	if !r.Time.IsZero() {
+		ts = r.Time.Format("2006-01-02T15:04:05.000")
+	}
+	msg := r.Message
+	keys := make([]string, 0, len(r.Fields))
+	for k := range r.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		msg += fmt.Sprintf(" %s=%s", k, r.Fields[k])
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", ts, r.Level, relPath(m, r.File), r.Line, r.Source, msg)
+}
+
+// relPath of a record's file relative to datadir
+func relPath(m *logger.Logger, path string) string {
+	name, err := filepath.Rel(m.Datadir, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(name)
+}
+
 func init() {
 	rootCmd.AddCommand(loggerCmd)
 
@@ -29,7 +330,55 @@ func init() {
 		"datadir", "d", "./log",
 		"Path to log directory")
 
-	loggerCmd.PersistentFlags().StringVarP(&fileext,
-		"fileext", "e", ".log",
-		"Extension of log files")
+	loggerCmd.PersistentFlags().StringSliceVarP(&fileexts,
+		"fileext", "e", []string{".log"},
+		"Extensions of log files, also matching rotated (.log.1) and compressed (.gz, .zst) files")
+
+	loggerCmd.PersistentFlags().BoolVarP(&recursive,
+		"recursive", "r", true,
+		"Search subdirectories of datadir")
+
+	loggerCmd.PersistentFlags().StringSliceVarP(&includes,
+		"include", "i", nil,
+		"Only files matching these globs, relative to datadir (** spans directories)")
+
+	loggerCmd.PersistentFlags().StringSliceVarP(&excludes,
+		"exclude", "x", nil,
+		"Skip files and directories matching these globs")
+
+	loggerCmd.PersistentFlags().StringVarP(&output,
+		"output", "o", outputText,
+		"Output format: text, json, yaml or table")
+
+	loggerCmd.PersistentFlags().StringVarP(&query,
+		"query", "q", "",
+		"Only records matching this expression, e.g. 'level>=warn and time>=-1h and msg~timeout'")
+
+	loggerCmd.PersistentFlags().BoolVar(&useIndex,
+		"index", false,
+		"Update the index next to datadir and use it to read only lines a time or level query can match")
+
+	loggerCmd.Flags().BoolVarP(&follow,
+		"follow", "f", false,
+		"Keep running and print lines as they are appended")
+
+	loggerCmd.Flags().BoolVarP(&merge,
+		"merge", "m", false,
+		"Merge all log files into one timeline ordered by timestamp, printed as it is read (json and yaml as one document per record)")
+
+	loggerCmd.Flags().BoolVar(&metrics,
+		"metrics", false,
+		"Summarize numeric series such as loss=0.21 found in the records")
+
+	loggerCmd.Flags().StringSliceVar(&metricKeys,
+		"metric-key", nil,
+		"Structured field to extract as a metric (repeatable)")
+
+	loggerCmd.Flags().StringArrayVar(&metricPatterns,
+		"metric-regex", nil,
+		"Regex on messages; named groups are metrics, or a key/value group pair (repeatable)")
+
+	loggerCmd.Flags().StringVar(&metricX,
+		"metric-x", logger.XIndex,
+		"Metric to use as x axis, \"time\" for timestamps, empty for point index")
 }
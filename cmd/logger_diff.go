@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/manyids2/go-tools/tui/models/logger"
+	"github.com/spf13/cobra"
+)
+
+// loggerDiffCmd represents the logger diff command
+var loggerDiffCmd = &cobra.Command{
+	Use:   "diff <dirA> <dirB>",
+	Short: "Compare the logs of two runs",
+	Long: `Load the log files of two runs, each merged into one timeline, and line up
+their message templates. Shows templates and lines found in only one run,
+and the final value of every extracted metric in both runs along with the
+duration, error count and time per epoch.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		e, err := logger.NewExtractor(metricKeys, metricPatterns, logger.XIndex)
+		if err != nil {
+			return err
+		}
+		a, _, err := loadLogger(args[0], true)
+		if err != nil {
+			return err
+		}
+		b, _, err := loadLogger(args[1], true)
+		if err != nil {
+			return err
+		}
+		d := logger.DiffRuns(a, b, e)
+
+		switch output {
+		case outputJSON, outputYAML:
+			return writeStructured(os.Stdout, output, d)
+		case outputTable:
+			t := newTable(os.Stdout)
+			fmt.Fprintln(t, "METRIC\tA\tB\tDELTA")
+			for _, m := range d.Metrics {
+				fmt.Fprintf(t, "%s\t%s\t%s\t%s\n", m.Name, formatMetric(m.A, m.HasA), formatMetric(m.B, m.HasB), formatMetric(m.Delta(), !math.IsNaN(m.Delta())))
+			}
+			fmt.Fprintln(t)
+			fmt.Fprintln(t, "A\tB\tTEMPLATE")
+			for _, tmpl := range d.Templates {
+				fmt.Fprintf(t, "%d\t%d\t%s\n", tmpl.CountA, tmpl.CountB, tmpl.Pattern)
+			}
+			return t.Flush()
+		default:
+			fmt.Print(d)
+			return nil
+		}
+	},
+}
+
+func formatMetric(v float64, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.6g", v)
+}
+
+func init() {
+	loggerCmd.AddCommand(loggerDiffCmd)
+
+	loggerDiffCmd.Flags().StringSliceVar(&metricKeys,
+		"metric-key", nil,
+		"Structured field to compare as a metric (repeatable)")
+
+	loggerDiffCmd.Flags().StringArrayVar(&metricPatterns,
+		"metric-regex", nil,
+		"Regex on messages; named groups are metrics, or a key/value group pair (repeatable)")
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// loggerIndexCmd represents the logger index command
+var loggerIndexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Build or update the index of log files",
+	Long: `Index line offsets, timestamps and levels of every uncompressed log file
+into a directory next to datadir (<datadir>.index). Only lines appended since
+the last run are read; truncated or replaced files are indexed again. Queries
+run with --index then seek straight to the lines they can match.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutput(output); err != nil {
+			return err
+		}
+		m := discoverLogger(datadir)
+		idx, err := m.UpdateIndex()
+		if err != nil {
+			return err
+		}
+
+		paths := make([]string, 0, len(idx.Files))
+		for p := range idx.Files {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+
+		switch output {
+		case outputJSON, outputYAML:
+			type indexed struct {
+				Path   string `json:"path" yaml:"path"`
+				Lines  int    `json:"lines" yaml:"lines"`
+				Blocks int    `json:"blocks" yaml:"blocks"`
+				Bytes  int64  `json:"bytes" yaml:"bytes"`
+			}
+			out := struct {
+				Dir   string    `json:"dir" yaml:"dir"`
+				Files []indexed `json:"files" yaml:"files"`
+			}{Dir: idx.Dir}
+			for _, p := range paths {
+				ix := idx.Files[p]
+				out.Files = append(out.Files, indexed{p, ix.Lines(), len(ix.Checkpoints), ix.Size})
+			}
+			return writeStructured(os.Stdout, output, out)
+		case outputTable:
+			t := newTable(os.Stdout)
+			fmt.Fprintln(t, "LINES\tBLOCKS\tBYTES\tPATH")
+			for _, p := range paths {
+				ix := idx.Files[p]
+				fmt.Fprintf(t, "%d\t%d\t%d\t%s\n", ix.Lines(), len(ix.Checkpoints), ix.Size, p)
+			}
+			return t.Flush()
+		default:
+			fmt.Println(idx)
+			return nil
+		}
+	},
+}
+
+func init() {
+	loggerCmd.AddCommand(loggerIndexCmd)
+}
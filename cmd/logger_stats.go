@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/manyids2/go-tools/tui/models/logger"
+	"github.com/spf13/cobra"
+)
+
+var statsBuckets, statsTop int
+
+// loggerStatsCmd represents the logger stats command
+var loggerStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize log files",
+	Long: `Count records per level and file, show the time span, the error rate over
+time and the most frequent messages with numbers and IDs masked.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, _, err := loadLogger(datadir, false)
+		if err != nil {
+			return err
+		}
+		m.SetStats(statsBuckets, statsTop)
+
+		switch output {
+		case outputJSON, outputYAML:
+			return writeStructured(os.Stdout, output, m.Stats)
+		case outputTable:
+			s := m.Stats
+			t := newTable(os.Stdout)
+			fmt.Fprintln(t, "LEVEL\tCOUNT")
+			for _, level := range append([]logger.Level{logger.LevelUnknown}, logger.Levels...) {
+				if n := s.ByLevel[level]; n > 0 {
+					fmt.Fprintf(t, "%s\t%d\n", level, n)
+				}
+			}
+			fmt.Fprintln(t)
+			fmt.Fprintln(t, "FILE\tCOUNT")
+			for _, f := range s.Files() {
+				fmt.Fprintf(t, "%s\t%d\n", f, s.ByFile[f])
+			}
+			fmt.Fprintln(t)
+			fmt.Fprintln(t, "START\tEND\tERRORS\tTOTAL")
+			for _, h := range s.Histogram {
+				fmt.Fprintf(t, "%s\t%s\t%d\t%d\n", h.Start.Format("2006-01-02T15:04:05"), h.End.Format("2006-01-02T15:04:05"), h.Errors, h.Total)
+			}
+			fmt.Fprintln(t)
+			fmt.Fprintln(t, "COUNT\tTEMPLATE")
+			for _, tmpl := range s.Templates {
+				fmt.Fprintf(t, "%d\t%s\n", tmpl.Count, tmpl.Pattern)
+			}
+			return t.Flush()
+		default:
+			fmt.Print(m.Stats)
+			return nil
+		}
+	},
+}
+
+func init() {
+	loggerCmd.AddCommand(loggerStatsCmd)
+
+	loggerStatsCmd.Flags().IntVarP(&statsBuckets,
+		"buckets", "b", 20,
+		"Number of time buckets in the error rate histogram")
+
+	loggerStatsCmd.Flags().IntVarP(&statsTop,
+		"top", "n", 10,
+		"Number of most frequent message templates to show")
+}
@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/manyids2/go-tools/tui/models/predictions"
+	"github.com/spf13/cobra"
+)
+
+var predDatadir string
+var onlyMissing bool
+var predWorkers int
+var predProgress bool
+var predLayout string
+var predRescan bool
+
+// predictionsCmd represents the predictions command
+var predictionsCmd = &cobra.Command{
+	Use:   "predictions",
+	Short: "Inspect prediction outputs",
+	Long: `Load an inference output directory laid out as <datadir>/<group>/<slide>/
+and summarize its groups and slides. Scans are cached in the user cache
+directory and only directories whose mtime changed are read again, unless
+--rescan. Other layouts are described in a YAML file given with --layout or
+found as ` + predictions.LayoutFile + ` in the datadir; see "predictions layout".`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := loadPredictions()
+		if err != nil {
+			return err
+		}
+
+		switch output {
+		case outputJSON, outputYAML:
+			groups := make([]predictions.Group, 0, len(m.Groups))
+			for _, name := range m.GroupNames() {
+				groups = append(groups, m.Groups[name])
+			}
+			return writeStructured(os.Stdout, output, struct {
+				Datadir string              `json:"datadir" yaml:"datadir"`
+				Groups  []predictions.Group `json:"groups" yaml:"groups"`
+			}{m.Datadir, groups})
+		case outputTable:
+			t := newTable(os.Stdout)
+			fmt.Fprintln(t, "GROUP\tSLIDES")
+			for _, name := range m.GroupNames() {
+				fmt.Fprintf(t, "%s\t%d\n", name, len(m.Groups[name].Slidenames))
+			}
+			return t.Flush()
+		default:
+			fmt.Println(m)
+			return nil
+		}
+	},
+}
+
+// predictionsGroupsCmd represents the predictions groups command
+var predictionsGroupsCmd = &cobra.Command{
+	Use:   "groups",
+	Short: "List groups",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := loadPredictions()
+		if err != nil {
+			return err
+		}
+		names := m.GroupNames()
+		switch output {
+		case outputJSON, outputYAML:
+			return writeStructured(os.Stdout, output, names)
+		default:
+			for _, name := range names {
+				fmt.Println(name)
+			}
+			return nil
+		}
+	},
+}
+
+// predictionsSlidesCmd represents the predictions slides command
+var predictionsSlidesCmd = &cobra.Command{
+	Use:   "slides <group>",
+	Short: "List the slides of a group and the artifacts they have",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := loadPredictions()
+		if err != nil {
+			return err
+		}
+		group, ok := m.Groups[args[0]]
+		if !ok {
+			return fmt.Errorf("no group %q in %s", args[0], m.Datadir)
+		}
+		slides := group.Slides
+		if onlyMissing {
+			slides = group.Incomplete()
+		}
+
+		switch output {
+		case outputJSON, outputYAML:
+			type slideOutput struct {
+				predictions.Slide `yaml:",inline"`
+				Missing           []predictions.Kind `json:"missing" yaml:"missing"`
+			}
+			out := make([]slideOutput, 0, len(slides))
+			for _, s := range slides {
+				out = append(out, slideOutput{s, s.Missing()})
+			}
+			return writeStructured(os.Stdout, output, out)
+		case outputTable:
+			t := newTable(os.Stdout)
+			fmt.Fprint(t, "SLIDE")
+			kinds := m.Layout.Listed()
+			for _, k := range kinds {
+				fmt.Fprintf(t, "\t%s", strings.ToUpper(string(k)))
+			}
+			fmt.Fprintln(t)
+			for _, s := range slides {
+				fmt.Fprint(t, s.Name)
+				for _, k := range kinds {
+					fmt.Fprintf(t, "\t%d", len(s.ByKind(k)))
+				}
+				fmt.Fprintln(t)
+			}
+			return t.Flush()
+		default:
+			for _, s := range slides {
+				if missing := s.Missing(); len(missing) > 0 {
+					fmt.Printf("%s  missing %v\n", s.Name, missing)
+				} else {
+					fmt.Println(s.Name)
+				}
+			}
+			return nil
+		}
+	},
+}
+
+// predictionsSlideCmd represents the predictions slide command
+var predictionsSlideCmd = &cobra.Command{
+	Use:   "slide <group> <slide>",
+	Short: "Show the artifacts of one slide",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := loadPredictions()
+		if err != nil {
+			return err
+		}
+		group, ok := m.Groups[args[0]]
+		if !ok {
+			return fmt.Errorf("no group %q in %s", args[0], m.Datadir)
+		}
+		slide, ok := group.Slide(args[1])
+		if !ok {
+			return fmt.Errorf("no slide %q in group %s", args[1], args[0])
+		}
+
+		features, err := slide.Annotations()
+		if err != nil {
+			log.Println("Could not read annotations: ", slide.Path, err)
+		}
+		var classes []predictions.ClassSummary
+		if len(features) > 0 {
+			classes = predictions.SummarizeFeatures(features)
+		}
+
+		switch output {
+		case outputJSON, outputYAML:
+			return writeStructured(os.Stdout, output, struct {
+				predictions.Slide `yaml:",inline"`
+				Missing           []predictions.Kind         `json:"missing" yaml:"missing"`
+				Annotations       []predictions.ClassSummary `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+			}{slide, slide.Missing(), classes})
+		case outputTable:
+			t := newTable(os.Stdout)
+			fmt.Fprintln(t, "KIND\tSIZE\tMODIFIED\tNAME")
+			for _, a := range slide.Artifacts {
+				fmt.Fprintf(t, "%s\t%d\t%s\t%s\n", a.Kind, a.Size, a.ModTime.Format(time.RFC3339), a.Name)
+			}
+			for _, k := range slide.Missing() {
+				fmt.Fprintf(t, "%s\t-\t-\tmissing\n", k)
+			}
+			return t.Flush()
+		default:
+			fmt.Print(slide)
+			if len(classes) > 0 {
+				fmt.Printf("  Annotations: %d features\n", len(features))
+				for _, c := range classes {
+					fmt.Printf("    %-20s %6d  %14.1f\n", c.Class, c.Count, c.Area)
+				}
+			}
+			return nil
+		}
+	},
+}
+
+// loadPredictions reads the groups and slides of --datadir with --workers
+// in parallel, stopping on interrupt
+func loadPredictions() (*predictions.Predictions, error) {
+	return loadPredictionsDir(predDatadir)
+}
+
+// loadPredictionsDir is loadPredictions for another directory
+func loadPredictionsDir(dir string) (*predictions.Predictions, error) {
+	if err := checkOutput(output); err != nil {
+		return nil, err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	m := predictions.NewPredictions(dir)
+	layout, err := loadLayout(dir)
+	if err != nil {
+		return nil, err
+	}
+	m.Layout = layout
+	m.Rescan = predRescan
+	for e := range m.Load(ctx, predWorkers) {
+		switch e.Kind {
+		case predictions.EventGroup:
+			if e.Err != nil {
+				log.Println("Could not read group: ", e.Group, e.Err)
+			}
+			if predProgress {
+				fmt.Fprintf(os.Stderr, "[%d/%d] %s\n", e.Done, e.Total, e.Group)
+			}
+		case predictions.EventError:
+			err = e.Err
+		}
+	}
+	if predProgress && err == nil {
+		fmt.Fprintf(os.Stderr, "%d slides from %s, %d scanned\n", m.Reused, m.Manifest, m.Scanned)
+	}
+	return m, err
+}
+
+// loadLayout reads --layout, or the layout file of dir or its parents
+func loadLayout(dir string) (*predictions.Layout, error) {
+	if predLayout != "" {
+		return predictions.ReadLayout(predLayout)
+	}
+	return predictions.FindLayout(dir)
+}
+
+func init() {
+	rootCmd.AddCommand(predictionsCmd)
+	predictionsCmd.AddCommand(predictionsGroupsCmd, predictionsSlidesCmd, predictionsSlideCmd)
+
+	predictionsCmd.PersistentFlags().StringVarP(&predDatadir,
+		"datadir", "d", ".",
+		"Path to prediction output directory")
+
+	predictionsSlidesCmd.Flags().BoolVar(&onlyMissing,
+		"missing", false,
+		"Only slides missing an expected artifact")
+
+	predictionsCmd.PersistentFlags().StringVar(&predLayout,
+		"layout", "",
+		"Layout file describing the directory levels and expected artifacts, default "+predictions.LayoutFile+" in the datadir or a parent")
+
+	predictionsCmd.PersistentFlags().BoolVar(&predRescan,
+		"rescan", false,
+		"Scan every slide again instead of reusing the cached manifest")
+
+	predictionsCmd.PersistentFlags().IntVarP(&predWorkers,
+		"workers", "j", 0,
+		"Groups loaded in parallel, 0 for one per CPU")
+
+	predictionsCmd.PersistentFlags().BoolVar(&predProgress,
+		"progress", false,
+		"Report each group on stderr as it finishes loading")
+
+	predictionsCmd.PersistentFlags().StringVarP(&output,
+		"output", "o", outputText,
+		"Output format: text, json, yaml or table")
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/manyids2/go-tools/tui/models/predictions"
+	"github.com/spf13/cobra"
+)
+
+var agreementThreshold float64
+var compareAll bool
+
+// predictionsCompareCmd represents the predictions compare command
+var predictionsCompareCmd = &cobra.Command{
+	Use:   "compare <runA> <runB>",
+	Short: "Compare the predictions of two runs",
+	Long: `Match the groups and slides of two prediction directories by name. Reports
+slides found in only one run, slides whose predicted label changed, the score
+difference of every slide scored in both runs and masks whose Dice agreement
+is below --threshold.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := loadPredictionsDir(args[0])
+		if err != nil {
+			return err
+		}
+		b, err := loadPredictionsDir(args[1])
+		if err != nil {
+			return err
+		}
+		c := predictions.Compare(a, b, agreementThreshold)
+		slides := c.Flagged()
+		if compareAll {
+			slides = c.Slides
+		}
+
+		switch output {
+		case outputJSON, outputYAML:
+			c.Slides = slides
+			return writeStructured(os.Stdout, output, c)
+		case outputTable:
+			t := newTable(os.Stdout)
+			fmt.Fprintln(t, "GROUP\tSLIDE\tLABEL A\tLABEL B\tSCORE A\tSCORE B\tDELTA\tDICE\tFLAG")
+			for _, s := range slides {
+				dice := formatMetric(s.Dice, s.HasMask)
+				fmt.Fprintf(t, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Group, s.Slide,
+					orDash(s.LabelA), orDash(s.LabelB),
+					formatMetric(s.ScoreA, s.HasScoreA), formatMetric(s.ScoreB, s.HasScoreB),
+					formatMetric(s.ScoreDelta(), !math.IsNaN(s.ScoreDelta())), dice, flag(s))
+			}
+			return t.Flush()
+		default:
+			if compareAll {
+				fmt.Print(c.Summary())
+				fmt.Println()
+				for _, s := range slides {
+					fmt.Println(s)
+				}
+				return nil
+			}
+			fmt.Print(c)
+			return nil
+		}
+	},
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// flag says why a slide needs a look
+func flag(s predictions.SlideChange) string {
+	switch {
+	case !s.InB:
+		return "only A"
+	case !s.InA:
+		return "only B"
+	case s.LabelChanged():
+		return "label"
+	case s.LowAgreement:
+		return "mask"
+	case s.MaskErr != "":
+		return "mask error"
+	}
+	return ""
+}
+
+func init() {
+	predictionsCmd.AddCommand(predictionsCompareCmd)
+
+	predictionsCompareCmd.Flags().Float64Var(&agreementThreshold,
+		"threshold", predictions.DefaultAgreement,
+		"Flag masks whose Dice agreement is below this")
+
+	predictionsCompareCmd.Flags().BoolVar(&compareAll,
+		"all", false,
+		"List every slide, not only flagged ones")
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/manyids2/go-tools/tui/models/predictions"
+	"github.com/spf13/cobra"
+)
+
+var truthPath, positiveClass string
+var perSlide bool
+
+// predictionsEvalCmd represents the predictions eval command
+var predictionsEvalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Compare predictions with ground truth",
+	Long: `Pair every slide with its true label, from a CSV with slide and label
+columns (and optionally group) or from a ground-truth directory laid out like
+the predictions. Predicted labels and scores are read from each slide's JSON
+summary. Reports accuracy, precision, recall and F1 per class, the confusion
+matrix, ROC-AUC of the positive class scores and Dice/IoU of masks, for the
+whole run and per group.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := loadPredictions()
+		if err != nil {
+			return err
+		}
+		gt, err := predictions.LoadGroundTruth(truthPath)
+		if err != nil {
+			return err
+		}
+		report := predictions.Evaluate(m, gt, positiveClass)
+
+		switch output {
+		case outputJSON, outputYAML:
+			return writeStructured(os.Stdout, output, report)
+		case outputTable:
+			t := newTable(os.Stdout)
+			fmt.Fprintln(t, "GROUP\tSLIDE\tTRUE\tPREDICTED\tSCORE\tDICE\tIOU")
+			for _, r := range report.Overall.Slides {
+				score, dice, iou := "-", "-", "-"
+				if r.HasScore {
+					score = fmt.Sprintf("%.4f", r.Score)
+				}
+				if r.HasMask {
+					dice, iou = fmt.Sprintf("%.4f", r.Dice), fmt.Sprintf("%.4f", r.IoU)
+				}
+				fmt.Fprintf(t, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Group, r.Slide, r.True, r.Predicted, score, dice, iou)
+			}
+			return t.Flush()
+		default:
+			fmt.Printf("Truth: %s\n\n", report.Truth)
+			fmt.Println(report.Overall)
+			for _, e := range report.Groups {
+				fmt.Println(e)
+			}
+			if perSlide {
+				for _, r := range report.Overall.Slides {
+					mark := " "
+					if !r.Correct() {
+						mark = "x"
+					}
+					fmt.Printf("%s %s/%s  true %s  predicted %s", mark, r.Group, r.Slide, r.True, r.Predicted)
+					if r.HasScore {
+						fmt.Printf("  score %.4f", r.Score)
+					}
+					if r.HasMask {
+						fmt.Printf("  dice %.4f  iou %.4f", r.Dice, r.IoU)
+					}
+					if r.MaskErr != "" {
+						fmt.Printf("  mask: %s", r.MaskErr)
+					}
+					fmt.Println()
+				}
+			}
+			return nil
+		}
+	},
+}
+
+func init() {
+	predictionsCmd.AddCommand(predictionsEvalCmd)
+
+	predictionsEvalCmd.Flags().StringVarP(&truthPath,
+		"truth", "t", "",
+		"Labels CSV or ground-truth directory")
+	predictionsEvalCmd.MarkFlagRequired("truth")
+
+	predictionsEvalCmd.Flags().StringVar(&positiveClass,
+		"positive", "",
+		"Class whose scores are used for ROC-AUC, default the last class in sorted order")
+
+	predictionsEvalCmd.Flags().BoolVar(&perSlide,
+		"per-slide", false,
+		"Also list every slide with its true and predicted label")
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/manyids2/go-tools/tui/models/predictions"
+	"github.com/spf13/cobra"
+)
+
+// predictionsLayoutCmd represents the predictions layout command
+var predictionsLayoutCmd = &cobra.Command{
+	Use:   "layout",
+	Short: "Show the directory layout used for the datadir",
+	Long: `Print the layout used to read --datadir: the given --layout file, the
+closest ` + predictions.LayoutFile + ` in the datadir or its parents, or the default of
+<group>/<slide>/ directories. A layout lists directory levels from the
+datadir down to the slides, each with an optional regex whose named groups
+become fields of the slide, and artifact rules giving a kind to files by
+their path within the slide directory:
+
+  levels:
+    - name: group
+    - name: slide
+      pattern: '(?P<patient>P\d+)_(?P<stain>HE|IHC)'
+  artifacts:
+    - kind: mask
+      pattern: 'masks/.*\.png'
+    - kind: summary
+      pattern: 'result\.json'
+    - kind: overlay
+      pattern: '.*_overlay\.jpg'
+      optional: true
+
+Patterns must match the whole name. Without artifact rules, kinds are
+guessed from file names, and CSVs with x and y columns are tiles; every slide
+is expected to have a mask and a summary.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutput(output); err != nil {
+			return err
+		}
+		l, err := loadLayout(predDatadir)
+		if err != nil {
+			return err
+		}
+		switch output {
+		case outputJSON:
+			return writeStructured(os.Stdout, output, l)
+		default:
+			if l.Path != "" {
+				fmt.Printf("# %s\n", l.Path)
+			} else {
+				fmt.Println("# default layout")
+			}
+			return writeStructured(os.Stdout, outputYAML, l)
+		}
+	},
+}
+
+func init() {
+	predictionsCmd.AddCommand(predictionsLayoutCmd)
+}
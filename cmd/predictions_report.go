@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/manyids2/go-tools/tui/models/predictions"
+	"github.com/spf13/cobra"
+)
+
+// Report formats
+const (
+	reportHTML     = "html"
+	reportMarkdown = "markdown"
+)
+
+var reportTruth, reportPositive string
+var reportFormat, reportFile string
+var reportTop int
+
+// predictionsReportCmd represents the predictions report command
+var predictionsReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Write a review report as HTML or Markdown",
+	Long: `Summarize the groups of a predictions directory for sharing: slides,
+incomplete and unlabeled slides, predicted classes and mean score per group,
+the evaluation against --truth when given, and the --top slides that most need
+a look, such as misclassified slides, slides missing artifacts, masks that
+disagree with the truth and scores close to 0.5.
+
+HTML reports embed the thumbnails of those slides and are a single file;
+Markdown reports link to them relative to --file. The format follows the
+extension of --file unless --format is given. With -o json or yaml the report
+data is written instead.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := reportFormatFor(reportFormat, reportFile)
+		if err != nil {
+			return err
+		}
+		m, err := loadPredictions()
+		if err != nil {
+			return err
+		}
+		var eval *predictions.Report
+		if reportTruth != "" {
+			gt, err := predictions.LoadGroundTruth(reportTruth)
+			if err != nil {
+				return err
+			}
+			e := predictions.Evaluate(m, gt, reportPositive)
+			eval = &e
+		}
+		report := predictions.NewRunReport(m, eval, reportTop)
+
+		if reportFile == "" {
+			return writeReport(os.Stdout, report, format, ".")
+		}
+		f, err := os.Create(reportFile)
+		if err != nil {
+			return err
+		}
+		if err := writeReport(f, report, format, filepath.Dir(reportFile)); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	},
+}
+
+// writeReport writes report in format, or as --output data when structured;
+// Markdown links are relative to dir
+func writeReport(w io.Writer, report predictions.RunReport, format, dir string) error {
+	switch {
+	case output == outputJSON || output == outputYAML:
+		return writeStructured(w, output, report)
+	case format == reportMarkdown:
+		return report.WriteMarkdown(w, dir)
+	default:
+		return report.WriteHTML(w)
+	}
+}
+
+// reportFormatFor returns format, or guesses it from the extension of file
+func reportFormatFor(format, file string) (string, error) {
+	switch strings.ToLower(format) {
+	case reportHTML:
+		return reportHTML, nil
+	case reportMarkdown, "md":
+		return reportMarkdown, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown report format %q, expected html or markdown", format)
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".md", ".markdown":
+		return reportMarkdown, nil
+	}
+	return reportHTML, nil
+}
+
+func init() {
+	predictionsCmd.AddCommand(predictionsReportCmd)
+
+	predictionsReportCmd.Flags().StringVarP(&reportTruth,
+		"truth", "t", "",
+		"Labels CSV or ground-truth directory to evaluate against")
+
+	predictionsReportCmd.Flags().StringVar(&reportPositive,
+		"positive", "",
+		"Class whose scores are used for ROC-AUC, default the last class in sorted order")
+
+	predictionsReportCmd.Flags().StringVarP(&reportFile,
+		"file", "f", "",
+		"File to write, default stdout")
+
+	predictionsReportCmd.Flags().StringVar(&reportFormat,
+		"format", "",
+		"Report format: html or markdown, default from the extension of --file or html")
+
+	predictionsReportCmd.Flags().IntVar(&reportTop,
+		"top", 20,
+		"Slides needing attention to list, 0 for all")
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/manyids2/go-tools/tui/models/predictions"
+	"github.com/spf13/cobra"
+)
+
+var tilesClass string
+var tilesTopK int
+var tilesThreshold float64
+
+// slideTiles are the tile score aggregates of one slide
+type slideTiles struct {
+	Group      string                      `json:"group" yaml:"group"`
+	Slide      string                      `json:"slide" yaml:"slide"`
+	Path       string                      `json:"path" yaml:"path"`
+	Aggregates []predictions.TileAggregate `json:"aggregates" yaml:"aggregates"`
+}
+
+// predictionsTilesCmd represents the predictions tiles command
+var predictionsTilesCmd = &cobra.Command{
+	Use:   "tiles [group]",
+	Short: "Aggregate per-tile scores of slides",
+	Long: `Read the tiles CSV of every slide, or of the slides of one group, and
+summarize the score columns per slide: mean, max, mean of the --top-k highest
+scores and the fraction of tiles scoring at least --threshold.
+
+Tile CSVs have a header with x and y columns; every other numeric column is a
+score, named after its class without a score_ or prob_ prefix.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := loadPredictions()
+		if err != nil {
+			return err
+		}
+		slides := m.AllSlides()
+		if len(args) == 1 {
+			group, ok := m.Groups[args[0]]
+			if !ok {
+				return fmt.Errorf("no group %q in %s", args[0], m.Datadir)
+			}
+			slides = group.Slides
+		}
+
+		var out []slideTiles
+		for _, s := range slides {
+			if !s.Has(predictions.KindTiles) {
+				continue
+			}
+			t, err := s.TileScores()
+			if err != nil {
+				log.Println("Could not read tiles: ", s.Path, err)
+				continue
+			}
+			aggregates := t.Aggregates(tilesTopK, tilesThreshold)
+			if tilesClass != "" {
+				i := t.Class(tilesClass)
+				if i < 0 {
+					continue
+				}
+				aggregates = aggregates[i : i+1]
+			}
+			out = append(out, slideTiles{s.Group, s.Name, t.Path, aggregates})
+		}
+
+		switch output {
+		case outputJSON, outputYAML:
+			return writeStructured(os.Stdout, output, out)
+		case outputTable:
+			t := newTable(os.Stdout)
+			fmt.Fprintf(t, "GROUP\tSLIDE\tCLASS\tTILES\tMEAN\tMAX\tTOP-%d\tABOVE %g\n", tilesTopK, tilesThreshold)
+			for _, s := range out {
+				for _, a := range s.Aggregates {
+					fmt.Fprintf(t, "%s\t%s\t%s\t%d\t%.3f\t%.3f\t%.3f\t%.1f%%\n",
+						s.Group, s.Slide, a.Class, a.Tiles, a.Mean, a.Max, a.TopK, 100*a.Above)
+				}
+			}
+			return t.Flush()
+		default:
+			for _, s := range out {
+				fmt.Printf("%s/%s\n", s.Group, s.Slide)
+				for _, a := range s.Aggregates {
+					fmt.Printf("  %-16s %6d tiles  mean %.3f  max %.3f  top-%d %.3f  %.1f%% >= %g\n",
+						a.Class, a.Tiles, a.Mean, a.Max, a.K, a.TopK, 100*a.Above, a.Threshold)
+				}
+			}
+			return nil
+		}
+	},
+}
+
+func init() {
+	predictionsCmd.AddCommand(predictionsTilesCmd)
+
+	predictionsTilesCmd.Flags().StringVar(&tilesClass,
+		"class", "",
+		"Only this score column, default all")
+
+	predictionsTilesCmd.Flags().IntVar(&tilesTopK,
+		"top-k", predictions.DefaultTopK,
+		"Number of highest scores averaged")
+
+	predictionsTilesCmd.Flags().Float64Var(&tilesThreshold,
+		"threshold", predictions.DefaultTileThreshold,
+		"Score counted as above for the fraction of tiles")
+}
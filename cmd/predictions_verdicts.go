@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/manyids2/go-tools/tui/models/predictions"
+	"github.com/spf13/cobra"
+)
+
+var verdictsAll bool
+var verdictsFile string
+
+// predictionsVerdictsCmd represents the predictions verdicts command
+var predictionsVerdictsCmd = &cobra.Command{
+	Use:   "verdicts",
+	Short: "List reviewer verdicts",
+	Long: `List the verdicts (accepted, rejected or needs-review) and notes given to
+slides, as saved in ` + predictions.VerdictsFile + ` in the datadir when reviewing slides in
+the TUI or with "predictions verdicts set".`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := loadPredictions()
+		if err != nil {
+			return err
+		}
+		reviews := verdictsOf(m)
+
+		switch output {
+		case outputJSON, outputYAML:
+			return writeStructured(os.Stdout, output, reviews)
+		case outputTable:
+			t := newTable(os.Stdout)
+			fmt.Fprintln(t, "GROUP\tSLIDE\tVERDICT\tREVIEWER\tTIME\tNOTE")
+			for _, r := range reviews {
+				fmt.Fprintf(t, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Group, r.Slide, orDash(string(r.Verdict)),
+					orDash(r.Reviewer), reviewTime(r), r.Note)
+			}
+			return t.Flush()
+		default:
+			counts := make(map[predictions.Verdict]int)
+			for _, r := range m.Reviews {
+				counts[r.Verdict]++
+			}
+			fmt.Printf("%d of %d slides reviewed:", len(m.Reviews), len(m.AllSlides()))
+			for _, v := range predictions.Verdicts {
+				fmt.Printf(" %d %s", counts[v], v)
+			}
+			fmt.Println()
+			for _, r := range reviews {
+				fmt.Printf("%s/%s  %s", r.Group, r.Slide, orDash(string(r.Verdict)))
+				if r.Note != "" {
+					fmt.Printf("  %q", r.Note)
+				}
+				fmt.Println()
+			}
+			return nil
+		}
+	},
+}
+
+// predictionsVerdictsExportCmd represents the predictions verdicts export command
+var predictionsVerdictsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export reviewer verdicts as CSV",
+	Long: `Write the verdicts as CSV with group, slide, verdict, note, reviewer and time
+columns, to share review work or read it into a spreadsheet.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := loadPredictions()
+		if err != nil {
+			return err
+		}
+		if verdictsFile == "" {
+			return predictions.WriteReviewsCSV(os.Stdout, verdictsOf(m))
+		}
+		f, err := os.Create(verdictsFile)
+		if err != nil {
+			return err
+		}
+		if err := predictions.WriteReviewsCSV(f, verdictsOf(m)); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	},
+}
+
+// predictionsVerdictsSetCmd represents the predictions verdicts set command
+var predictionsVerdictsSetCmd = &cobra.Command{
+	Use:   "set <group> <slide> <accepted|rejected|needs-review|none> [note]",
+	Short: "Give a slide a verdict and note",
+	Args:  cobra.RangeArgs(3, 4),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := loadPredictions()
+		if err != nil {
+			return err
+		}
+		group, ok := m.Groups[args[0]]
+		if !ok {
+			return fmt.Errorf("no group %q in %s", args[0], m.Datadir)
+		}
+		if _, ok := group.Slide(args[1]); !ok {
+			return fmt.Errorf("no slide %q in group %s", args[1], args[0])
+		}
+		var verdict predictions.Verdict
+		if args[2] != "none" {
+			if verdict, err = predictions.ParseVerdict(args[2]); err != nil {
+				return err
+			}
+		}
+		note := ""
+		if len(args) == 4 {
+			note = args[3]
+		}
+		return m.SetReview(args[0], args[1], verdict, note)
+	},
+}
+
+// verdictsOf returns the reviews of m, with every unreviewed slide too if
+// --all
+func verdictsOf(m *predictions.Predictions) []predictions.Review {
+	if !verdictsAll {
+		return m.SortedReviews()
+	}
+	var out []predictions.Review
+	for _, s := range m.AllSlides() {
+		r, ok := m.Review(s.Group, s.Name)
+		if !ok {
+			r = predictions.Review{Group: s.Group, Slide: s.Name}
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func reviewTime(r predictions.Review) string {
+	if r.Time.IsZero() {
+		return "-"
+	}
+	return r.Time.Local().Format("2006-01-02 15:04:05")
+}
+
+func init() {
+	predictionsCmd.AddCommand(predictionsVerdictsCmd)
+	predictionsVerdictsCmd.AddCommand(predictionsVerdictsExportCmd, predictionsVerdictsSetCmd)
+
+	predictionsVerdictsCmd.PersistentFlags().BoolVar(&verdictsAll,
+		"all", false,
+		"Also list slides without a verdict")
+
+	predictionsVerdictsExportCmd.Flags().StringVarP(&verdictsFile,
+		"file", "f", "",
+		"CSV file to write, default stdout")
+}
@@ -12,6 +12,13 @@ var rootCmd = &cobra.Command{
 	Use:   "go-tools",
 	Short: "Collection of tools to visualize data.",
 	Long:  `Collection of tools to visualize data.`,
+
+	// The TUI runs only without a subcommand, so that subcommands can be
+	// used from scripts
+	Run: func(cmd *cobra.Command, args []string) {
+		ui := layout.NewUI("./")
+		loops.Run(ui)
+	},
 }
 
 func Execute() {
@@ -19,8 +26,6 @@ func Execute() {
 	if err != nil {
 		os.Exit(1)
 	}
-	ui := layout.NewUI("./")
-	loops.Run(ui)
 }
 
 func init() {}
@@ -4,8 +4,12 @@ go 1.20
 
 require (
 	github.com/gdamore/tcell/v2 v2.6.0
+	github.com/klauspost/compress v1.17.9
 	github.com/rivo/tview v0.0.0-20231024211518-8b7bcf9883df
 	github.com/spf13/cobra v1.7.0
+	golang.org/x/image v0.5.0
+	golang.org/x/sys v0.5.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -15,7 +19,6 @@ require (
 	github.com/mattn/go-runewidth v0.0.14 // indirect
 	github.com/rivo/uniseg v0.4.3 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/sys v0.5.0 // indirect
 	golang.org/x/term v0.5.0 // indirect
 	golang.org/x/text v0.7.0 // indirect
 )
@@ -0,0 +1,285 @@
+package chart
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/manyids2/go-tools/tui/models/logger"
+	"github.com/rivo/tview"
+)
+
+// Colors cycled over series
+var Palette = []tcell.Color{
+	tcell.ColorGreen,
+	tcell.ColorYellow,
+	tcell.ColorAqua,
+	tcell.ColorFuchsia,
+	tcell.ColorOrange,
+	tcell.ColorBlue,
+	tcell.ColorRed,
+	tcell.ColorWhite,
+}
+
+// Bars from one to eight eighths
+var blocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// Braille dot bits, indexed by [column][row] within a cell
+var brailleBits = [2][4]rune{
+	{0x01, 0x02, 0x04, 0x40},
+	{0x08, 0x10, 0x20, 0x80},
+}
+
+// Width of the y axis labels
+const gutter = 9
+
+// Chart plots series as braille line charts or a block bar chart.
+//
+//	h, l : previous, next series (or all)
+//	b    : toggle braille / blocks
+type Chart struct {
+	*tview.Box
+	Series []logger.Series
+
+	// When set, called on every draw to refresh Series
+	Source func() []logger.Series
+
+	// Block characters instead of braille, showing one series
+	Block bool
+
+	// Index of the only series shown, -1 for all
+	Selected int
+}
+
+func NewChart() *Chart {
+	return &Chart{
+		Box:      tview.NewBox(),
+		Selected: -1,
+	}
+}
+
+// SetSeries replaces the plotted series
+func (r *Chart) SetSeries(series []logger.Series) *Chart {
+	r.Series = series
+	if r.Selected >= len(series) {
+		r.Selected = -1
+	}
+	return r
+}
+
+// shown returns the plotted series with their palette index
+func (r *Chart) shown() ([]logger.Series, []int) {
+	if len(r.Series) == 0 {
+		return nil, nil
+	}
+	if r.Selected >= 0 {
+		return r.Series[r.Selected : r.Selected+1], []int{r.Selected}
+	}
+	if r.Block {
+		return r.Series[:1], []int{0}
+	}
+	idx := make([]int, len(r.Series))
+	for i := range idx {
+		idx[i] = i
+	}
+	return r.Series, idx
+}
+
+func (r *Chart) Draw(screen tcell.Screen) {
+	r.Box.DrawForSubclass(screen, r)
+	if r.Source != nil {
+		r.SetSeries(r.Source())
+	}
+	x, y, width, height := r.GetInnerRect()
+
+	// Legend on the first row
+	legend := ""
+	for i, s := range r.Series {
+		name := tview.Escape(s.Name)
+		if i == r.Selected {
+			name = "[::r]" + name + "[::-]"
+		}
+		legend += fmt.Sprintf("[%s]■[-] %s  ", colorName(i), name)
+	}
+	if legend == "" {
+		legend = "no metrics"
+	}
+	tview.Print(screen, legend, x, y, width, tview.AlignLeft, tcell.ColorWhite)
+
+	series, colors := r.shown()
+	plotX, plotY := x+gutter, y+1
+	plotW, plotH := width-gutter, height-2
+	if len(series) == 0 || plotW < 2 || plotH < 2 {
+		return
+	}
+
+	// Ranges over all shown points
+	xmin, xmax := math.Inf(1), math.Inf(-1)
+	ymin, ymax := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for _, p := range s.Points {
+			xmin, xmax = math.Min(xmin, p.X), math.Max(xmax, p.X)
+			ymin, ymax = math.Min(ymin, p.Value), math.Max(ymax, p.Value)
+		}
+	}
+	if math.IsInf(xmin, 0) {
+		return
+	}
+	if ymax == ymin {
+		ymin, ymax = ymin-1, ymax+1
+	}
+	if xmax == xmin {
+		xmax = xmin + 1
+	}
+
+	// Axis labels
+	tview.Print(screen, fmt.Sprintf("%8.4g", ymax), x, plotY, gutter-1, tview.AlignRight, tcell.ColorGray)
+	tview.Print(screen, fmt.Sprintf("%8.4g", ymin), x, plotY+plotH-1, gutter-1, tview.AlignRight, tcell.ColorGray)
+	tview.Print(screen, fmt.Sprintf("%g", xmin), plotX, plotY+plotH, plotW, tview.AlignLeft, tcell.ColorGray)
+	tview.Print(screen, fmt.Sprintf("%g", xmax), plotX, plotY+plotH, plotW, tview.AlignRight, tcell.ColorGray)
+	for row := 0; row < plotH; row++ {
+		screen.SetContent(plotX-1, plotY+row, '│', nil, tcell.StyleDefault.Foreground(tcell.ColorGray))
+	}
+
+	if r.Block {
+		r.drawBlocks(screen, series[0], Palette[colors[0]%len(Palette)],
+			plotX, plotY, plotW, plotH, xmin, xmax, ymin, ymax)
+		return
+	}
+
+	// Braille canvas, two by four dots per cell
+	dotsW, dotsH := plotW*2, plotH*4
+	cells := make([]rune, plotW*plotH)
+	cellColor := make([]tcell.Color, plotW*plotH)
+	toDot := func(p logger.Point) (int, int) {
+		dx := int(math.Round((p.X - xmin) / (xmax - xmin) * float64(dotsW-1)))
+		dy := int(math.Round((ymax - p.Value) / (ymax - ymin) * float64(dotsH-1)))
+		return dx, dy
+	}
+	set := func(dx, dy int, color tcell.Color) {
+		if dx < 0 || dy < 0 || dx >= dotsW || dy >= dotsH {
+			return
+		}
+		i := (dy/4)*plotW + dx/2
+		cells[i] |= brailleBits[dx%2][dy%4]
+		cellColor[i] = color
+	}
+	for i, s := range series {
+		color := Palette[colors[i]%len(Palette)]
+		for j, p := range s.Points {
+			x1, y1 := toDot(p)
+			if j == 0 {
+				set(x1, y1, color)
+				continue
+			}
+			x0, y0 := toDot(s.Points[j-1])
+			line(x0, y0, x1, y1, func(dx, dy int) { set(dx, dy, color) })
+		}
+	}
+	for i, bits := range cells {
+		if bits != 0 {
+			style := tcell.StyleDefault.Foreground(cellColor[i])
+			screen.SetContent(plotX+i%plotW, plotY+i/plotW, 0x2800+bits, nil, style)
+		}
+	}
+}
+
+// drawBlocks draws one series as bars, one column per x bucket
+func (r *Chart) drawBlocks(screen tcell.Screen, s logger.Series, color tcell.Color,
+	x, y, width, height int, xmin, xmax, ymin, ymax float64) {
+	// Last value falling in each column
+	values := make([]float64, width)
+	has := make([]bool, width)
+	for _, p := range s.Points {
+		col := int(math.Round((p.X - xmin) / (xmax - xmin) * float64(width-1)))
+		values[col], has[col] = p.Value, true
+	}
+
+	style := tcell.StyleDefault.Foreground(color)
+	for col := 0; col < width; col++ {
+		if !has[col] {
+			continue
+		}
+		eighths := int(math.Round((values[col] - ymin) / (ymax - ymin) * float64(height*8)))
+		if eighths < 1 {
+			eighths = 1
+		}
+		for row := height - 1; row >= 0 && eighths > 0; row-- {
+			n := eighths
+			if n > 8 {
+				n = 8
+			}
+			screen.SetContent(x+col, y+row, blocks[n], nil, style)
+			eighths -= n
+		}
+	}
+}
+
+// line calls plot for every dot between two dots (Bresenham)
+func line(x0, y0, x1, y1 int, plot func(int, int)) {
+	dx, sx := abs(x1-x0), 1
+	if x0 > x1 {
+		sx = -1
+	}
+	dy, sy := -abs(y1-y0), 1
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		plot(x0, y0)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func colorName(i int) string {
+	return fmt.Sprintf("#%06x", Palette[i%len(Palette)].Hex())
+}
+
+func (r *Chart) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return r.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		n := len(r.Series)
+		switch event.Key() {
+		// Sane keys
+		case tcell.KeyLeft:
+			r.cycle(-1, n)
+		case tcell.KeyRight:
+			r.cycle(1, n)
+
+		// Vim keys
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'h':
+				r.cycle(-1, n)
+			case 'l':
+				r.cycle(1, n)
+			case 'b':
+				r.Block = !r.Block
+			}
+		}
+	},
+	)
+}
+
+// cycle moves Selected through -1 (all) and each series
+func (r *Chart) cycle(delta, n int) {
+	r.Selected = (r.Selected+1+delta+n+1)%(n+1) - 1
+}
@@ -0,0 +1,141 @@
+package chart
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Line of a Curve, points in order
+type Line struct {
+	Name string
+	X, Y []float64
+}
+
+// Curve plots lines in the unit square with braille dots, such as ROC
+// (false positive rate against true positive rate) or precision-recall
+// curves.
+type Curve struct {
+	*tview.Box
+	Title  string
+	XLabel string
+	YLabel string
+	Lines  []Line
+
+	// Dotted reference from (0,0) to (1,1), chance level of a ROC curve
+	Diagonal bool
+}
+
+func NewCurve() *Curve {
+	return &Curve{
+		Box: tview.NewBox(),
+	}
+}
+
+// SetLines replaces the plotted lines
+func (r *Curve) SetLines(lines ...Line) *Curve {
+	r.Lines = lines
+	return r
+}
+
+// SetLabels sets the title and axis labels
+func (r *Curve) SetLabels(title, xlabel, ylabel string) *Curve {
+	r.Title, r.XLabel, r.YLabel = title, xlabel, ylabel
+	return r
+}
+
+// SetDiagonal toggles the reference line
+func (r *Curve) SetDiagonal(diagonal bool) *Curve {
+	r.Diagonal = diagonal
+	return r
+}
+
+func (r *Curve) Draw(screen tcell.Screen) {
+	r.Box.DrawForSubclass(screen, r)
+	x, y, width, height := r.GetInnerRect()
+
+	// Title and legend on the first row
+	legend := "[::b]" + tview.Escape(r.Title) + "[::-]  "
+	for i, l := range r.Lines {
+		legend += fmt.Sprintf("[%s]■[-] %s  ", colorName(i), tview.Escape(l.Name))
+	}
+	tview.Print(screen, legend, x, y, width, tview.AlignLeft, tcell.ColorWhite)
+
+	// Square-ish plot: cells are about twice as tall as wide
+	const left = 5
+	plotH := height - 2
+	plotW := width - left
+	if plotW > plotH*2 {
+		plotW = plotH * 2
+	}
+	plotX, plotY := x+left, y+1
+	if plotW < 2 || plotH < 2 {
+		return
+	}
+
+	tview.Print(screen, "1", x, plotY, left-1, tview.AlignRight, tcell.ColorGray)
+	tview.Print(screen, "0", x, plotY+plotH-1, left-1, tview.AlignRight, tcell.ColorGray)
+	tview.Print(screen, tview.Escape(r.YLabel), x, plotY+plotH/2, left-1, tview.AlignRight, tcell.ColorGray)
+	tview.Print(screen, "0", plotX, plotY+plotH, plotW, tview.AlignLeft, tcell.ColorGray)
+	tview.Print(screen, tview.Escape(r.XLabel), plotX, plotY+plotH, plotW, tview.AlignCenter, tcell.ColorGray)
+	tview.Print(screen, "1", plotX, plotY+plotH, plotW, tview.AlignRight, tcell.ColorGray)
+	for row := 0; row < plotH; row++ {
+		screen.SetContent(plotX-1, plotY+row, '│', nil, tcell.StyleDefault.Foreground(tcell.ColorGray))
+	}
+
+	// Braille canvas, two by four dots per cell
+	dotsW, dotsH := plotW*2, plotH*4
+	cells := make([]rune, plotW*plotH)
+	cellColor := make([]tcell.Color, plotW*plotH)
+	toDot := func(px, py float64) (int, int) {
+		dx := int(math.Round(clamp01(px) * float64(dotsW-1)))
+		dy := int(math.Round((1 - clamp01(py)) * float64(dotsH-1)))
+		return dx, dy
+	}
+	set := func(dx, dy int, color tcell.Color) {
+		if dx < 0 || dy < 0 || dx >= dotsW || dy >= dotsH {
+			return
+		}
+		i := (dy/4)*plotW + dx/2
+		cells[i] |= brailleBits[dx%2][dy%4]
+		cellColor[i] = color
+	}
+
+	if r.Diagonal {
+		x1, y1 := toDot(1, 1)
+		n := 0
+		line(0, dotsH-1, x1, y1, func(dx, dy int) {
+			if n%3 == 0 {
+				set(dx, dy, tcell.ColorGray)
+			}
+			n++
+		})
+	}
+	for i, l := range r.Lines {
+		color := Palette[i%len(Palette)]
+		for j := range l.X {
+			if j >= len(l.Y) {
+				break
+			}
+			x1, y1 := toDot(l.X[j], l.Y[j])
+			if j == 0 {
+				set(x1, y1, color)
+				continue
+			}
+			x0, y0 := toDot(l.X[j-1], l.Y[j-1])
+			line(x0, y0, x1, y1, func(dx, dy int) { set(dx, dy, color) })
+		}
+	}
+	for i, bits := range cells {
+		if bits != 0 {
+			style := tcell.StyleDefault.Foreground(cellColor[i])
+			screen.SetContent(plotX+i%plotW, plotY+i/plotW, 0x2800+bits, nil, style)
+		}
+	}
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,133 @@
+package compareview
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/manyids2/go-tools/tui/models/predictions"
+	"github.com/rivo/tview"
+)
+
+// Compareview lists the slides of two prediction runs side by side with
+// their labels, scores and mask agreement. Slides in one run only are red,
+// changed labels yellow and masks that disagree magenta.
+//
+//	j, k, g, G : move
+//	enter      : select the slide
+//	o          : show only flagged slides
+type Compareview struct {
+	*tview.Table
+	Comparison predictions.Comparison
+
+	// Hide slides that need no look
+	OnlyFlagged bool
+
+	// Slides in row order, below the header
+	shown    []predictions.SlideChange
+	selected func(s predictions.SlideChange)
+}
+
+func NewCompareview() *Compareview {
+	r := &Compareview{
+		Table:       tview.NewTable(),
+		OnlyFlagged: true,
+	}
+	r.SetFixed(1, 0).SetSelectable(true, false)
+	r.Table.SetSelectedFunc(func(row, column int) {
+		if row >= 1 && row-1 < len(r.shown) && r.selected != nil {
+			r.selected(r.shown[row-1])
+		}
+	})
+	return r
+}
+
+// SetComparison replaces the shown comparison
+func (r *Compareview) SetComparison(c predictions.Comparison) *Compareview {
+	r.Comparison = c
+	r.refresh()
+	r.Select(1, 0).ScrollToBeginning()
+	return r
+}
+
+// SetSelectedFunc sets the handler called when a slide is selected.
+func (r *Compareview) SetSelectedFunc(handler func(s predictions.SlideChange)) *Compareview {
+	r.selected = handler
+	return r
+}
+
+// refresh fills the table from Comparison
+func (r *Compareview) refresh() {
+	r.Clear()
+	c := r.Comparison
+	r.SetTitle(fmt.Sprintf(" %d slides, %d only A, %d only B, %d changed, %d masks < %.2f ",
+		len(c.Slides), c.OnlyA, c.OnlyB, c.Changed, c.LowAgreement, c.Threshold))
+
+	for col, h := range []string{"GROUP", "SLIDE", "LABEL A", "LABEL B", "SCORE A", "SCORE B", "DELTA", "DICE"} {
+		r.SetCell(0, col, tview.NewTableCell(h).SetAttributes(tcell.AttrBold).SetSelectable(false))
+	}
+
+	r.shown = nil
+	for _, s := range c.Slides {
+		if r.OnlyFlagged && !s.Flagged() {
+			continue
+		}
+		r.shown = append(r.shown, s)
+		row := len(r.shown)
+
+		color := tcell.ColorWhite
+		switch {
+		case s.Missing():
+			color = tcell.ColorRed
+		case s.LabelChanged():
+			color = tcell.ColorYellow
+		case s.LowAgreement || s.MaskErr != "":
+			color = tcell.ColorFuchsia
+		}
+		delta := s.ScoreDelta()
+		cells := []string{
+			s.Group, s.Slide,
+			orDash(s.LabelA), orDash(s.LabelB),
+			value(s.ScoreA, s.HasScoreA), value(s.ScoreB, s.HasScoreB),
+			signed(delta, !math.IsNaN(delta)), value(s.Dice, s.HasMask),
+		}
+		for col, text := range cells {
+			r.SetCell(row, col, tview.NewTableCell(tview.Escape(text)).SetTextColor(color))
+		}
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func value(v float64, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.4f", v)
+}
+
+func signed(v float64, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%+.4f", v)
+}
+
+func (r *Compareview) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return r.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'o' {
+			r.OnlyFlagged = !r.OnlyFlagged
+			r.refresh()
+			r.Select(1, 0).ScrollToBeginning()
+			return
+		}
+		if handler := r.Table.InputHandler(); handler != nil {
+			handler(event, setFocus)
+		}
+	})
+}
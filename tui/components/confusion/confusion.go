@@ -0,0 +1,179 @@
+package confusion
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Confusion shows a confusion matrix as a grid of counts, true classes as
+// rows and predicted classes as columns. Correct cells are shaded green and
+// errors red, darker the larger their share of the row.
+//
+//	h, j, k, l : move between cells
+//	enter      : select the cell
+type Confusion struct {
+	*tview.Box
+	Classes []string
+	Counts  [][]int
+
+	// Cell under the cursor
+	Row, Col int
+
+	// Called with the true and predicted class of a cell
+	changed  func(row, col string)
+	selected func(row, col string)
+}
+
+func NewConfusion() *Confusion {
+	return &Confusion{
+		Box: tview.NewBox(),
+	}
+}
+
+// SetMatrix replaces the classes and counts, Counts[true][predicted]
+func (r *Confusion) SetMatrix(classes []string, counts [][]int) *Confusion {
+	r.Classes, r.Counts = classes, counts
+	if r.Row >= len(classes) {
+		r.Row = 0
+	}
+	if r.Col >= len(classes) {
+		r.Col = 0
+	}
+	return r
+}
+
+// SetChangedFunc sets the handler called when the cursor moves to a cell.
+func (r *Confusion) SetChangedFunc(handler func(row, col string)) *Confusion {
+	r.changed = handler
+	return r
+}
+
+// SetSelectedFunc sets the handler called when a cell is selected.
+func (r *Confusion) SetSelectedFunc(handler func(row, col string)) *Confusion {
+	r.selected = handler
+	return r
+}
+
+// Current returns the true and predicted class of the cell under the cursor
+func (r *Confusion) Current() (string, string, bool) {
+	if len(r.Classes) == 0 {
+		return "", "", false
+	}
+	return r.Classes[r.Row], r.Classes[r.Col], true
+}
+
+func (r *Confusion) Draw(screen tcell.Screen) {
+	r.Box.DrawForSubclass(screen, r)
+	x, y, width, height := r.GetInnerRect()
+	n := len(r.Classes)
+	if n == 0 {
+		tview.Print(screen, "no classes", x, y, width, tview.AlignLeft, tcell.ColorGray)
+		return
+	}
+
+	// Columns as wide as the longest class name or count
+	cellW := 5
+	for i, c := range r.Classes {
+		if len(c)+1 > cellW {
+			cellW = len(c) + 1
+		}
+		for _, v := range r.Counts[i] {
+			if w := len(fmt.Sprint(v)) + 2; w > cellW {
+				cellW = w
+			}
+		}
+	}
+	labelW := cellW
+	if labelW > width/(n+1) && width/(n+1) > 3 {
+		labelW = width / (n + 1)
+	}
+
+	tview.Print(screen, "[::d]true\\pred[::-]", x, y, labelW, tview.AlignLeft, tcell.ColorWhite)
+	for j, c := range r.Classes {
+		tview.Print(screen, tview.Escape(c), x+labelW+j*cellW, y, cellW, tview.AlignCenter, tcell.ColorWhite)
+	}
+	for i, c := range r.Classes {
+		if 1+i >= height {
+			break
+		}
+		tview.Print(screen, tview.Escape(c), x, y+1+i, labelW, tview.AlignLeft, tcell.ColorWhite)
+
+		total := 0
+		for _, v := range r.Counts[i] {
+			total += v
+		}
+		for j, v := range r.Counts[i] {
+			share := 0.0
+			if total > 0 {
+				share = float64(v) / float64(total)
+			}
+			style := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(shade(i == j, share))
+			if i == r.Row && j == r.Col && r.HasFocus() {
+				style = style.Reverse(true).Bold(true)
+			}
+			text := fmt.Sprintf("%*d ", cellW-1, v)
+			for k, ch := range text {
+				screen.SetContent(x+labelW+j*cellW+k, y+1+i, ch, nil, style)
+			}
+		}
+	}
+}
+
+// shade of green for correct cells, red for errors, by share of the row
+func shade(correct bool, share float64) tcell.Color {
+	if share == 0 {
+		return tcell.NewRGBColor(30, 30, 30)
+	}
+	level := int32(40 + share*160)
+	if correct {
+		return tcell.NewRGBColor(0, level, 0)
+	}
+	return tcell.NewRGBColor(level, 0, 0)
+}
+
+func (r *Confusion) move(drow, dcol int) {
+	n := len(r.Classes)
+	if n == 0 {
+		return
+	}
+	r.Row = (r.Row + drow + n) % n
+	r.Col = (r.Col + dcol + n) % n
+	if r.changed != nil {
+		r.changed(r.Classes[r.Row], r.Classes[r.Col])
+	}
+}
+
+func (r *Confusion) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return r.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		switch event.Key() {
+		// Sane keys
+		case tcell.KeyUp:
+			r.move(-1, 0)
+		case tcell.KeyDown:
+			r.move(1, 0)
+		case tcell.KeyLeft:
+			r.move(0, -1)
+		case tcell.KeyRight:
+			r.move(0, 1)
+		case tcell.KeyEnter:
+			if row, col, ok := r.Current(); ok && r.selected != nil {
+				r.selected(row, col)
+			}
+
+		// Vim keys
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'k':
+				r.move(-1, 0)
+			case 'j':
+				r.move(1, 0)
+			case 'h':
+				r.move(0, -1)
+			case 'l':
+				r.move(0, 1)
+			}
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package diffview
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/manyids2/go-tools/tui/models/logger"
+	"github.com/rivo/tview"
+)
+
+// One aligned row of the two panes
+type row struct {
+	left, right string
+}
+
+// Diffview lines up two runs side by side: metrics with their change, then
+// message templates with their counts, then the lines whose template is
+// found in one run only. Those of A are red on the left and those of B green
+// on the right.
+//
+//	j, k, g, G : move
+//	c-d, c-u   : page down, up
+//	o          : show only templates found in one run
+type Diffview struct {
+	*tview.Box
+	Diff logger.Diff
+
+	// Hide templates found in both runs
+	OnlyChanged bool
+
+	rows   []row
+	offset int
+	height int
+}
+
+func NewDiffview() *Diffview {
+	return &Diffview{
+		Box: tview.NewBox(),
+	}
+}
+
+// SetDiff replaces the shown diff
+func (r *Diffview) SetDiff(d logger.Diff) *Diffview {
+	r.Diff = d
+	r.offset = 0
+	r.refresh()
+	return r
+}
+
+// refresh builds the rows from Diff
+func (r *Diffview) refresh() {
+	r.rows = nil
+	d := r.Diff
+
+	r.rows = append(r.rows, row{"[::b]Metrics[::-]", "[::b]Metrics[::-]"})
+	for _, m := range d.Metrics {
+		left, right := fmt.Sprintf("%-16s %s", tview.Escape(m.Name), value(m.A, m.HasA)), value(m.B, m.HasB)
+		delta := m.Delta()
+		switch {
+		case math.IsNaN(delta):
+			right = "[yellow]" + right + "[-]"
+		case delta != 0:
+			right += fmt.Sprintf("  [yellow]%+.6g[-]", delta)
+		}
+		r.rows = append(r.rows, row{left, right})
+	}
+
+	r.rows = append(r.rows, row{}, row{"[::b]Templates[::-]", "[::b]Templates[::-]"})
+	for _, t := range d.Templates {
+		if r.OnlyChanged && !t.OnlyA() && !t.OnlyB() {
+			continue
+		}
+		left, right := "", ""
+		if t.CountA > 0 {
+			left = fmt.Sprintf("%6d  %s", t.CountA, tview.Escape(t.ExampleA))
+		}
+		if t.CountB > 0 {
+			right = fmt.Sprintf("%6d  %s", t.CountB, tview.Escape(t.ExampleB))
+		}
+		switch {
+		case t.OnlyA():
+			left = "[red]" + left + "[-]"
+		case t.OnlyB():
+			right = "[green]" + right + "[-]"
+		}
+		r.rows = append(r.rows, row{left, right})
+	}
+
+	// Lines of either run whose template the other lacks
+	if len(d.OnlyA) == 0 && len(d.OnlyB) == 0 {
+		return
+	}
+	r.rows = append(r.rows, row{},
+		row{fmt.Sprintf("[::b]Only in A (%d)[::-]", len(d.OnlyA)), fmt.Sprintf("[::b]Only in B (%d)[::-]", len(d.OnlyB))})
+	for i := 0; i < len(d.OnlyA) || i < len(d.OnlyB); i++ {
+		left, right := "", ""
+		if i < len(d.OnlyA) {
+			left = "[red]" + tview.Escape(d.OnlyA[i].String()) + "[-]"
+		}
+		if i < len(d.OnlyB) {
+			right = "[green]" + tview.Escape(d.OnlyB[i].String()) + "[-]"
+		}
+		r.rows = append(r.rows, row{left, right})
+	}
+}
+
+func value(v float64, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.6g", v)
+}
+
+func (r *Diffview) move(delta int) {
+	r.offset += delta
+	if last := len(r.rows) - r.height; r.offset > last {
+		r.offset = last
+	}
+	if r.offset < 0 {
+		r.offset = 0
+	}
+}
+
+func (r *Diffview) Draw(screen tcell.Screen) {
+	r.Box.DrawForSubclass(screen, r)
+	x, y, width, height := r.GetInnerRect()
+	if height < 2 {
+		return
+	}
+
+	// Header with both runs, then the panes split by a line
+	half := (width - 1) / 2
+	tview.Print(screen, "[::b]A:[::-] "+tview.Escape(r.Diff.A), x, y, half, tview.AlignLeft, tcell.ColorWhite)
+	tview.Print(screen, "[::b]B:[::-] "+tview.Escape(r.Diff.B), x+half+1, y, width-half-1, tview.AlignLeft, tcell.ColorWhite)
+
+	r.height = height - 1
+	r.move(0)
+	for i := 0; i < r.height; i++ {
+		screen.SetContent(x+half, y+1+i, tview.BoxDrawingsLightVertical, nil, tcell.StyleDefault.Foreground(tcell.ColorGray))
+		if r.offset+i >= len(r.rows) {
+			continue
+		}
+		row := r.rows[r.offset+i]
+		tview.Print(screen, row.left, x, y+1+i, half, tview.AlignLeft, tcell.ColorWhite)
+		tview.Print(screen, row.right, x+half+1, y+1+i, width-half-1, tview.AlignLeft, tcell.ColorWhite)
+	}
+}
+
+func (r *Diffview) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return r.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		switch event.Key() {
+		// Sane keys
+		case tcell.KeyUp:
+			r.move(-1)
+		case tcell.KeyDown:
+			r.move(1)
+		case tcell.KeyHome:
+			r.move(-len(r.rows))
+		case tcell.KeyEnd:
+			r.move(len(r.rows))
+		case tcell.KeyPgUp, tcell.KeyCtrlU:
+			r.move(-r.height)
+		case tcell.KeyPgDn, tcell.KeyCtrlD:
+			r.move(r.height)
+
+		// Vim keys
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'k':
+				r.move(-1)
+			case 'j':
+				r.move(1)
+			case 'g':
+				r.move(-len(r.rows))
+			case 'G':
+				r.move(len(r.rows))
+			case 'o':
+				r.OnlyChanged = !r.OnlyChanged
+				r.refresh()
+				r.move(0)
+			}
+		}
+	})
+}
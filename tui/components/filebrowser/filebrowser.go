@@ -12,6 +12,12 @@ type Filebrowser struct {
 	*tview.Box
 	Datadir string
 	Tree    *tview.TreeView
+
+	// Called when a file (not a directory) is selected
+	fileSelected func(path string)
+
+	// Called when the root node is selected
+	rootSelected func(path string)
 }
 
 func (r *Filebrowser) Draw(screen tcell.Screen) {
@@ -22,6 +28,37 @@ func (r *Filebrowser) InputHandler() func(event *tcell.EventKey, setFocus func(p
 	return r.Tree.InputHandler()
 }
 
+// SetFileSelectedFunc sets the handler called when a file is selected.
+func (r *Filebrowser) SetFileSelectedFunc(handler func(path string)) *Filebrowser {
+	r.fileSelected = handler
+	return r
+}
+
+// SetRootSelectedFunc sets the handler called when the root node is selected.
+func (r *Filebrowser) SetRootSelectedFunc(handler func(path string)) *Filebrowser {
+	r.rootSelected = handler
+	return r
+}
+
+// CurrentPath returns the path of the node under the cursor.
+func (r *Filebrowser) CurrentPath() string {
+	node := r.Tree.GetCurrentNode()
+	if node == nil || node.GetReference() == nil {
+		return r.Datadir
+	}
+	return node.GetReference().(string)
+}
+
+// CurrentDir returns the directory under the cursor, or the one holding the
+// file under it.
+func (r *Filebrowser) CurrentDir() string {
+	path := r.CurrentPath()
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return filepath.Dir(path)
+	}
+	return path
+}
+
 func NewFilebrowser(datadir string) *Filebrowser {
 	root := tview.NewTreeNode(datadir).
 		SetColor(tcell.ColorRed)
@@ -38,8 +75,7 @@ func NewFilebrowser(datadir string) *Filebrowser {
 		}
 		for _, file := range files {
 			node := tview.NewTreeNode(file.Name()).
-				SetReference(filepath.Join(path, file.Name())).
-				SetSelectable(file.IsDir())
+				SetReference(filepath.Join(path, file.Name()))
 			if file.IsDir() {
 				node.SetColor(tcell.ColorGreen)
 			}
@@ -50,16 +86,27 @@ func NewFilebrowser(datadir string) *Filebrowser {
 	// Add the current directory to the root node.
 	add(root, datadir)
 
+	fb := &Filebrowser{Box: tree.Box, Datadir: datadir, Tree: tree}
+
 	// If a directory was selected, open it.
 	tree.SetSelectedFunc(func(node *tview.TreeNode) {
 		reference := node.GetReference()
 		if reference == nil {
-			return // Selecting the root node does nothing.
+			if fb.rootSelected != nil {
+				fb.rootSelected(datadir)
+			}
+			return
+		}
+		path := reference.(string)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			if fb.fileSelected != nil {
+				fb.fileSelected(path)
+			}
+			return
 		}
 		children := node.GetChildren()
 		if len(children) == 0 {
 			// Load and show files in this directory.
-			path := reference.(string)
 			add(node, path)
 		} else {
 			// Collapse if visible, expand if collapsed.
@@ -69,5 +116,5 @@ func NewFilebrowser(datadir string) *Filebrowser {
 
 	tree.SetBorder(true)
 
-	return &Filebrowser{Datadir: datadir, Tree: tree}
+	return fb
 }
@@ -0,0 +1,559 @@
+package logview
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/manyids2/go-tools/tui/models/logger"
+	"github.com/rivo/tview"
+)
+
+// Records kept while following, older ones are dropped
+const maxFollowed = 10000
+
+// Input modes
+const (
+	modeNormal = iota
+	modeSearch
+	modeTime
+)
+
+// Colors per level
+var LevelColors = map[logger.Level]string{
+	logger.LevelUnknown: "white",
+	logger.LevelTrace:   "gray",
+	logger.LevelDebug:   "blue",
+	logger.LevelInfo:    "green",
+	logger.LevelWarn:    "yellow",
+	logger.LevelError:   "red",
+	logger.LevelFatal:   "fuchsia",
+}
+
+// Logview shows parsed records with level filters, regex search and
+// jump-to-timestamp.
+//
+//	j, k, g, G : move, G also resumes following
+//	c-d, c-u   : page down, up
+//	/, c-f     : incremental regex search; n, N : next, previous match
+//	1 - 6      : toggle trace, debug, info, warn, error, fatal
+//	0          : toggle lines without level
+//	t          : jump to timestamp
+type Logview struct {
+	*tview.Box
+	Records []logger.Record
+
+	// Levels currently hidden
+	Hidden map[logger.Level]bool
+
+	// Show the base name of each record's file, for merged timelines
+	ShowFile bool
+
+	// Rendered lines, parallel to Records
+	lines []string
+
+	// Indices of shown records, position in visible, first drawn row
+	visible []int
+	cursor  int
+	offset  int
+
+	// Keep cursor on the last record as records arrive
+	following bool
+	follower  *logger.Follower
+
+	// Search and prompt state
+	mode    int
+	input   string
+	search  *regexp.Regexp
+	message string
+	height  int
+}
+
+func NewLogview() *Logview {
+	return &Logview{
+		Box:       tview.NewBox(),
+		Hidden:    make(map[logger.Level]bool),
+		following: true,
+	}
+}
+
+// SetRecords replaces the shown records
+func (r *Logview) SetRecords(records []logger.Record) *Logview {
+	r.Records = nil
+	r.lines = nil
+	r.visible = nil
+	r.cursor, r.offset = 0, 0
+	r.Append(records...)
+	return r
+}
+
+// Append adds records at the end, keeping the cursor at the end if following
+func (r *Logview) Append(records ...logger.Record) {
+	for _, rec := range records {
+		r.Records = append(r.Records, rec)
+		r.lines = append(r.lines, r.render(rec))
+		if !r.Hidden[rec.Level] {
+			r.visible = append(r.visible, len(r.Records)-1)
+		}
+	}
+	if r.following {
+		r.cursor = len(r.visible) - 1
+	}
+}
+
+// trim drops the oldest records beyond the last n
+func (r *Logview) trim(n int) {
+	drop := len(r.Records) - n
+	if drop <= 0 {
+		return
+	}
+	r.Records = r.Records[drop:]
+	r.lines = r.lines[drop:]
+
+	// Shift the shown records, along with the cursor and first drawn row
+	visible := r.visible[:0]
+	gone := 0
+	for _, i := range r.visible {
+		if i < drop {
+			gone++
+			continue
+		}
+		visible = append(visible, i-drop)
+	}
+	r.visible = visible
+	r.cursor -= gone
+	r.offset -= gone
+	if r.cursor < 0 {
+		r.cursor = 0
+	}
+	if r.offset < 0 {
+		r.offset = 0
+	}
+}
+
+// render formats a record as plain text, with its file after the level
+func (r *Logview) render(rec logger.Record) string {
+	line := rec.String()
+	if !r.ShowFile {
+		return line
+	}
+	_, i := headerEnds(rec, line)
+	if i < len(line) {
+		i++
+	}
+	return line[:i] + filepath.Base(rec.File) + ": " + line[i:]
+}
+
+// headerEnds returns where the timestamp and level columns of a rendered
+// record end
+func headerEnds(rec logger.Record, line string) (int, int) {
+	tsEnd := 1
+	if !rec.Time.IsZero() {
+		tsEnd = len("2006-01-02 15:04:05.000")
+	}
+	lvlEnd := tsEnd + 6
+	if lvlEnd > len(line) {
+		lvlEnd = len(line)
+	}
+	return tsEnd, lvlEnd
+}
+
+// Follow streams records of f into the view, replacing the current records.
+// draw must run its argument on the application goroutine and redraw.
+func (r *Logview) Follow(f *logger.Follower, draw func(func())) {
+	r.Stop()
+	r.SetRecords(nil)
+	r.following = true
+	r.follower = f
+
+	records := f.Subscribe()
+	go f.Run()
+	go func() {
+		for rec := range records {
+			batch := []logger.Record{rec}
+
+			// Batch whatever else is already queued into one redraw
+			for more := true; more; {
+				select {
+				case rec, ok := <-records:
+					if !ok {
+						more = false
+						break
+					}
+					batch = append(batch, rec)
+				default:
+					more = false
+				}
+			}
+			draw(func() {
+				if r.follower == f {
+					r.Append(batch...)
+					r.trim(maxFollowed)
+				}
+			})
+		}
+	}()
+}
+
+// Stop ends the current follower
+func (r *Logview) Stop() {
+	if r.follower != nil {
+		r.follower.Stop()
+		r.follower = nil
+	}
+}
+
+// ToggleLevel shows or hides records of a level
+func (r *Logview) ToggleLevel(level logger.Level) {
+	r.Hidden[level] = !r.Hidden[level]
+	r.refilter()
+}
+
+// refilter rebuilds visible, keeping the cursor on the same record if shown
+func (r *Logview) refilter() {
+	current := r.current()
+	r.visible = r.visible[:0]
+	r.cursor = 0
+	for i, rec := range r.Records {
+		if r.Hidden[rec.Level] {
+			continue
+		}
+		if i <= current {
+			r.cursor = len(r.visible)
+		}
+		r.visible = append(r.visible, i)
+	}
+	if r.following {
+		r.cursor = len(r.visible) - 1
+	}
+}
+
+// current returns the index into Records under the cursor, or -1
+func (r *Logview) current() int {
+	if r.cursor < 0 || r.cursor >= len(r.visible) {
+		return -1
+	}
+	return r.visible[r.cursor]
+}
+
+func (r *Logview) move(delta int) {
+	r.cursor += delta
+	if r.cursor >= len(r.visible) {
+		r.cursor = len(r.visible) - 1
+	}
+	if r.cursor < 0 {
+		r.cursor = 0
+	}
+	r.following = r.cursor == len(r.visible)-1
+}
+
+// findMatch moves to the next (dir 1) or previous (dir -1) matching record,
+// starting at the cursor itself when inclusive is set
+func (r *Logview) findMatch(dir int, inclusive bool) bool {
+	if r.search == nil || len(r.visible) == 0 {
+		return false
+	}
+	start := r.cursor
+	if !inclusive {
+		start += dir
+	}
+	n := len(r.visible)
+	for i := 0; i < n; i++ {
+		pos := ((start+dir*i)%n + n) % n
+		if r.search.MatchString(r.lines[r.visible[pos]]) {
+			r.cursor = pos
+			r.following = false
+			return true
+		}
+	}
+	return false
+}
+
+// JumpToTime moves to the first shown record at or after t. Only records
+// with a timestamp are searched, lines without one being out of order.
+func (r *Logview) JumpToTime(t time.Time) bool {
+	var timed []int
+	for pos, i := range r.visible {
+		if !r.Records[i].Time.IsZero() {
+			timed = append(timed, pos)
+		}
+	}
+	k := sort.Search(len(timed), func(k int) bool {
+		return !r.Records[r.visible[timed[k]]].Time.Before(t)
+	})
+	if k == len(timed) {
+		return false
+	}
+	r.cursor = timed[k]
+	r.following = false
+	return true
+}
+
+// parseJumpTime accepts full timestamps, or a time of day on the date of
+// the record under the cursor
+func (r *Logview) parseJumpTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if t := logger.ParseTime(s); !t.IsZero() {
+		return t, true
+	}
+	clock, err := time.ParseInLocation("15:04:05", s, time.Local)
+	if err != nil {
+		if clock, err = time.ParseInLocation("15:04", s, time.Local); err != nil {
+			return time.Time{}, false
+		}
+	}
+	ref := time.Now()
+	if i := r.current(); i >= 0 && !r.Records[i].Time.IsZero() {
+		ref = r.Records[i].Time
+	}
+	y, m, d := ref.Date()
+	return time.Date(y, m, d, clock.Hour(), clock.Minute(), clock.Second(), 0, ref.Location()), true
+}
+
+func (r *Logview) Draw(screen tcell.Screen) {
+	r.Box.DrawForSubclass(screen, r)
+	x, y, width, height := r.GetInnerRect()
+	if height < 2 {
+		return
+	}
+
+	// Last row is the prompt / status line
+	rows := height - 1
+	r.height = rows
+	if r.cursor < r.offset {
+		r.offset = r.cursor
+	}
+	if r.cursor >= r.offset+rows {
+		r.offset = r.cursor - rows + 1
+	}
+	if r.offset < 0 {
+		r.offset = 0
+	}
+
+	for row := 0; row < rows && r.offset+row < len(r.visible); row++ {
+		pos := r.offset + row
+		idx := r.visible[pos]
+		line := r.taggedLine(idx, pos == r.cursor && r.HasFocus())
+		tview.Print(screen, line, x, y+row, width, tview.AlignLeft, tcell.ColorWhite)
+	}
+
+	tview.Print(screen, r.statusLine(), x, y+rows, width, tview.AlignLeft, tcell.ColorWhite)
+}
+
+// taggedLine renders a record with level colors and search highlights
+func (r *Logview) taggedLine(idx int, selected bool) string {
+	rec := r.Records[idx]
+	plain := r.lines[idx]
+
+	// Tags per byte range: timestamp, level, rest, and search matches
+	color := LevelColors[rec.Level]
+	rest := "white"
+	if rec.Level >= logger.LevelError {
+		rest = color
+	}
+	bg := "-"
+	if selected {
+		bg = "darkslategray"
+	}
+	tsTag, lvlTag, restTag := "[gray:"+bg+"]", "["+color+":"+bg+"]", "["+rest+":"+bg+"]"
+	const matchTag = "[black:yellow]"
+	tsEnd, lvlEnd := headerEnds(rec, plain)
+
+	var matches [][]int
+	if r.search != nil {
+		matches = r.search.FindAllStringIndex(plain, -1)
+	}
+
+	// Emit a tag every time the style changes
+	var b strings.Builder
+	prev := ""
+	start := 0
+	flush := func(end int, tag string) {
+		if end > start {
+			b.WriteString(prev)
+			b.WriteString(tview.Escape(plain[start:end]))
+		}
+		start, prev = end, tag
+	}
+	m := 0
+	for i := range plain {
+		for m < len(matches) && i >= matches[m][1] {
+			m++
+		}
+		var tag string
+		switch {
+		case m < len(matches) && i >= matches[m][0]:
+			tag = matchTag
+		case i < tsEnd:
+			tag = tsTag
+		case i < lvlEnd:
+			tag = lvlTag
+		default:
+			tag = restTag
+		}
+		if tag != prev {
+			flush(i, tag)
+		}
+	}
+	flush(len(plain), "")
+	return b.String()
+}
+
+func (r *Logview) statusLine() string {
+	switch r.mode {
+	case modeSearch:
+		return "[yellow]/[-]" + tview.Escape(r.input) + "_"
+	case modeTime:
+		return "[yellow]time:[-] " + tview.Escape(r.input) + "_"
+	}
+
+	// Level toggles, lit when shown
+	levels := ""
+	for i, level := range append([]logger.Level{logger.LevelUnknown}, logger.Levels...) {
+		color := LevelColors[level]
+		if r.Hidden[level] {
+			color = "darkgray"
+		}
+		levels += fmt.Sprintf("[%s]%d:%s[-] ", color, i, level)
+	}
+
+	status := fmt.Sprintf("%d/%d ", r.cursor+1, len(r.visible))
+	if r.following {
+		status += "[green]follow[-] "
+	}
+	if r.message != "" {
+		status += "[red]" + tview.Escape(r.message) + "[-] "
+	}
+	return status + levels
+}
+
+func (r *Logview) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return r.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if r.mode != modeNormal {
+			r.handlePrompt(event)
+			return
+		}
+		r.message = ""
+
+		switch event.Key() {
+		// Sane keys
+		case tcell.KeyUp:
+			r.move(-1)
+		case tcell.KeyDown:
+			r.move(1)
+		case tcell.KeyHome:
+			r.move(-len(r.visible))
+		case tcell.KeyEnd:
+			r.move(len(r.visible))
+		case tcell.KeyPgUp, tcell.KeyCtrlU:
+			r.move(-r.page())
+		case tcell.KeyPgDn, tcell.KeyCtrlD:
+			r.move(r.page())
+		case tcell.KeyCtrlF:
+			r.startPrompt(modeSearch)
+
+		// Vim keys
+		case tcell.KeyRune:
+			switch ch := event.Rune(); ch {
+			case 'k':
+				r.move(-1)
+			case 'j':
+				r.move(1)
+			case 'g':
+				r.move(-len(r.visible))
+			case 'G':
+				r.move(len(r.visible))
+			case '/':
+				r.startPrompt(modeSearch)
+			case 'n':
+				if !r.findMatch(1, false) && r.search != nil {
+					r.message = "no match"
+				}
+			case 'N':
+				if !r.findMatch(-1, false) && r.search != nil {
+					r.message = "no match"
+				}
+			case 't':
+				r.startPrompt(modeTime)
+			case '0', '1', '2', '3', '4', '5', '6':
+				level := logger.LevelUnknown
+				if ch != '0' {
+					level = logger.Levels[ch-'1']
+				}
+				r.ToggleLevel(level)
+			}
+		}
+	})
+}
+
+// Prompting reports whether keys currently go to the search or time prompt
+func (r *Logview) Prompting() bool {
+	return r.mode != modeNormal
+}
+
+func (r *Logview) page() int {
+	if r.height > 1 {
+		return r.height - 1
+	}
+	return 1
+}
+
+func (r *Logview) startPrompt(mode int) {
+	r.mode = mode
+	r.input = ""
+	r.message = ""
+}
+
+// handlePrompt edits the prompt; search updates on every keystroke
+func (r *Logview) handlePrompt(event *tcell.EventKey) {
+	switch event.Key() {
+	case tcell.KeyEscape:
+		if r.mode == modeSearch {
+			r.search = nil
+		}
+		r.mode = modeNormal
+		return
+	case tcell.KeyEnter:
+		if r.mode == modeTime {
+			if t, ok := r.parseJumpTime(r.input); !ok {
+				r.message = "bad time: " + r.input
+			} else if !r.JumpToTime(t) {
+				r.message = "no record after " + r.input
+			}
+		}
+		r.mode = modeNormal
+		return
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(r.input) > 0 {
+			runes := []rune(r.input)
+			r.input = string(runes[:len(runes)-1])
+		}
+	case tcell.KeyRune:
+		r.input += string(event.Rune())
+	default:
+		return
+	}
+
+	if r.mode == modeSearch {
+		r.updateSearch()
+	}
+}
+
+// updateSearch compiles the prompt, jumping to the first match from the cursor
+func (r *Logview) updateSearch() {
+	if r.input == "" {
+		r.search = nil
+		return
+	}
+	re, err := regexp.Compile(r.input)
+	if err != nil {
+		// Keep the last valid pattern while typing
+		return
+	}
+	r.search = re
+	r.findMatch(1, true)
+}
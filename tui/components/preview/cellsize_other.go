@@ -0,0 +1,8 @@
+//go:build !unix
+
+package preview
+
+// cellSize returns the size of a terminal cell in pixels
+func cellSize() (int, int) {
+	return defaultCellWidth, defaultCellHeight
+}
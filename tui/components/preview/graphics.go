@@ -0,0 +1,151 @@
+package preview
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+)
+
+// Kitty image id used for every placement, so a new image replaces the old
+const kittyID = 1
+
+var kittyDelete = fmt.Sprintf("\x1b_Ga=d,d=I,i=%d,q=2\x1b\\", kittyID)
+
+// Largest side in pixels sent to the terminal, kitty scales it to the cells
+const kittyMaxSide = 1024
+
+// writeKitty places img over cols by rows cells at x, y (zero based) using
+// the kitty graphics protocol
+func writeKitty(w io.Writer, img *image.RGBA, x, y, cols, rows int) error {
+	b := img.Bounds()
+	if b.Dx() > kittyMaxSide || b.Dy() > kittyMaxSide {
+		sw, sh := kittyMaxSide, b.Dy()*kittyMaxSide/b.Dx()
+		if b.Dy() > b.Dx() {
+			sw, sh = b.Dx()*kittyMaxSide/b.Dy(), kittyMaxSide
+		}
+		img = scale(img, sw, sh)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	out := bufio.NewWriter(w)
+	fmt.Fprint(out, kittyDelete)
+	fmt.Fprintf(out, "\x1b7\x1b[%d;%dH", y+1, x+1)
+
+	// Payload in chunks of at most 4096 bytes, m=1 while more follow
+	const chunk = 4096
+	for i := 0; i < len(data); i += chunk {
+		end := i + chunk
+		if end > len(data) {
+			end = len(data)
+		}
+		more := 0
+		if end < len(data) {
+			more = 1
+		}
+		if i == 0 {
+			fmt.Fprintf(out, "\x1b_Gf=100,a=T,i=%d,c=%d,r=%d,C=1,q=2,m=%d;%s\x1b\\", kittyID, cols, rows, more, data[i:end])
+		} else {
+			fmt.Fprintf(out, "\x1b_Gm=%d;%s\x1b\\", more, data[i:end])
+		}
+	}
+	fmt.Fprint(out, "\x1b8")
+	return out.Flush()
+}
+
+// writeSixel draws img at cell x, y (zero based) as sixels with a 6x6x6
+// color cube
+func writeSixel(w io.Writer, img *image.RGBA, x, y int) error {
+	sw, sh := img.Bounds().Dx(), img.Bounds().Dy()
+	if sw < 1 || sh < 1 {
+		return nil
+	}
+
+	// Palette index of every pixel
+	level := func(v uint8) int { return (int(v)*5 + 127) / 255 }
+	pix := make([]int, sw*sh)
+	for py := 0; py < sh; py++ {
+		for px := 0; px < sw; px++ {
+			c := img.RGBAAt(px, py)
+			pix[py*sw+px] = level(c.R)*36 + level(c.G)*6 + level(c.B)
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "\x1b7\x1b[%d;%dH", y+1, x+1)
+	fmt.Fprintf(out, "\x1bP0;1q\"1;1;%d;%d", sw, sh)
+	for i := 0; i < 216; i++ {
+		r, g, bl := i/36, i/6%6, i%6
+		fmt.Fprintf(out, "#%d;2;%d;%d;%d", i, r*20, g*20, bl*20)
+	}
+
+	// Bands of six rows, one pass per color used in the band
+	bits := make([]byte, sw)
+	for top := 0; top < sh; top += 6 {
+		used := map[int]bool{}
+		var order []int
+		for py := top; py < top+6 && py < sh; py++ {
+			for px := 0; px < sw; px++ {
+				if c := pix[py*sw+px]; !used[c] {
+					used[c] = true
+					order = append(order, c)
+				}
+			}
+		}
+		for n, c := range order {
+			for px := range bits {
+				bits[px] = 0
+				for k := 0; k < 6 && top+k < sh; k++ {
+					if pix[(top+k)*sw+px] == c {
+						bits[px] |= 1 << k
+					}
+				}
+			}
+			if n > 0 {
+				out.WriteByte('$')
+			}
+			fmt.Fprintf(out, "#%d", c)
+			writeRuns(out, bits)
+		}
+		out.WriteByte('-')
+	}
+	fmt.Fprint(out, "\x1b\\\x1b8")
+	return out.Flush()
+}
+
+// writeRuns writes sixel bits with repeats compressed as !<count><char>
+func writeRuns(out *bufio.Writer, bits []byte) {
+	for i := 0; i < len(bits); {
+		j := i
+		for j < len(bits) && bits[j] == bits[i] {
+			j++
+		}
+		ch := 63 + bits[i]
+		if n := j - i; n > 3 {
+			fmt.Fprintf(out, "!%d%c", n, ch)
+		} else {
+			for k := 0; k < n; k++ {
+				out.WriteByte(ch)
+			}
+		}
+		i = j
+	}
+}
+
+// scale resizes img to w by h pixels by averaging
+func scale(img *image.RGBA, w, h int) *image.RGBA {
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for py := 0; py < h; py++ {
+		for px := 0; px < w; px++ {
+			out.SetRGBA(px, py, average(img, px, py, w, h))
+		}
+	}
+	return out
+}
@@ -0,0 +1,480 @@
+package preview
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Ways of drawing images in the terminal
+const (
+	ProtocolBlocks = "blocks"
+	ProtocolKitty  = "kitty"
+	ProtocolSixel  = "sixel"
+)
+
+// Cell size in pixels when the terminal does not report it
+const (
+	defaultCellWidth  = 10
+	defaultCellHeight = 20
+)
+
+// DetectProtocol picks the best protocol the terminal is known to support.
+// GO_TOOLS_IMAGES=blocks|kitty|sixel overrides the guess.
+func DetectProtocol() string {
+	switch p := os.Getenv("GO_TOOLS_IMAGES"); p {
+	case ProtocolBlocks, ProtocolKitty, ProtocolSixel:
+		return p
+	}
+	term, program := os.Getenv("TERM"), os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || program == "ghostty" || program == "WezTerm":
+		return ProtocolKitty
+	case strings.Contains(term, "sixel") || strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm"):
+		return ProtocolSixel
+	}
+	return ProtocolBlocks
+}
+
+// IsImage reports whether path has an extension Preview can decode
+func IsImage(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg", ".gif":
+		return true
+	}
+	return false
+}
+
+// LoadImage decodes a PNG, JPEG or GIF file
+func LoadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return img, nil
+}
+
+// Outline drawn over the image, such as an annotation polygon
+type Outline struct {
+	// In pixels of the image, one point is drawn as a dot
+	Points [][2]float64
+	Closed bool
+	Color  color.RGBA
+}
+
+// Preview shows an image, optionally with a mask blended over it and
+// outlines drawn on top, using half blocks in truecolor or the kitty or
+// sixel graphics protocol.
+//
+//	o    : toggle overlay
+//	+, - : overlay opacity
+//	a    : toggle outlines
+type Preview struct {
+	*tview.Box
+	Image    image.Image
+	Overlay  image.Image
+	Outlines []Outline
+
+	// Draw Outlines
+	ShowOutlines bool
+
+	// Blending of the overlay, 0 to 1
+	Opacity     float64
+	ShowOverlay bool
+
+	// Color of gray overlay pixels such as binary masks; colored pixels
+	// like heatmaps keep their own color
+	OverlayColor color.RGBA
+
+	// One of the Protocol constants, and where graphics are written
+	Protocol string
+	Output   io.Writer
+
+	// Image and overlay blended, rebuilt when either changes
+	composite *image.RGBA
+
+	// Composite scaled with outlines, rebuilt when the size or version changes
+	rendered    *image.RGBA
+	renderedVer int
+
+	// Cells covered by graphics this frame and when they were last sent
+	drawn    bool
+	area     [4]int
+	sent     [4]int
+	sentOnce bool
+	version  int
+	sentVer  int
+}
+
+func NewPreview() *Preview {
+	return &Preview{
+		Box:          tview.NewBox(),
+		Opacity:      0.5,
+		ShowOverlay:  true,
+		ShowOutlines: true,
+		OverlayColor: color.RGBA{255, 0, 64, 255},
+		Protocol:     DetectProtocol(),
+		Output:       os.Stdout,
+	}
+}
+
+// SetImage replaces the image and removes the overlay and outlines
+func (r *Preview) SetImage(img image.Image) *Preview {
+	r.Image, r.Overlay, r.Outlines = img, nil, nil
+	r.changed()
+	return r
+}
+
+// SetOutlines sets the outlines drawn over the image
+func (r *Preview) SetOutlines(outlines []Outline) *Preview {
+	r.Outlines = outlines
+	r.version++
+	return r
+}
+
+// SetOverlay sets the mask blended over the image, scaled to its size
+func (r *Preview) SetOverlay(img image.Image) *Preview {
+	r.Overlay = img
+	r.changed()
+	return r
+}
+
+// SetOpacity sets the overlay opacity, clamped to 0 to 1
+func (r *Preview) SetOpacity(opacity float64) *Preview {
+	if opacity < 0 {
+		opacity = 0
+	}
+	if opacity > 1 {
+		opacity = 1
+	}
+	r.Opacity = opacity
+	r.changed()
+	return r
+}
+
+func (r *Preview) changed() {
+	r.composite = nil
+	r.version++
+}
+
+// blended returns the image with the overlay applied
+func (r *Preview) blended() *image.RGBA {
+	if r.composite != nil || r.Image == nil {
+		return r.composite
+	}
+	b := r.Image.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	var ob image.Rectangle
+	if r.Overlay != nil {
+		ob = r.Overlay.Bounds()
+	}
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := color.RGBAModel.Convert(r.Image.At(b.Min.X+x, b.Min.Y+y)).(color.RGBA)
+			if r.Overlay != nil && r.ShowOverlay {
+				// Nearest overlay pixel
+				ox := ob.Min.X + x*ob.Dx()/b.Dx()
+				oy := ob.Min.Y + y*ob.Dy()/b.Dy()
+				c = r.blend(c, color.RGBAModel.Convert(r.Overlay.At(ox, oy)).(color.RGBA))
+			}
+			c.A = 255
+			out.SetRGBA(x, y, c)
+		}
+	}
+	r.composite = out
+	return out
+}
+
+// blend mixes one overlay pixel into c; black and transparent pixels leave
+// c unchanged
+func (r *Preview) blend(c, o color.RGBA) color.RGBA {
+	if o.A == 0 || (o.R == 0 && o.G == 0 && o.B == 0) {
+		return c
+	}
+	alpha := r.Opacity * float64(o.A) / 255
+	if o.R == o.G && o.G == o.B {
+		// Gray masks are tinted, stronger where brighter
+		alpha *= float64(o.R) / 255
+		o = r.OverlayColor
+	}
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a)*(1-alpha) + float64(b)*alpha + 0.5)
+	}
+	return color.RGBA{mix(c.R, o.R), mix(c.G, o.G), mix(c.B, o.B), 255}
+}
+
+// render returns the composite scaled to w by h pixels with the outlines
+// drawn one pixel wide
+func (r *Preview) render(w, h int) *image.RGBA {
+	img := r.blended()
+	if img == nil || w <= 0 || h <= 0 {
+		return nil
+	}
+	if r.rendered != nil && r.renderedVer == r.version && r.rendered.Bounds().Dx() == w && r.rendered.Bounds().Dy() == h {
+		return r.rendered
+	}
+	out := scale(img, w, h)
+	if r.ShowOutlines {
+		sx := float64(w) / float64(img.Bounds().Dx())
+		sy := float64(h) / float64(img.Bounds().Dy())
+		for _, o := range r.Outlines {
+			drawOutline(out, o, sx, sy)
+		}
+	}
+	r.rendered, r.renderedVer = out, r.version
+	return out
+}
+
+// drawOutline draws o scaled by sx, sy
+func drawOutline(img *image.RGBA, o Outline, sx, sy float64) {
+	n := len(o.Points)
+	at := func(i int) (int, int) {
+		p := o.Points[i%n]
+		return int(p[0] * sx), int(p[1] * sy)
+	}
+	switch {
+	case n == 0:
+		return
+	case n == 1:
+		x, y := at(0)
+		for _, d := range [][2]int{{0, 0}, {-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			setPixel(img, x+d[0], y+d[1], o.Color)
+		}
+		return
+	}
+	segments := n - 1
+	if o.Closed {
+		segments = n
+	}
+	for i := 0; i < segments; i++ {
+		x0, y0 := at(i)
+		x1, y1 := at(i + 1)
+		line(x0, y0, x1, y1, func(x, y int) { setPixel(img, x, y, o.Color) })
+	}
+}
+
+func setPixel(img *image.RGBA, x, y int, c color.RGBA) {
+	if image.Pt(x, y).In(img.Bounds()) {
+		img.SetRGBA(x, y, c)
+	}
+}
+
+// line calls set for every pixel from (x0, y0) to (x1, y1), Bresenham
+func line(x0, y0, x1, y1 int, set func(x, y int)) {
+	dx, dy := x1-x0, y1-y0
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx - dy
+	for {
+		set(x0, y0)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 > -dy {
+			err -= dy
+			x0 += sx
+		}
+		if e2 < dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+// fit returns the size in cells of an image of w by h pixels drawn as large
+// as possible within cols by rows, with cells twice as tall as wide
+func fit(w, h, cols, rows int) (int, int) {
+	if w == 0 || h == 0 || cols <= 0 || rows <= 0 {
+		return 0, 0
+	}
+	// Half blocks give one by two pixels per cell
+	fw, fh := cols, cols*h/w/2
+	if fh > rows {
+		fw, fh = rows*2*w/h, rows
+	}
+	if fw < 1 {
+		fw = 1
+	}
+	if fh < 1 {
+		fh = 1
+	}
+	return fw, fh
+}
+
+func (r *Preview) Draw(screen tcell.Screen) {
+	r.Box.DrawForSubclass(screen, r)
+	x, y, width, height := r.GetInnerRect()
+	if height < 2 {
+		return
+	}
+
+	// Last row is the status line
+	status := fmt.Sprintf("[gray]%s", r.Protocol)
+	if r.Overlay != nil {
+		state := "off"
+		if r.ShowOverlay {
+			state = fmt.Sprintf("%.0f%%", 100*r.Opacity)
+		}
+		status += fmt.Sprintf("  overlay %s  o: toggle  +/-: opacity", state)
+	}
+	if len(r.Outlines) > 0 {
+		status += fmt.Sprintf("  %d outlines  a: toggle", len(r.Outlines))
+	}
+	tview.Print(screen, status+"[-]", x, y+height-1, width, tview.AlignLeft, tcell.ColorWhite)
+
+	img := r.blended()
+	if img == nil {
+		tview.Print(screen, "no image", x, y, width, tview.AlignLeft, tcell.ColorGray)
+		return
+	}
+	b := img.Bounds()
+	cols, rows := fit(b.Dx(), b.Dy(), width, height-1)
+
+	if r.Protocol != ProtocolBlocks {
+		// Keep the cells blank, graphics are sent after the frame is drawn
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				screen.SetContent(x+col, y+row, ' ', nil, tcell.StyleDefault)
+			}
+		}
+		r.drawn = true
+		r.area = [4]int{x, y, cols, rows}
+		return
+	}
+
+	// Upper half block: foreground is the top pixel, background the bottom
+	pixels := r.render(cols, 2*rows)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			top, bottom := pixels.RGBAAt(col, 2*row), pixels.RGBAAt(col, 2*row+1)
+			style := tcell.StyleDefault.
+				Foreground(tcell.NewRGBColor(int32(top.R), int32(top.G), int32(top.B))).
+				Background(tcell.NewRGBColor(int32(bottom.R), int32(bottom.G), int32(bottom.B)))
+			screen.SetContent(x+col, y+row, '▀', nil, style)
+		}
+	}
+}
+
+// average color of the source pixels covered by pixel (px, py) of an image
+// scaled to w by h
+func average(img *image.RGBA, px, py, w, h int) color.RGBA {
+	b := img.Bounds()
+	x0, x1 := px*b.Dx()/w, (px+1)*b.Dx()/w
+	y0, y1 := py*b.Dy()/h, (py+1)*b.Dy()/h
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+	var sr, sg, sb, n int
+	for y := y0; y < y1 && y < b.Dy(); y++ {
+		for x := x0; x < x1 && x < b.Dx(); x++ {
+			c := img.RGBAAt(x, y)
+			sr, sg, sb, n = sr+int(c.R), sg+int(c.G), sb+int(c.B), n+1
+		}
+	}
+	if n == 0 {
+		return color.RGBA{}
+	}
+	return color.RGBA{uint8(sr / n), uint8(sg / n), uint8(sb / n), 255}
+}
+
+// AfterDraw sends kitty or sixel graphics for the area reserved by Draw, or
+// removes them once the preview is no longer drawn. Call it from the
+// application's after draw function.
+func (r *Preview) AfterDraw(screen tcell.Screen) {
+	if r.Protocol == ProtocolBlocks {
+		return
+	}
+	defer func() { r.drawn = false }()
+
+	if !r.drawn {
+		if r.sentOnce {
+			r.sentOnce = false
+			if r.Protocol == ProtocolKitty {
+				fmt.Fprint(r.Output, kittyDelete)
+			} else {
+				// Sixel pixels stay until the cells below are redrawn
+				screen.Sync()
+			}
+		}
+		return
+	}
+	if r.sentOnce && r.sent == r.area && r.sentVer == r.version {
+		return
+	}
+
+	// Pixels as large as the cells, so outlines stay one pixel wide
+	x, y, cols, rows := r.area[0], r.area[1], r.area[2], r.area[3]
+	cw, ch := cellSize()
+	b := r.blended().Bounds()
+	w, h := cols*cw, b.Dy()*cols*cw/b.Dx()
+	if h > rows*ch {
+		w, h = b.Dx()*rows*ch/b.Dy(), rows*ch
+	}
+	img := r.render(w, h)
+	if img == nil {
+		return
+	}
+	var err error
+	switch r.Protocol {
+	case ProtocolKitty:
+		err = writeKitty(r.Output, img, x, y, cols, rows)
+	case ProtocolSixel:
+		err = writeSixel(r.Output, img, x, y)
+	}
+	if err != nil {
+		// Fall back to half blocks from the next frame on
+		r.Protocol = ProtocolBlocks
+		return
+	}
+	r.sent, r.sentVer, r.sentOnce = r.area, r.version, true
+}
+
+func (r *Preview) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return r.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if event.Key() != tcell.KeyRune {
+			return
+		}
+		switch event.Rune() {
+		case 'o':
+			r.ShowOverlay = !r.ShowOverlay
+			r.changed()
+		case 'a':
+			r.ShowOutlines = !r.ShowOutlines
+			r.version++
+		case '+', '=':
+			r.SetOpacity(r.Opacity + 0.1)
+		case '-':
+			r.SetOpacity(r.Opacity - 0.1)
+		}
+	})
+}
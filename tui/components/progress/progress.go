@@ -0,0 +1,72 @@
+package progress
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Progress is a one line bar with a label and a done/total counter
+type Progress struct {
+	*tview.Box
+	Label string
+	Done  int
+	Total int
+
+	// Shown in red after the counter, e.g. why loading stopped
+	Message string
+}
+
+func NewProgress() *Progress {
+	return &Progress{
+		Box: tview.NewBox(),
+	}
+}
+
+// SetLabel sets the text in front of the bar
+func (r *Progress) SetLabel(label string) *Progress {
+	r.Label = label
+	return r
+}
+
+// SetProgress sets how much of total is done
+func (r *Progress) SetProgress(done, total int) *Progress {
+	r.Done, r.Total = done, total
+	return r
+}
+
+// SetMessage sets the text after the counter
+func (r *Progress) SetMessage(message string) *Progress {
+	r.Message = message
+	return r
+}
+
+func (r *Progress) Draw(screen tcell.Screen) {
+	r.Box.DrawForSubclass(screen, r)
+	x, y, width, height := r.GetInnerRect()
+	if height < 1 {
+		return
+	}
+
+	counter := fmt.Sprintf(" %d/%d", r.Done, r.Total)
+	if r.Message != "" {
+		counter += " [red]" + tview.Escape(r.Message) + "[-]"
+	}
+	label := tview.Escape(r.Label) + " "
+	bar := width - tview.TaggedStringWidth(label) - tview.TaggedStringWidth(counter)
+	if bar > 40 {
+		bar = 40
+	}
+	if bar < 0 {
+		bar = 0
+	}
+
+	filled := 0
+	if r.Total > 0 {
+		filled = bar * r.Done / r.Total
+	}
+	line := label + "[green]" + strings.Repeat("█", filled) + "[gray]" + strings.Repeat("░", bar-filled) + "[-]" + counter
+	tview.Print(screen, line, x, y, width, tview.AlignLeft, tcell.ColorWhite)
+}
@@ -0,0 +1,188 @@
+package slidetable
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/manyids2/go-tools/tui/components/table"
+	"github.com/manyids2/go-tools/tui/models/predictions"
+)
+
+// Entry is a slide with its predicted label and review
+type Entry struct {
+	predictions.Slide
+	Label    predictions.Label
+	HasLabel bool
+
+	// Empty verdict when not reviewed
+	Review predictions.Review
+}
+
+// Entries reads the label of every slide of m, by group then name
+func Entries(m *predictions.Predictions) []Entry {
+	slides := m.AllSlides()
+	entries := make([]Entry, len(slides))
+	for i, s := range slides {
+		entries[i].Slide = s
+		entries[i].Label, entries[i].HasLabel = s.Label()
+		entries[i].Review, _ = m.Review(s.Group, s.Name)
+	}
+	return entries
+}
+
+// Columns of the table, in order
+var Columns = []table.Column{
+	{Title: "GROUP"},
+	{Title: "SLIDE"},
+	{Title: "ARTIFACTS", Numeric: true},
+	{Title: "LABEL"},
+	{Title: "SCORE", Numeric: true},
+	{Title: "MODIFIED"},
+	{Title: "VERDICT"},
+	{Title: "NOTE"},
+}
+
+// Slidetable lists the slides of every group of a predictions directory in
+// a table, see table.Table for more keys. Verdicts and notes apply to the
+// selected slides, or the one under the cursor, and are saved right away.
+//
+//	1, 2, 3 : accept, reject, mark as needing review
+//	0       : clear the verdict and note
+//	n       : edit the note, marking unreviewed slides as needing review
+type Slidetable struct {
+	*table.Table
+	Entries []Entry
+
+	// Where reviews are saved, none when nil
+	Predictions *predictions.Predictions
+
+	selected func(e Entry)
+}
+
+func NewSlidetable() *Slidetable {
+	r := &Slidetable{Table: table.NewTable()}
+	r.SetColumns(Columns...)
+	r.Table.SetSelectedFunc(func(row int) {
+		if row < len(r.Entries) && r.selected != nil {
+			r.selected(r.Entries[row])
+		}
+	})
+	return r
+}
+
+// SetEntries replaces the shown slides, of m
+func (r *Slidetable) SetEntries(m *predictions.Predictions, entries []Entry) *Slidetable {
+	r.Predictions = m
+	r.Entries = entries
+	rows := make([][]string, len(entries))
+	for i, e := range entries {
+		rows[i] = Row(e)
+	}
+	r.SetRows(rows)
+	r.SetTitle(fmt.Sprintf(" %d slides ", len(entries)))
+	return r
+}
+
+// SetSelectedFunc sets the handler called when a slide is selected.
+func (r *Slidetable) SetSelectedFunc(handler func(e Entry)) *Slidetable {
+	r.selected = handler
+	return r
+}
+
+// SelectedEntries returns the selected slides, or the one under the cursor
+func (r *Slidetable) SelectedEntries() []Entry {
+	var out []Entry
+	for _, i := range r.SelectedRows() {
+		out = append(out, r.Entries[i])
+	}
+	return out
+}
+
+// Row renders the cells of an entry in the order of Columns
+func Row(e Entry) []string {
+	label, score := "-", "-"
+	if e.HasLabel {
+		label = e.Label.Class
+		if e.Label.HasScore {
+			score = strconv.FormatFloat(e.Label.Score, 'f', 4, 64)
+		}
+	}
+	modified := "-"
+	if t := e.LastModified(); !t.IsZero() {
+		modified = t.Format("2006-01-02 15:04:05")
+	}
+	verdict := "-"
+	if e.Review.Verdict != "" {
+		verdict = string(e.Review.Verdict)
+	}
+	return []string{e.Group, e.Name, strconv.Itoa(len(e.Artifacts)), label, score, modified, verdict, e.Review.Note}
+}
+
+func (r *Slidetable) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return r.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if !r.Prompting() && event.Key() == tcell.KeyRune {
+			switch event.Rune() {
+			case '1', '2', '3':
+				r.review(predictions.Verdicts[event.Rune()-'1'], nil)
+				return
+			case '0':
+				r.review("", nil)
+				return
+			case 'n':
+				rows := r.SelectedRows()
+				if len(rows) == 0 || r.Predictions == nil {
+					return
+				}
+				r.Prompt(fmt.Sprintf("note for %d:", len(rows)), r.Entries[rows[0]].Review.Note, func(text string, ok bool) {
+					if ok {
+						r.review(predictions.VerdictNeedsReview, &text)
+					}
+				})
+				return
+			}
+		}
+		if handler := r.Table.InputHandler(); handler != nil {
+			handler(event, setFocus)
+		}
+	})
+}
+
+// review saves a verdict for the selected slides, keeping their notes unless
+// note is given. With a note, slides that have a verdict keep it; an empty
+// verdict without a note clears the review.
+func (r *Slidetable) review(verdict predictions.Verdict, note *string) {
+	m := r.Predictions
+	if m == nil {
+		return
+	}
+	rows := r.SelectedRows()
+	reviews := make([]predictions.Review, 0, len(rows))
+	for _, i := range rows {
+		e := r.Entries[i]
+		rv := predictions.Review{Group: e.Group, Slide: e.Name, Verdict: verdict, Note: e.Review.Note}
+		if note != nil {
+			rv.Note = *note
+			if e.Review.Verdict != "" {
+				rv.Verdict = e.Review.Verdict
+			}
+		}
+		if verdict == "" {
+			rv.Note = ""
+		}
+		reviews = append(reviews, rv)
+	}
+	if err := m.SetReviews(reviews); err != nil {
+		log.Println("Could not save verdicts: ", m.VerdictsPath(), err)
+		r.SetMessage(err.Error())
+		return
+	}
+	for _, i := range rows {
+		e := &r.Entries[i]
+		e.Review, _ = m.Review(e.Group, e.Name)
+		r.SetRow(i, Row(*e))
+	}
+}
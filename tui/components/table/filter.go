@@ -0,0 +1,239 @@
+package table
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Filter selects rows with an expression such as
+//
+//	label=tumor and score>=0.8 or group~^batch_[12] and P01
+//
+// Terms compare a column with a value using =, !=, <, <=, >, >=, ~ (regex
+// match) or !~. Columns are named by their title in any case, or by a prefix
+// naming only one of them. "and" binds tighter than "or". Values compare as
+// numbers when both sides parse as such, else as text ignoring case; cells
+// that are not numbers only match != a number. A term that is a single
+// word matches rows with a cell containing it.
+type Filter struct {
+	Source string
+
+	// Any group matches when all of its terms match
+	groups [][]term
+}
+
+type term struct {
+	// Index of the column, -1 for any cell
+	col   int
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+var operators = []string{"!=", "<=", ">=", "!~", "=", "<", ">", "~"}
+
+// ParseFilter compiles an expression against columns; an empty expression
+// matches everything
+func ParseFilter(s string, columns []Column) (*Filter, error) {
+	f := &Filter{Source: s}
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var group []term
+	for _, tok := range tokens {
+		switch strings.ToLower(tok) {
+		case "and", "&&":
+			continue
+		case "or", "||":
+			if len(group) == 0 {
+				return nil, fmt.Errorf("filter: %q without a term before it", tok)
+			}
+			f.groups = append(f.groups, group)
+			group = nil
+			continue
+		}
+		t, err := parseTerm(tok, columns)
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, t)
+	}
+	if len(group) > 0 {
+		f.groups = append(f.groups, group)
+	}
+	return f, nil
+}
+
+// tokenize splits on spaces, keeping quoted strings together
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	var b strings.Builder
+	inToken := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("filter: unterminated quote at %d", i)
+			}
+			b.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inToken = true
+		case c == ' ' || c == '\t':
+			if inToken {
+				tokens = append(tokens, b.String())
+				b.Reset()
+				inToken = false
+			}
+		default:
+			b.WriteByte(c)
+			inToken = true
+		}
+	}
+	if inToken {
+		tokens = append(tokens, b.String())
+	}
+	return tokens, nil
+}
+
+// parseTerm reads "column op value", or a word to look for in every cell
+func parseTerm(tok string, columns []Column) (term, error) {
+	// Earliest operator, longest first at the same position
+	at, op := -1, ""
+	for _, o := range operators {
+		if i := strings.Index(tok, o); i >= 0 && (at < 0 || i < at) {
+			at, op = i, o
+		}
+	}
+	if at < 0 {
+		return term{col: -1, op: "~", value: tok, re: regexp.MustCompile("(?i)" + regexp.QuoteMeta(tok))}, nil
+	}
+	if at == 0 {
+		return term{}, fmt.Errorf("filter: expected column before %q", tok)
+	}
+
+	col, err := findColumn(tok[:at], columns)
+	if err != nil {
+		return term{}, err
+	}
+	t := term{col: col, op: op, value: tok[at+len(op):]}
+	if op == "~" || op == "!~" {
+		re, err := regexp.Compile("(?i)" + t.value)
+		if err != nil {
+			return t, fmt.Errorf("filter: %s: %v", tok[:at], err)
+		}
+		t.re = re
+	}
+	return t, nil
+}
+
+// findColumn returns the column titled name, or the only one it prefixes
+func findColumn(name string, columns []Column) (int, error) {
+	found := -1
+	for i, c := range columns {
+		title := strings.ToLower(c.Title)
+		switch {
+		case title == strings.ToLower(name):
+			return i, nil
+		case strings.HasPrefix(title, strings.ToLower(name)):
+			if found >= 0 {
+				return -1, fmt.Errorf("filter: %q could be %s or %s", name, columns[found].Title, c.Title)
+			}
+			found = i
+		}
+	}
+	if found < 0 {
+		return -1, fmt.Errorf("filter: no column %q", name)
+	}
+	return found, nil
+}
+
+// Empty reports whether the filter matches everything
+func (f *Filter) Empty() bool {
+	return f == nil || len(f.groups) == 0
+}
+
+// Match reports whether a row with cells satisfies the filter
+func (f *Filter) Match(cells []string) bool {
+	if f.Empty() {
+		return true
+	}
+	for _, group := range f.groups {
+		all := true
+		for _, t := range group {
+			if !t.match(cells) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+func (t term) match(cells []string) bool {
+	if t.col < 0 {
+		for _, c := range cells {
+			if t.re.MatchString(c) {
+				return true
+			}
+		}
+		return false
+	}
+	actual := ""
+	if t.col < len(cells) {
+		actual = cells[t.col]
+	}
+
+	switch t.op {
+	case "~":
+		return t.re.MatchString(actual)
+	case "!~":
+		return !t.re.MatchString(actual)
+	}
+
+	// Numbers compare numerically, everything else as text
+	a, errA := strconv.ParseFloat(actual, 64)
+	b, errB := strconv.ParseFloat(t.value, 64)
+	if errB == nil && errA != nil {
+		// "-" or empty cells are neither above nor below a number
+		return t.op == "!="
+	}
+	if errA == nil && errB == nil {
+		switch {
+		case a < b:
+			return compare(-1, t.op)
+		case a > b:
+			return compare(1, t.op)
+		default:
+			return compare(0, t.op)
+		}
+	}
+	return compare(strings.Compare(strings.ToLower(actual), strings.ToLower(t.value)), t.op)
+}
+
+// compare applies op to the sign of a three-way comparison
+func compare(c int, op string) bool {
+	switch op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
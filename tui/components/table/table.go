@@ -0,0 +1,524 @@
+package table
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Widest a column is drawn, longer cells are cut
+const maxColumnWidth = 40
+
+// Column of a table. Numeric columns sort by value, with cells that are not
+// numbers last, and are right aligned.
+type Column struct {
+	Title   string
+	Numeric bool
+}
+
+// Table shows rows of text cells that can be sorted by any column, narrowed
+// with a filter expression and selected several at a time.
+//
+//	j, k, g, G : move
+//	c-d, c-u   : page down, up
+//	h, l       : previous, next column
+//	s          : sort by the column, again to reverse
+//	/, c-f     : filter, see Filter; esc clears it
+//	space      : select the row
+//	a, A       : select all shown rows, clear the selection
+//	enter      : call the selected handler on the row
+type Table struct {
+	*tview.Box
+	Columns []Column
+	Rows    [][]string
+
+	// Column sorted by, -1 for row order, and its direction
+	SortColumn int
+	Descending bool
+
+	// Current filter, nil when showing every row
+	Filter *Filter
+
+	// Rows selected with space, by index in Rows
+	Selected map[int]bool
+
+	// Indices of shown rows in order, position in shown, first drawn row
+	shown  []int
+	cursor int
+	offset int
+
+	// Column under the cursor, drawn widths
+	column int
+	widths []int
+
+	// Prompt state, done is nil for the filter
+	prompting bool
+	label     string
+	input     string
+	done      func(text string, ok bool)
+	message   string
+	height    int
+
+	selected func(row int)
+}
+
+func NewTable() *Table {
+	return &Table{
+		Box:        tview.NewBox(),
+		SortColumn: -1,
+		Selected:   make(map[int]bool),
+	}
+}
+
+// SetColumns replaces the columns, clearing the rows
+func (r *Table) SetColumns(columns ...Column) *Table {
+	r.Columns = columns
+	r.SortColumn, r.Descending = -1, false
+	r.column = 0
+	r.Filter = nil
+	return r.SetRows(nil)
+}
+
+// SetRows replaces the rows, keeping the sort and filter and clearing the
+// selection
+func (r *Table) SetRows(rows [][]string) *Table {
+	r.Rows = rows
+	r.Selected = make(map[int]bool)
+	r.shown = nil
+	r.cursor, r.offset = 0, 0
+	r.widths = make([]int, len(r.Columns))
+	for col, c := range r.Columns {
+		r.widths[col] = tview.TaggedStringWidth(tview.Escape(c.Title)) + 2
+	}
+	for _, row := range rows {
+		for col := 0; col < len(row) && col < len(r.widths); col++ {
+			if w := tview.TaggedStringWidth(tview.Escape(row[col])); w > r.widths[col] {
+				r.widths[col] = w
+			}
+		}
+	}
+	for col := range r.widths {
+		if r.widths[col] > maxColumnWidth {
+			r.widths[col] = maxColumnWidth
+		}
+	}
+	r.refresh()
+	return r
+}
+
+// SetSelectedFunc sets the handler called with the index of the row under
+// the cursor when enter is pressed
+func (r *Table) SetSelectedFunc(handler func(row int)) *Table {
+	r.selected = handler
+	return r
+}
+
+// Current returns the index in Rows of the row under the cursor
+func (r *Table) Current() (int, bool) {
+	if r.cursor < 0 || r.cursor >= len(r.shown) {
+		return 0, false
+	}
+	return r.shown[r.cursor], true
+}
+
+// Shown returns the indices in Rows of the rows passing the filter, in
+// sorted order
+func (r *Table) Shown() []int {
+	return r.shown
+}
+
+// SelectedRows returns the indices in Rows of the selected rows, or the row
+// under the cursor when none is selected
+func (r *Table) SelectedRows() []int {
+	var rows []int
+	for i := range r.Selected {
+		rows = append(rows, i)
+	}
+	sort.Ints(rows)
+	if len(rows) == 0 {
+		if i, ok := r.Current(); ok {
+			rows = []int{i}
+		}
+	}
+	return rows
+}
+
+// SortBy sorts by column, -1 for row order
+func (r *Table) SortBy(column int, descending bool) *Table {
+	r.SortColumn, r.Descending = column, descending
+	r.refresh()
+	return r
+}
+
+// SetFilter shows only the rows matching expr
+func (r *Table) SetFilter(expr string) error {
+	f, err := ParseFilter(expr, r.Columns)
+	if err != nil {
+		return err
+	}
+	r.Filter = f
+	r.refresh()
+	return nil
+}
+
+// refresh filters and sorts the rows, keeping the cursor on its row
+func (r *Table) refresh() {
+	current, ok := r.Current()
+	r.shown = r.shown[:0]
+	for i, row := range r.Rows {
+		if r.Filter.Match(row) {
+			r.shown = append(r.shown, i)
+		}
+	}
+	if r.SortColumn >= 0 && r.SortColumn < len(r.Columns) {
+		col, numeric := r.SortColumn, r.Columns[r.SortColumn].Numeric
+		sort.SliceStable(r.shown, func(i, j int) bool {
+			return r.less(r.cell(r.shown[i], col), r.cell(r.shown[j], col), numeric)
+		})
+	}
+
+	r.cursor = 0
+	if ok {
+		for pos, i := range r.shown {
+			if i == current {
+				r.cursor = pos
+				break
+			}
+		}
+	}
+}
+
+// less orders two cells of a column in the sort direction
+func (r *Table) less(a, b string, numeric bool) bool {
+	if numeric {
+		fa, errA := strconv.ParseFloat(a, 64)
+		fb, errB := strconv.ParseFloat(b, 64)
+		switch {
+		case errA != nil || errB != nil:
+			// Cells that are not numbers go last either way
+			return errA == nil && errB != nil
+		case r.Descending:
+			return fa > fb
+		default:
+			return fa < fb
+		}
+	}
+	c := strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	if r.Descending {
+		return c > 0
+	}
+	return c < 0
+}
+
+func (r *Table) cell(row, col int) string {
+	if col < len(r.Rows[row]) {
+		return r.Rows[row][col]
+	}
+	return ""
+}
+
+func (r *Table) Draw(screen tcell.Screen) {
+	r.Box.DrawForSubclass(screen, r)
+	x, y, width, height := r.GetInnerRect()
+	if height < 3 {
+		return
+	}
+
+	// Header on the first row, prompt / status line on the last
+	rows := height - 2
+	r.height = rows
+	if r.cursor < r.offset {
+		r.offset = r.cursor
+	}
+	if r.cursor >= r.offset+rows {
+		r.offset = r.cursor - rows + 1
+	}
+	if r.offset < 0 {
+		r.offset = 0
+	}
+
+	header := make([]string, len(r.Columns))
+	for col, c := range r.Columns {
+		title := tview.Escape(c.Title)
+		switch {
+		case col == r.SortColumn && r.Descending:
+			title += " ▼"
+		case col == r.SortColumn:
+			title += " ▲"
+		}
+		style := "[::b]"
+		if col == r.column && r.HasFocus() {
+			style = "[black:white:b]"
+		}
+		header[col] = style + title + "[-:-:-]"
+	}
+	r.drawRow(screen, header, x, y, width)
+
+	for row := 0; row < rows && r.offset+row < len(r.shown); row++ {
+		pos := r.offset + row
+		i := r.shown[pos]
+		fg, bg := "white", "-"
+		if r.Selected[i] {
+			fg = "yellow"
+		}
+		if pos == r.cursor && r.HasFocus() {
+			bg = "darkslategray"
+			for cx := x; cx < x+width; cx++ {
+				screen.SetContent(cx, y+1+row, ' ', nil, tcell.StyleDefault.Background(tcell.ColorDarkSlateGray))
+			}
+		}
+		cells := make([]string, len(r.Columns))
+		for col := range r.Columns {
+			cells[col] = fmt.Sprintf("[%s:%s]", fg, bg) + tview.Escape(r.cell(i, col))
+		}
+		mark := " "
+		if r.Selected[i] {
+			mark = "[yellow]*"
+		}
+		tview.Print(screen, mark, x, y+1+row, 1, tview.AlignLeft, tcell.ColorWhite)
+		r.drawRow(screen, cells, x, y+1+row, width)
+	}
+
+	tview.Print(screen, r.statusLine(), x, y+height-1, width, tview.AlignLeft, tcell.ColorWhite)
+}
+
+// drawRow prints tagged cells in their columns after the selection mark
+func (r *Table) drawRow(screen tcell.Screen, cells []string, x, y, width int) {
+	cx := x + 2
+	for col, text := range cells {
+		w := r.widths[col]
+		if cx+w > x+width {
+			w = x + width - cx
+		}
+		if w <= 0 {
+			return
+		}
+		align := tview.AlignLeft
+		if r.Columns[col].Numeric {
+			align = tview.AlignRight
+		}
+		tview.Print(screen, text, cx, y, w, align, tcell.ColorWhite)
+		cx += r.widths[col] + 2
+	}
+}
+
+func (r *Table) statusLine() string {
+	if r.prompting {
+		status := "[yellow]" + tview.Escape(r.label) + "[-] " + tview.Escape(r.input) + "_"
+		if r.message != "" {
+			status += "  [red]" + tview.Escape(r.message) + "[-]"
+		}
+		return status
+	}
+
+	pos := r.cursor + 1
+	if len(r.shown) == 0 {
+		pos = 0
+	}
+	status := fmt.Sprintf("%d/%d", pos, len(r.shown))
+	if len(r.shown) < len(r.Rows) {
+		status += fmt.Sprintf(" of %d", len(r.Rows))
+	}
+	if len(r.Selected) > 0 {
+		status += fmt.Sprintf("  [yellow]%d selected[-]", len(r.Selected))
+	}
+	if !r.Filter.Empty() {
+		status += "  [green]filter:[-] " + tview.Escape(r.Filter.Source)
+	}
+	if r.message != "" {
+		status += "  [red]" + tview.Escape(r.message) + "[-]"
+	}
+	return status
+}
+
+func (r *Table) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return r.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if r.prompting {
+			r.handlePrompt(event)
+			return
+		}
+		r.message = ""
+
+		switch event.Key() {
+		// Sane keys
+		case tcell.KeyUp:
+			r.move(-1)
+		case tcell.KeyDown:
+			r.move(1)
+		case tcell.KeyLeft:
+			r.moveColumn(-1)
+		case tcell.KeyRight:
+			r.moveColumn(1)
+		case tcell.KeyHome:
+			r.move(-len(r.shown))
+		case tcell.KeyEnd:
+			r.move(len(r.shown))
+		case tcell.KeyPgUp, tcell.KeyCtrlU:
+			r.move(-r.page())
+		case tcell.KeyPgDn, tcell.KeyCtrlD:
+			r.move(r.page())
+		case tcell.KeyCtrlF:
+			r.startPrompt()
+		case tcell.KeyEnter:
+			if i, ok := r.Current(); ok && r.selected != nil {
+				r.selected(i)
+			}
+
+		// Vim keys
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'k':
+				r.move(-1)
+			case 'j':
+				r.move(1)
+			case 'h':
+				r.moveColumn(-1)
+			case 'l':
+				r.moveColumn(1)
+			case 'g':
+				r.move(-len(r.shown))
+			case 'G':
+				r.move(len(r.shown))
+			case 's':
+				r.SortBy(r.column, r.SortColumn == r.column && !r.Descending)
+			case '/':
+				r.startPrompt()
+			case ' ':
+				if i, ok := r.Current(); ok {
+					if r.Selected[i] {
+						delete(r.Selected, i)
+					} else {
+						r.Selected[i] = true
+					}
+					r.move(1)
+				}
+			case 'a':
+				for _, i := range r.shown {
+					r.Selected[i] = true
+				}
+			case 'A':
+				r.Selected = make(map[int]bool)
+			}
+		}
+	})
+}
+
+// Prompting reports whether keys currently go to the filter or another
+// prompt
+func (r *Table) Prompting() bool {
+	return r.prompting
+}
+
+// Prompt asks for a line of text on the status line, starting from text.
+// done is called with ok false when esc cancels it.
+func (r *Table) Prompt(label, text string, done func(text string, ok bool)) {
+	r.prompting = true
+	r.label, r.input, r.done = label, text, done
+	r.message = ""
+}
+
+// SetMessage shows msg on the status line until the next key
+func (r *Table) SetMessage(msg string) *Table {
+	r.message = msg
+	return r
+}
+
+// SetRow replaces the cells of a row, keeping the selection and cursor
+func (r *Table) SetRow(i int, cells []string) *Table {
+	if i < 0 || i >= len(r.Rows) {
+		return r
+	}
+	r.Rows[i] = cells
+	for col := 0; col < len(cells) && col < len(r.widths); col++ {
+		if w := tview.TaggedStringWidth(tview.Escape(cells[col])); w > r.widths[col] {
+			r.widths[col] = w
+		}
+		if r.widths[col] > maxColumnWidth {
+			r.widths[col] = maxColumnWidth
+		}
+	}
+	r.refresh()
+	return r
+}
+
+func (r *Table) move(n int) {
+	r.cursor += n
+	if r.cursor >= len(r.shown) {
+		r.cursor = len(r.shown) - 1
+	}
+	if r.cursor < 0 {
+		r.cursor = 0
+	}
+}
+
+func (r *Table) moveColumn(n int) {
+	if len(r.Columns) > 0 {
+		r.column = (r.column + n + len(r.Columns)) % len(r.Columns)
+	}
+}
+
+func (r *Table) page() int {
+	if r.height > 1 {
+		return r.height - 1
+	}
+	return 1
+}
+
+func (r *Table) startPrompt() {
+	text := ""
+	if !r.Filter.Empty() {
+		text = r.Filter.Source
+	}
+	r.Prompt("filter:", text, nil)
+}
+
+// handlePrompt edits the prompt. The filter is applied on every keystroke
+// that makes it valid, other prompts only end with enter or esc.
+func (r *Table) handlePrompt(event *tcell.EventKey) {
+	if r.done != nil {
+		switch event.Key() {
+		case tcell.KeyEscape, tcell.KeyEnter:
+			r.prompting = false
+			done := r.done
+			r.done = nil
+			done(r.input, event.Key() == tcell.KeyEnter)
+			return
+		}
+	}
+
+	switch event.Key() {
+	case tcell.KeyEscape:
+		r.Filter = nil
+		r.refresh()
+		r.prompting = false
+		return
+	case tcell.KeyEnter:
+		if err := r.SetFilter(r.input); err != nil {
+			// Keep the last valid filter
+			r.message = err.Error()
+		}
+		r.prompting = false
+		return
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(r.input) > 0 {
+			runes := []rune(r.input)
+			r.input = string(runes[:len(runes)-1])
+		}
+	case tcell.KeyRune:
+		r.input += string(event.Rune())
+	default:
+		return
+	}
+
+	r.message = ""
+	if r.done != nil {
+		return
+	}
+	if err := r.SetFilter(r.input); err != nil {
+		r.message = err.Error()
+	}
+}
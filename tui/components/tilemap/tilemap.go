@@ -0,0 +1,208 @@
+package tilemap
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/manyids2/go-tools/tui/models/predictions"
+	"github.com/rivo/tview"
+)
+
+// Threshold steps of the slider
+const (
+	coarseStep = 0.05
+	fineStep   = 0.01
+)
+
+// Rows under the grid: slider, aggregates and key hints
+const footerRows = 3
+
+// Color stops from score 0 to 1, dark blue through green to red
+var ramp = [][3]float64{
+	{48, 18, 59},
+	{65, 130, 240},
+	{30, 200, 150},
+	{250, 190, 40},
+	{200, 30, 20},
+}
+
+// Tilemap draws the per-tile scores of a slide as a heatmap grid in half
+// blocks, one pixel per tile, or per block of tiles when the slide is larger
+// than the view. Tiles under the threshold are dimmed, and the aggregates
+// of the class follow the threshold as it moves.
+//
+//	c, C : next, previous class
+//	h, l : threshold down, up by 0.05, arrows too
+//	H, L : threshold down, up by 0.01
+type Tilemap struct {
+	*tview.Box
+	Scores *predictions.TileScores
+	Name   string
+
+	// Score column shown
+	Class int
+
+	Threshold float64
+	TopK      int
+}
+
+func NewTilemap() *Tilemap {
+	return &Tilemap{
+		Box:       tview.NewBox(),
+		Threshold: predictions.DefaultTileThreshold,
+		TopK:      predictions.DefaultTopK,
+	}
+}
+
+// SetScores shows the tiles of a slide, keeping the class if it has one of
+// the same name
+func (r *Tilemap) SetScores(t *predictions.TileScores, name string) *Tilemap {
+	class := ""
+	if r.Scores != nil && r.Class < len(r.Scores.Classes) {
+		class = r.Scores.Classes[r.Class]
+	}
+	r.Scores, r.Name, r.Class = t, name, 0
+	if i := t.Class(class); i >= 0 {
+		r.Class = i
+	}
+	return r
+}
+
+// SetThreshold moves the slider, clamped to 0 to 1
+func (r *Tilemap) SetThreshold(threshold float64) *Tilemap {
+	r.Threshold = math.Max(0, math.Min(1, math.Round(threshold*100)/100))
+	return r
+}
+
+// heat is the color of a score, dimmed to gray under the threshold
+func (r *Tilemap) heat(v float64) tcell.Color {
+	t := math.Max(0, math.Min(1, v)) * float64(len(ramp)-1)
+	i := int(t)
+	if i >= len(ramp)-1 {
+		i = len(ramp) - 2
+	}
+	f := t - float64(i)
+	var c [3]float64
+	for k := range c {
+		c[k] = ramp[i][k]*(1-f) + ramp[i+1][k]*f
+	}
+	if v < r.Threshold {
+		gray := (c[0] + c[1] + c[2]) / 3 * 0.35
+		c = [3]float64{gray, gray, gray}
+	}
+	return tcell.NewRGBColor(int32(c[0]), int32(c[1]), int32(c[2]))
+}
+
+func (r *Tilemap) Draw(screen tcell.Screen) {
+	r.Box.DrawForSubclass(screen, r)
+	x, y, width, height := r.GetInnerRect()
+	if height <= footerRows || width < 1 {
+		return
+	}
+	if r.Scores == nil || len(r.Scores.Tiles) == 0 {
+		tview.Print(screen, "no tiles", x, y, width, tview.AlignLeft, tcell.ColorGray)
+		return
+	}
+
+	// Blocks of scale by scale tiles per pixel so the slide fits, half
+	// blocks giving two pixels per cell
+	rows := height - footerRows
+	across, down := r.Scores.Size()
+	scale := math.Max(1, math.Max(float64(across)/float64(width), float64(down)/float64(2*rows)))
+	cols, pixels := int(math.Ceil(float64(across)/scale)), int(math.Ceil(float64(down)/scale))
+	grid := r.Scores.Grid(r.Class, cols, pixels)
+
+	at := func(col, row int) (float64, bool) {
+		if row >= pixels {
+			return 0, false
+		}
+		v := grid[row*cols+col]
+		return v, !math.IsNaN(v)
+	}
+	for row := 0; row < (pixels+1)/2; row++ {
+		for col := 0; col < cols; col++ {
+			top, topOK := at(col, 2*row)
+			bottom, bottomOK := at(col, 2*row+1)
+			style, ch := tcell.StyleDefault, ' '
+			switch {
+			case topOK && bottomOK:
+				style, ch = style.Foreground(r.heat(top)).Background(r.heat(bottom)), '▀'
+			case topOK:
+				style, ch = style.Foreground(r.heat(top)), '▀'
+			case bottomOK:
+				style, ch = style.Foreground(r.heat(bottom)), '▄'
+			}
+			screen.SetContent(x+col, y+row, ch, nil, style)
+		}
+	}
+
+	// Slider
+	footer := y + height - footerRows
+	label := fmt.Sprintf("threshold %.2f ", r.Threshold)
+	track := width - len(label) - 1
+	if track > 40 {
+		track = 40
+	}
+	tview.Print(screen, label, x, footer, width, tview.AlignLeft, tcell.ColorWhite)
+	knob := int(math.Round(r.Threshold * float64(track-1)))
+	for i := 0; i < track; i++ {
+		v := (float64(i) + 0.5) / float64(track)
+		ch, style := '━', tcell.StyleDefault.Foreground(r.heat(v))
+		if i == knob {
+			ch, style = '●', tcell.StyleDefault.Foreground(tcell.ColorWhite)
+		}
+		screen.SetContent(x+len(label)+i, footer, ch, nil, style)
+	}
+
+	// Aggregates of the class at the threshold
+	a := r.Scores.Aggregate(r.Class, r.TopK, r.Threshold)
+	summary := fmt.Sprintf("[::b]%s[::-]  %d tiles  mean %.3f  max %.3f  top-%d %.3f  [yellow]%.1f%%[-] >= %.2f",
+		tview.Escape(a.Class), a.Tiles, a.Mean, a.Max, a.K, a.TopK, 100*a.Above, a.Threshold)
+	tview.Print(screen, summary, x, footer+1, width, tview.AlignLeft, tcell.ColorWhite)
+
+	hints := []string{tview.Escape(r.Name)}
+	if scale > 1 {
+		hints = append(hints, fmt.Sprintf("%dx%d tiles, %.1f per pixel", across, down, scale))
+	} else {
+		hints = append(hints, fmt.Sprintf("%dx%d tiles", across, down))
+	}
+	if len(r.Scores.Classes) > 1 {
+		hints = append(hints, fmt.Sprintf("class %d/%d  c: next", r.Class+1, len(r.Scores.Classes)))
+	}
+	hints = append(hints, "h/l: threshold")
+	tview.Print(screen, "[gray]"+strings.Join(hints, "  ")+"[-]", x, footer+2, width, tview.AlignLeft, tcell.ColorWhite)
+}
+
+func (r *Tilemap) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return r.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		switch event.Key() {
+		// Sane keys
+		case tcell.KeyLeft:
+			r.SetThreshold(r.Threshold - coarseStep)
+		case tcell.KeyRight:
+			r.SetThreshold(r.Threshold + coarseStep)
+		case tcell.KeyRune:
+			// Vim keys
+			switch event.Rune() {
+			case 'h':
+				r.SetThreshold(r.Threshold - coarseStep)
+			case 'l':
+				r.SetThreshold(r.Threshold + coarseStep)
+			case 'H':
+				r.SetThreshold(r.Threshold - fineStep)
+			case 'L':
+				r.SetThreshold(r.Threshold + fineStep)
+			case 'c':
+				if r.Scores != nil && len(r.Scores.Classes) > 0 {
+					r.Class = (r.Class + 1) % len(r.Scores.Classes)
+				}
+			case 'C':
+				if r.Scores != nil && len(r.Scores.Classes) > 0 {
+					r.Class = (r.Class + len(r.Scores.Classes) - 1) % len(r.Scores.Classes)
+				}
+			}
+		}
+	})
+}
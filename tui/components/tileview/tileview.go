@@ -0,0 +1,326 @@
+package tileview
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/manyids2/go-tools/tui/models/pyramid"
+	"github.com/rivo/tview"
+)
+
+// Tiles kept in memory, about 64MB of 256 pixel tiles
+const defaultCapacity = 256
+
+// Goroutines reading tiles
+const loaders = 4
+
+// Most screen pixels per image pixel when zooming in
+const maxMagnification = 16
+
+// Background of tiles still loading or that failed to load
+var missingColor = tcell.NewRGBColor(32, 32, 32)
+
+// Tileview shows a pyramid image such as a whole-slide heatmap in half
+// blocks, reading only the tiles in view from the level closest to the zoom.
+// Coarser tiles already in memory stand in while finer ones load.
+//
+//	h, j, k, l : pan a quarter screen, arrows too
+//	H, J, K, L : pan a screen
+//	+, -       : zoom in, out
+//	0          : one image pixel per screen pixel
+//	f          : fit the image
+type Tileview struct {
+	*tview.Box
+	Pyramid pyramid.Pyramid
+	Name    string
+
+	// Tiles kept in memory
+	Capacity int
+
+	// Center of the view in full resolution pixels
+	CenterX, CenterY float64
+
+	// Full resolution pixels per screen pixel, half a cell tall
+	Scale float64
+
+	cache *pyramid.Cache
+
+	// Fit the image at the next draw, once the size is known
+	fit bool
+
+	// Size in screen pixels at the last draw
+	width, height int
+
+	// Called from a loader goroutine when a tile is ready
+	changed func()
+}
+
+func NewTileview() *Tileview {
+	return &Tileview{
+		Box:      tview.NewBox(),
+		Capacity: defaultCapacity,
+		Scale:    1,
+	}
+}
+
+// SetChangedFunc sets the function called, from another goroutine, when
+// tiles have loaded and the view should be drawn again
+func (r *Tileview) SetChangedFunc(handler func()) *Tileview {
+	r.changed = handler
+	return r
+}
+
+// SetPyramid shows p fitted to the view, closing the previous pyramid
+func (r *Tileview) SetPyramid(p pyramid.Pyramid, name string) *Tileview {
+	r.Close()
+	r.Pyramid, r.Name = p, name
+	r.cache = pyramid.NewCache(p, r.Capacity, loaders, func() {
+		if r.changed != nil {
+			r.changed()
+		}
+	})
+	r.fit = true
+	return r
+}
+
+// Close stops loading tiles and closes the pyramid
+func (r *Tileview) Close() error {
+	if r.cache == nil {
+		return nil
+	}
+	err := r.cache.Close()
+	r.cache, r.Pyramid = nil, nil
+	return err
+}
+
+// Fit zooms out to show the whole image
+func (r *Tileview) Fit() {
+	if r.Pyramid == nil || r.width == 0 || r.height == 0 {
+		r.fit = true
+		return
+	}
+	l := r.Pyramid.Levels()[0]
+	r.Scale = math.Max(float64(l.Width)/float64(r.width), float64(l.Height)/float64(r.height))
+	r.CenterX, r.CenterY = float64(l.Width)/2, float64(l.Height)/2
+	r.fit = false
+}
+
+// Zoom multiplies the magnification by factor, keeping the center
+func (r *Tileview) Zoom(factor float64) {
+	if r.Pyramid == nil {
+		return
+	}
+	l := r.Pyramid.Levels()[0]
+	fitted := math.Max(float64(l.Width)/float64(r.width), float64(l.Height)/float64(r.height))
+	r.Scale = math.Max(1.0/maxMagnification, math.Min(2*fitted, r.Scale/factor))
+}
+
+// Pan moves the view by dx, dy screen pixels, keeping the center on the
+// image
+func (r *Tileview) Pan(dx, dy int) {
+	if r.Pyramid == nil {
+		return
+	}
+	l := r.Pyramid.Levels()[0]
+	r.CenterX = math.Max(0, math.Min(float64(l.Width), r.CenterX+float64(dx)*r.Scale))
+	r.CenterY = math.Max(0, math.Min(float64(l.Height), r.CenterY+float64(dy)*r.Scale))
+}
+
+func (r *Tileview) Draw(screen tcell.Screen) {
+	r.Box.DrawForSubclass(screen, r)
+	x, y, width, height := r.GetInnerRect()
+	if height < 2 || width < 1 {
+		return
+	}
+	if r.Pyramid == nil {
+		tview.Print(screen, "no image", x, y, width, tview.AlignLeft, tcell.ColorGray)
+		return
+	}
+
+	// Half blocks give one by two pixels per cell, the last row is the
+	// status line
+	rows := height - 1
+	r.width, r.height = width, 2*rows
+	if r.fit {
+		r.Fit()
+	}
+	levels := r.Pyramid.Levels()
+	level := pyramid.BestLevel(levels, r.Scale)
+
+	// Screen pixel px, py shows full resolution pixel fx, fy
+	left := r.CenterX - float64(r.width)/2*r.Scale
+	top := r.CenterY - float64(r.height)/2*r.Scale
+	r.cache.Want(r.visible(level, left, top))
+
+	tiles := make(map[pyramid.TileKey]image.Image)
+	var failed error
+	sample := func(px, py int) (color.RGBA, bool, bool) {
+		fx, fy := left+(float64(px)+0.5)*r.Scale, top+(float64(py)+0.5)*r.Scale
+		if fx < 0 || fy < 0 || fx >= float64(levels[0].Width) || fy >= float64(levels[0].Height) {
+			return color.RGBA{}, false, false
+		}
+		// The wanted level, else the finest coarser one in memory
+		for i := level; i < len(levels); i++ {
+			l := levels[i]
+			lx, ly := int(fx/l.Downsample), int(fy/l.Downsample)
+			k := pyramid.TileKey{Level: i, Col: lx / l.TileWidth, Row: ly / l.TileHeight}
+			img, ok := tiles[k]
+			if !ok {
+				var err error
+				img, err, _ = r.cache.Get(k)
+				if err != nil && i == level {
+					failed = err
+				}
+				tiles[k] = img
+			}
+			if img == nil {
+				continue
+			}
+			p := img.Bounds().Min.Add(image.Pt(lx%l.TileWidth, ly%l.TileHeight))
+			if !p.In(img.Bounds()) {
+				continue
+			}
+			return color.RGBAModel.Convert(img.At(p.X, p.Y)).(color.RGBA), true, true
+		}
+		return color.RGBA{}, true, false
+	}
+
+	for row := 0; row < rows; row++ {
+		for col := 0; col < width; col++ {
+			topColor, topIn, topOK := sample(col, 2*row)
+			bottomColor, bottomIn, bottomOK := sample(col, 2*row+1)
+			style := tcell.StyleDefault
+			switch {
+			case topOK:
+				style = style.Foreground(rgb(topColor))
+			case topIn:
+				style = style.Foreground(missingColor)
+			default:
+				style = style.Foreground(tcell.ColorDefault)
+			}
+			switch {
+			case bottomOK:
+				style = style.Background(rgb(bottomColor))
+			case bottomIn:
+				style = style.Background(missingColor)
+			}
+			ch := '▀'
+			if !topIn && !bottomIn {
+				ch = ' '
+			}
+			screen.SetContent(x+col, y+row, ch, nil, style)
+		}
+	}
+
+	// Status line
+	cached, pending := r.cache.Stats()
+	l := levels[level]
+	status := fmt.Sprintf("[gray]zoom %s  level %d/%d %dx%d  at %d,%d  tiles %d",
+		zoomText(r.Scale), level, len(levels)-1, l.Width, l.Height, int(r.CenterX), int(r.CenterY), cached)
+	if pending > 0 {
+		status += fmt.Sprintf(", %d loading", pending)
+	}
+	if failed != nil {
+		status += "  [red]" + tview.Escape(failed.Error()) + "[gray]"
+	}
+	status += fmt.Sprintf("  %s  %s", tview.Escape(r.Name), r.Pyramid.Describe())
+	tview.Print(screen, status+"[-]", x, y+height-1, width, tview.AlignLeft, tcell.ColorWhite)
+}
+
+// visible returns the tiles of level in view, nearest the center first
+func (r *Tileview) visible(level int, left, top float64) []pyramid.TileKey {
+	l := r.Pyramid.Levels()[level]
+	scale := r.Scale / l.Downsample
+	c0 := clamp(int(left/l.Downsample)/l.TileWidth, 0, l.Across()-1)
+	r0 := clamp(int(top/l.Downsample)/l.TileHeight, 0, l.Down()-1)
+	c1 := clamp(int((left/l.Downsample+float64(r.width)*scale)/float64(l.TileWidth)), 0, l.Across()-1)
+	r1 := clamp(int((top/l.Downsample+float64(r.height)*scale)/float64(l.TileHeight)), 0, l.Down()-1)
+
+	var keys []pyramid.TileKey
+	for row := r0; row <= r1; row++ {
+		for col := c0; col <= c1; col++ {
+			keys = append(keys, pyramid.TileKey{Level: level, Col: col, Row: row})
+		}
+	}
+	cx, cy := float64(c0+c1)/2, float64(r0+r1)/2
+	distance := func(k pyramid.TileKey) float64 {
+		return math.Abs(float64(k.Col)-cx) + math.Abs(float64(k.Row)-cy)
+	}
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && distance(keys[j]) < distance(keys[j-1]); j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+	return keys
+}
+
+// zoomText shows the magnification as a percentage, or a fraction when
+// far out
+func zoomText(scale float64) string {
+	if scale > 100 {
+		return fmt.Sprintf("1/%.0f", scale)
+	}
+	return fmt.Sprintf("%.0f%%", 100/scale)
+}
+
+func rgb(c color.RGBA) tcell.Color {
+	return tcell.NewRGBColor(int32(c.R), int32(c.G), int32(c.B))
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func (r *Tileview) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return r.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		quarterX, quarterY := r.width/4, r.height/4
+		switch event.Key() {
+		// Sane keys
+		case tcell.KeyLeft:
+			r.Pan(-quarterX, 0)
+		case tcell.KeyRight:
+			r.Pan(quarterX, 0)
+		case tcell.KeyUp:
+			r.Pan(0, -quarterY)
+		case tcell.KeyDown:
+			r.Pan(0, quarterY)
+		case tcell.KeyRune:
+			// Vim keys
+			switch event.Rune() {
+			case 'h':
+				r.Pan(-quarterX, 0)
+			case 'l':
+				r.Pan(quarterX, 0)
+			case 'k':
+				r.Pan(0, -quarterY)
+			case 'j':
+				r.Pan(0, quarterY)
+			case 'H':
+				r.Pan(-r.width, 0)
+			case 'L':
+				r.Pan(r.width, 0)
+			case 'K':
+				r.Pan(0, -r.height)
+			case 'J':
+				r.Pan(0, r.height)
+			case '+', '=':
+				r.Zoom(2)
+			case '-':
+				r.Zoom(0.5)
+			case '0':
+				r.Scale = 1
+			case 'f':
+				r.Fit()
+			}
+		}
+	})
+}
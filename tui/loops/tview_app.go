@@ -7,6 +7,10 @@ import (
 
 func Run(ui *layout.UI) {
 	app := tview.NewApplication()
+	ui.App = app
+
+	// Kitty and sixel images are written once tview has drawn the frame
+	app.SetAfterDrawFunc(ui.Preview.AfterDraw)
 	if err := app.SetRoot(ui, true).EnableMouse(false).Run(); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,237 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TemplateDiff lines up one message template across two runs
+type TemplateDiff struct {
+	Pattern  string `json:"pattern" yaml:"pattern"`
+	CountA   int    `json:"count_a" yaml:"count_a"`
+	CountB   int    `json:"count_b" yaml:"count_b"`
+	ExampleA string `json:"example_a,omitempty" yaml:"example_a,omitempty"`
+	ExampleB string `json:"example_b,omitempty" yaml:"example_b,omitempty"`
+}
+
+// OnlyA reports whether the template occurs in the first run only
+func (t TemplateDiff) OnlyA() bool {
+	return t.CountA > 0 && t.CountB == 0
+}
+
+// OnlyB reports whether the template occurs in the second run only
+func (t TemplateDiff) OnlyB() bool {
+	return t.CountB > 0 && t.CountA == 0
+}
+
+// MetricDiff compares the final value of a metric in two runs
+type MetricDiff struct {
+	Name string  `json:"name" yaml:"name"`
+	A    float64 `json:"a" yaml:"a"`
+	B    float64 `json:"b" yaml:"b"`
+	HasA bool    `json:"has_a" yaml:"has_a"`
+	HasB bool    `json:"has_b" yaml:"has_b"`
+}
+
+// Delta from A to B, NaN unless both runs have the metric
+func (d MetricDiff) Delta() float64 {
+	if !d.HasA || !d.HasB {
+		return math.NaN()
+	}
+	return d.B - d.A
+}
+
+// Print
+func (d MetricDiff) String() string {
+	value := func(v float64, ok bool) string {
+		if !ok {
+			return "-"
+		}
+		return fmt.Sprintf("%.6g", v)
+	}
+	s := fmt.Sprintf("%s: %s -> %s", d.Name, value(d.A, d.HasA), value(d.B, d.HasB))
+	if delta := d.Delta(); !math.IsNaN(delta) {
+		s += fmt.Sprintf(" (%+.6g", delta)
+		if d.A != 0 {
+			s += fmt.Sprintf(", %+.1f%%", 100*delta/math.Abs(d.A))
+		}
+		s += ")"
+	}
+	return s
+}
+
+// Diff of two runs
+type Diff struct {
+	A string `json:"a" yaml:"a"`
+	B string `json:"b" yaml:"b"`
+
+	// Templates of A in order of first appearance, then those only in B
+	Templates []TemplateDiff `json:"templates" yaml:"templates"`
+
+	// Records whose template does not occur in the other run
+	OnlyA []Record `json:"only_a" yaml:"only_a"`
+	OnlyB []Record `json:"only_b" yaml:"only_b"`
+
+	// Final metric values, and derived ones such as duration and epoch_time
+	Metrics []MetricDiff `json:"metrics" yaml:"metrics"`
+}
+
+// Metrics derived from timestamps rather than extracted
+const (
+	MetricDuration  = "duration"
+	MetricEpochTime = "epoch_time"
+	MetricRecords   = "records"
+	MetricErrors    = "errors"
+)
+
+// DiffRuns compares the records of two loggers, extracting metrics with e
+func DiffRuns(a, b *Logger, e *Extractor) Diff {
+	d := Diff{A: a.Datadir, B: b.Datadir}
+
+	// Line up templates
+	countsA, order := countTemplates(a.Records)
+	countsB, orderB := countTemplates(b.Records)
+	for _, p := range orderB {
+		if _, ok := countsA[p]; !ok {
+			order = append(order, p)
+		}
+	}
+	for _, p := range order {
+		t := TemplateDiff{Pattern: p}
+		if ta, ok := countsA[p]; ok {
+			t.CountA, t.ExampleA = ta.Count, ta.Example
+		}
+		if tb, ok := countsB[p]; ok {
+			t.CountB, t.ExampleB = tb.Count, tb.Example
+		}
+		d.Templates = append(d.Templates, t)
+	}
+	for _, r := range a.Records {
+		if _, ok := countsB[TemplateOf(r)]; !ok {
+			d.OnlyA = append(d.OnlyA, r)
+		}
+	}
+	for _, r := range b.Records {
+		if _, ok := countsA[TemplateOf(r)]; !ok {
+			d.OnlyB = append(d.OnlyB, r)
+		}
+	}
+
+	// Final values of every metric in either run
+	finalA, finalB := runMetrics(a.Records, e), runMetrics(b.Records, e)
+	names := make([]string, 0, len(finalA)+len(finalB))
+	for name := range finalA {
+		names = append(names, name)
+	}
+	for name := range finalB {
+		if _, ok := finalA[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		va, okA := finalA[name]
+		vb, okB := finalB[name]
+		d.Metrics = append(d.Metrics, MetricDiff{Name: name, A: va, B: vb, HasA: okA, HasB: okB})
+	}
+	return d
+}
+
+// countTemplates counts templates of records, keeping their order of first
+// appearance
+func countTemplates(records []Record) (map[string]*Template, []string) {
+	counts := make(map[string]*Template)
+	var order []string
+	for _, r := range records {
+		p := TemplateOf(r)
+		t, ok := counts[p]
+		if !ok {
+			t = &Template{Pattern: p, Example: r.String()}
+			counts[p] = t
+			order = append(order, p)
+		}
+		t.Count++
+	}
+	return counts, order
+}
+
+// runMetrics returns the last value of each extracted metric along with
+// record counts, the duration of the run and the mean time per epoch, all
+// times in seconds
+func runMetrics(records []Record, e *Extractor) map[string]float64 {
+	values := make(map[string]float64)
+	values[MetricRecords] = float64(len(records))
+	errors := 0
+
+	var first, last time.Time
+	var epochStarts []time.Time
+	epoch, hasEpoch := 0.0, false
+	for _, r := range records {
+		if r.Level >= LevelError {
+			errors++
+		}
+		if !r.Time.IsZero() {
+			if first.IsZero() || r.Time.Before(first) {
+				first = r.Time
+			}
+			if r.Time.After(last) {
+				last = r.Time
+			}
+		}
+		for name, v := range e.Extract(r) {
+			values[name] = v
+			if name == "epoch" && !r.Time.IsZero() && (!hasEpoch || v != epoch) {
+				epoch, hasEpoch = v, true
+				epochStarts = append(epochStarts, r.Time)
+			}
+		}
+	}
+	values[MetricErrors] = float64(errors)
+	if !first.IsZero() {
+		values[MetricDuration] = last.Sub(first).Seconds()
+	}
+	if n := len(epochStarts); n > 1 {
+		values[MetricEpochTime] = epochStarts[n-1].Sub(epochStarts[0]).Seconds() / float64(n-1)
+	}
+	return values
+}
+
+// Print
+func (d Diff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "A: %s\nB: %s\n", d.A, d.B)
+
+	b.WriteString("\nMetrics:\n")
+	for _, m := range d.Metrics {
+		fmt.Fprintf(&b, "  %s\n", m)
+	}
+
+	b.WriteString("\nTemplates:\n")
+	for _, t := range d.Templates {
+		mark := " "
+		switch {
+		case t.OnlyA():
+			mark = "-"
+		case t.OnlyB():
+			mark = "+"
+		}
+		fmt.Fprintf(&b, "%s %8d %8d  %s\n", mark, t.CountA, t.CountB, t.Pattern)
+	}
+
+	if len(d.OnlyA) > 0 {
+		fmt.Fprintf(&b, "\nOnly in A (%d):\n", len(d.OnlyA))
+		for _, r := range d.OnlyA {
+			fmt.Fprintf(&b, "- %s\n", r)
+		}
+	}
+	if len(d.OnlyB) > 0 {
+		fmt.Fprintf(&b, "\nOnly in B (%d):\n", len(d.OnlyB))
+		for _, r := range d.OnlyB {
+			fmt.Fprintf(&b, "+ %s\n", r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,300 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// LogFile found under Datadir
+type LogFile struct {
+	// Relative to Datadir, with forward slashes
+	Path    string    `json:"path" yaml:"path"`
+	Size    int64     `json:"size" yaml:"size"`
+	ModTime time.Time `json:"mtime" yaml:"mtime"`
+
+	// ".gz", ".zst" or empty
+	Compression string `json:"compression,omitempty" yaml:"compression,omitempty"`
+
+	// Rotation suffix such as "1" in app.log.1, empty for the live file
+	Rotation string `json:"rotation,omitempty" yaml:"rotation,omitempty"`
+}
+
+// Print
+func (f LogFile) String() string {
+	return fmt.Sprintf("%10d  %s  %s", f.Size, f.ModTime.Format("2006-01-02 15:04:05"), f.Path)
+}
+
+// Compressed reports whether the file has to be decompressed to be read
+func (f LogFile) Compressed() bool {
+	return f.Compression != ""
+}
+
+// Suffixes of compressed files
+var compressions = []string{".gz", ".zst"}
+
+// app.log.1, app.log.2023-01-02
+var rotationRe = regexp.MustCompile(`\.(\d[\d_-]*)$`)
+
+// splitLogName strips compression and rotation suffixes from a file name
+func splitLogName(name string) (stem, rotation, compression string) {
+	stem = name
+	for _, c := range compressions {
+		if strings.HasSuffix(stem, c) {
+			stem, compression = strings.TrimSuffix(stem, c), c
+			break
+		}
+	}
+	if m := rotationRe.FindStringSubmatch(stem); m != nil {
+		// Only a suffix after a real extension counts as rotation
+		if base := strings.TrimSuffix(stem, m[0]); filepath.Ext(base) != "" {
+			stem, rotation = base, m[1]
+		}
+	}
+	return stem, rotation, compression
+}
+
+// IsCompressed reports whether path has a compression suffix
+func IsCompressed(path string) bool {
+	_, _, compression := splitLogName(filepath.Base(path))
+	return compression != ""
+}
+
+// matchesExt reports whether name, without rotation and compression
+// suffixes, has one of exts
+func matchesExt(name string, exts []string) bool {
+	stem, _, _ := splitLogName(name)
+	for _, ext := range exts {
+		if filepath.Ext(stem) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// Glob turns a pattern with *, ? and ** into a matcher on slash separated
+// paths. Patterns without a slash match the base name only.
+type Glob struct {
+	Pattern  string
+	re       *regexp.Regexp
+	baseOnly bool
+}
+
+func NewGlob(pattern string) (*Glob, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [ in %q", pattern)
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, err
+	}
+	return &Glob{Pattern: pattern, re: re, baseOnly: !strings.Contains(pattern, "/")}, nil
+}
+
+// Match a slash separated relative path
+func (g *Glob) Match(rel string) bool {
+	if g.baseOnly {
+		return g.re.MatchString(rel[strings.LastIndexByte(rel, '/')+1:])
+	}
+	return g.re.MatchString(rel)
+}
+
+func compileGlobs(patterns []string) ([]*Glob, error) {
+	globs := make([]*Glob, 0, len(patterns))
+	for _, p := range patterns {
+		g, err := NewGlob(p)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, g)
+	}
+	return globs, nil
+}
+
+func matchAny(globs []*Glob, rel string) bool {
+	for _, g := range globs {
+		if g.Match(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListLogFiles finds files in Datadir matching FileExts, Include and
+// Exclude, descending into subdirectories when Recursive is set
+func (m *Logger) ListLogFiles() ([]LogFile, error) {
+	include, err := compileGlobs(m.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compileGlobs(m.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []LogFile
+	err = filepath.WalkDir(m.Datadir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable subdirectories are skipped, the root is an error
+			if path == m.Datadir {
+				return err
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(m.Datadir, path)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if path != m.Datadir && (!m.Recursive || matchAny(exclude, rel)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !matchesExt(d.Name(), m.FileExts) || matchAny(exclude, rel) {
+			return nil
+		}
+		if len(include) > 0 && !matchAny(include, rel) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		_, rotation, compression := splitLogName(d.Name())
+		files = append(files, LogFile{
+			Path:        rel,
+			Size:        info.Size(),
+			ModTime:     info.ModTime(),
+			Compression: compression,
+			Rotation:    rotation,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortLogFiles(files)
+	return files, nil
+}
+
+// sortLogFiles orders by path, rotated files oldest first before the live one
+func sortLogFiles(files []LogFile) {
+	stem := func(f LogFile) string {
+		s, _, _ := splitLogName(f.Path)
+		return s
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if sa, sb := stem(a), stem(b); sa != sb {
+			return sa < sb
+		}
+		switch {
+		case a.Rotation == b.Rotation:
+			return a.Path < b.Path
+		case a.Rotation == "":
+			return false
+		case b.Rotation == "":
+			return true
+		}
+
+		// app.log.2 is older than app.log.1, dated suffixes sort as text
+		na, errA := strconv.Atoi(a.Rotation)
+		nb, errB := strconv.Atoi(b.Rotation)
+		if errA == nil && errB == nil {
+			return na > nb
+		}
+		return a.Rotation < b.Rotation
+	})
+}
+
+// OpenLogFile opens a log file, decompressing .gz and .zst transparently
+func OpenLogFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	_, _, compression := splitLogName(filepath.Base(path))
+	switch compression {
+	case ".gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &decompressed{Reader: gz, closers: []io.Closer{gz, f}}, nil
+	case ".zst":
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &decompressed{Reader: zr, closers: []io.Closer{zstdCloser{zr}, f}}, nil
+	default:
+		return f, nil
+	}
+}
+
+// Closes the decompressor and the underlying file
+type decompressed struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (d *decompressed) Close() error {
+	var first error
+	for _, c := range d.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// zstd.Decoder.Close returns nothing
+type zstdCloser struct{ d *zstd.Decoder }
+
+func (z zstdCloser) Close() error {
+	z.d.Close()
+	return nil
+}
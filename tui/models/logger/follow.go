@@ -0,0 +1,270 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Follower tails log files and streams appended records to subscribers
+type Follower struct {
+	// How often files are checked for new data
+	Interval time.Duration
+
+	// Emit existing content of files found on the first poll,
+	// otherwise only lines appended after Run starts
+	FromStart bool
+
+	discover    func() ([]string, error)
+	offsets     map[string]int64
+	mu          sync.Mutex
+	subscribers []chan Record
+	files       map[string]*tailedFile
+	stop        chan bool
+	stopOnce    sync.Once
+}
+
+// State of one followed file
+type tailedFile struct {
+	path    string
+	file    *os.File
+	info    os.FileInfo
+	offset  int64
+	line    int
+	partial []byte
+}
+
+// NewFollower follows the live log files of m, including files created
+// later. Rotated and compressed files are skipped. Files already in
+// m.LogFiles are followed from the size they were listed with, so lines
+// appended while they were being read are not missed.
+func NewFollower(m *Logger) *Follower {
+	f := newFollower(func() ([]string, error) {
+		files, err := m.ListLogFiles()
+		var paths []string
+		for _, f := range files {
+			if !f.Compressed() && f.Rotation == "" {
+				paths = append(paths, m.FilePath(f))
+			}
+		}
+		return paths, err
+	})
+	for _, lf := range m.LogFiles {
+		f.offsets[m.FilePath(lf)] = lf.Size
+	}
+	return f
+}
+
+// NewFileFollower follows a single file, waiting for it if it does not exist
+func NewFileFollower(path string) *Follower {
+	return newFollower(func() ([]string, error) {
+		return []string{path}, nil
+	})
+}
+
+func newFollower(discover func() ([]string, error)) *Follower {
+	return &Follower{
+		Interval: 250 * time.Millisecond,
+		discover: discover,
+		offsets:  make(map[string]int64),
+		files:    make(map[string]*tailedFile),
+		stop:     make(chan bool),
+	}
+}
+
+// Subscribe returns a channel receiving every new record
+func (f *Follower) Subscribe() <-chan Record {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan Record, 256)
+	f.subscribers = append(f.subscribers, ch)
+	return ch
+}
+
+// Run polls the files until Stop is called, then closes all subscriptions
+func (f *Follower) Run() {
+	defer f.closeAll()
+
+	f.poll(f.FromStart)
+	ticker := time.NewTicker(f.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			f.poll(true)
+		}
+	}
+}
+
+// Stop ends Run; safe to call more than once
+func (f *Follower) Stop() {
+	f.stopOnce.Do(func() { close(f.stop) })
+}
+
+func (f *Follower) poll(fromStart bool) {
+	paths, err := f.discover()
+	if err != nil {
+		log.Println("Could not list log files: ", err)
+	}
+
+	// Pick up files that appeared since the last poll
+	for _, path := range paths {
+		if _, ok := f.files[path]; ok {
+			continue
+		}
+		skip := int64(-1)
+		if fromStart {
+			skip = 0
+		} else if offset, ok := f.offsets[path]; ok {
+			skip = offset
+		}
+		if t := openTailed(path, skip); t != nil {
+			f.files[path] = t
+		}
+	}
+
+	for path, t := range f.files {
+		if !f.update(t) {
+			delete(f.files, path)
+		}
+	}
+}
+
+// openTailed opens path positioned after its first skip bytes, or at its
+// current end if skip is negative
+func openTailed(path string, skip int64) *tailedFile {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil
+	}
+	t := &tailedFile{path: path, file: file, info: info}
+	if skip != 0 {
+		t.skip(skip)
+	}
+	return t
+}
+
+// skip consumes the first n bytes, or all existing content if n is
+// negative, counting lines and keeping the unterminated tail so that it is
+// emitted once completed
+func (t *tailedFile) skip(n int64) {
+	var r io.Reader = t.file
+	if n >= 0 {
+		r = io.LimitReader(t.file, n)
+	}
+	buf := make([]byte, 64*1024)
+	var last []byte
+	for {
+		n, err := r.Read(buf)
+		chunk := buf[:n]
+		t.offset += int64(n)
+		t.line += bytes.Count(chunk, []byte{'\n'})
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			last = append(last[:0], chunk[i+1:]...)
+		} else {
+			last = append(last, chunk...)
+		}
+		if err != nil {
+			break
+		}
+	}
+	t.partial = last
+}
+
+// update emits new lines of t; returns false once t should be dropped
+func (f *Follower) update(t *tailedFile) bool {
+	info, err := os.Stat(t.path)
+	switch {
+	case err != nil:
+		// Removed: drain what was written before, then wait for a new file
+		f.drain(t)
+		t.file.Close()
+		return false
+	case !os.SameFile(info, t.info):
+		// Rotated: finish the old file and start on the new one
+		f.drain(t)
+		t.file.Close()
+		file, err := os.Open(t.path)
+		if err != nil {
+			return false
+		}
+		t.file, t.info, t.offset, t.line, t.partial = file, info, 0, 0, nil
+	case info.Size() < t.offset:
+		// Truncated: start over
+		t.offset, t.line, t.partial = 0, 0, nil
+	}
+	t.info = info
+	f.drain(t)
+	return true
+}
+
+// drain reads from the current offset to EOF and emits complete lines
+func (f *Follower) drain(t *tailedFile) {
+	if _, err := t.file.Seek(t.offset, io.SeekStart); err != nil {
+		return
+	}
+	data, err := io.ReadAll(t.file)
+	if err != nil || len(data) == 0 {
+		return
+	}
+	t.offset += int64(len(data))
+	data = append(t.partial, data...)
+
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		t.line++
+		line := string(data[:i])
+		data = data[i+1:]
+		if len(bytes.TrimSpace([]byte(line))) == 0 {
+			continue
+		}
+		rec := ParseLine(line)
+		rec.File = t.path
+		rec.Line = t.line
+		if !f.publish(rec) {
+			return
+		}
+	}
+	t.partial = append([]byte(nil), data...)
+}
+
+// publish sends rec to every subscriber, without holding mu so a slow
+// subscriber never blocks Subscribe, and gives up once stopped
+func (f *Follower) publish(rec Record) bool {
+	f.mu.Lock()
+	subscribers := append([]chan Record(nil), f.subscribers...)
+	f.mu.Unlock()
+	for _, ch := range subscribers {
+		select {
+		case ch <- rec:
+		case <-f.stop:
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Follower) closeAll() {
+	for _, t := range f.files {
+		t.file.Close()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ch := range f.subscribers {
+		close(ch)
+	}
+	f.subscribers = nil
+}
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFollowerFromListedSize(t *testing.T) {
+	m := writeLogs(t, map[string]string{"a.log": "msg=one\nmsg=two\n"})
+	path := m.FilePath(m.LogFiles[0])
+
+	// Appended after the files were listed, before following starts
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString("msg=three\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFollower(m)
+	f.Interval = 10 * time.Millisecond
+	records := f.Subscribe()
+	go f.Run()
+	defer f.Stop()
+
+	if _, err := file.WriteString("msg=four\n"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []struct {
+		msg  string
+		line int
+	}{{"three", 3}, {"four", 4}} {
+		select {
+		case r := <-records:
+			if r.Message != want.msg || r.Line != want.line {
+				t.Errorf("got %q at line %d, want %q at line %d", r.Message, r.Line, want.msg, want.line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no record for %q", want.msg)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Lines per checkpoint block
+const indexBlock = 1024
+
+// Bumped whenever the on-disk layout or line parsing changes
+const indexVersion = 1
+
+// Bytes hashed to recognize a file that was replaced or rewritten
+const indexHeadLen = 4096
+
+// Block of indexBlock lines with the range of timestamps inside it
+type Checkpoint struct {
+	Line    int
+	Offset  int64
+	MinTime time.Time
+	MaxTime time.Time
+}
+
+// HasTime reports whether any line of the block has a timestamp
+func (c Checkpoint) HasTime() bool {
+	return !c.MaxTime.IsZero()
+}
+
+// Bitmap over line numbers
+type Bitmap []uint64
+
+func (b *Bitmap) Set(i int) {
+	for len(*b) <= i/64 {
+		*b = append(*b, 0)
+	}
+	(*b)[i/64] |= 1 << uint(i%64)
+}
+
+func (b Bitmap) Has(i int) bool {
+	return i/64 < len(b) && b[i/64]&(1<<uint(i%64)) != 0
+}
+
+// FileIndex records where every line of a log file starts, timestamp
+// checkpoints and one bitmap of lines per level
+type FileIndex struct {
+	Version int
+	Path    string
+
+	// Bytes covered, up to the end of the last complete line
+	Size    int64
+	ModTime time.Time
+	Head    uint32
+
+	// Offsets[i] is where line i+1 starts
+	Offsets     []int64
+	Checkpoints []Checkpoint
+	Levels      map[Level]Bitmap
+}
+
+// Lines indexed
+func (ix *FileIndex) Lines() int {
+	return len(ix.Offsets)
+}
+
+// Index of all log files of a Logger, stored next to Datadir
+type Index struct {
+	Dir   string
+	Files map[string]*FileIndex
+}
+
+// IndexDir is where the index of Datadir is stored
+func (m *Logger) IndexDir() string {
+	return filepath.Clean(m.Datadir) + ".index"
+}
+
+// UpdateIndex loads the stored index, indexes lines appended since, rebuilds
+// files that were truncated or replaced, and saves it again. Compressed files
+// are not indexed.
+func (m *Logger) UpdateIndex() (*Index, error) {
+	idx := &Index{Dir: m.IndexDir(), Files: make(map[string]*FileIndex)}
+	for _, f := range m.LogFiles {
+		if f.Compressed() {
+			continue
+		}
+		stored := filepath.Join(idx.Dir, filepath.FromSlash(f.Path)+".idx")
+		ix, err := loadFileIndex(stored)
+		if err != nil || ix.Version != indexVersion || ix.Path != f.Path {
+			ix = &FileIndex{Version: indexVersion, Path: f.Path}
+		}
+		changed, err := ix.update(m.FilePath(f))
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			if err := saveFileIndex(stored, ix); err != nil {
+				return nil, err
+			}
+		}
+		idx.Files[f.Path] = ix
+	}
+	return idx, nil
+}
+
+func loadFileIndex(path string) (*FileIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var ix FileIndex
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&ix); err != nil {
+		return nil, err
+	}
+	return &ix, nil
+}
+
+// saveFileIndex writes through a temporary file so a crash never leaves a
+// half written index
+func saveFileIndex(path string, ix *FileIndex) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	w := bufio.NewWriter(f)
+	err = gob.NewEncoder(w).Encode(ix)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// headSum hashes the first bytes of a file, limited to what was indexed
+func headSum(f *os.File, size int64) (uint32, error) {
+	n := size
+	if n > indexHeadLen {
+		n = indexHeadLen
+	}
+	buf := make([]byte, n)
+	if _, err := f.ReadAt(buf, 0); err != nil && err != io.EOF {
+		return 0, err
+	}
+	return crc32.ChecksumIEEE(buf), nil
+}
+
+// update indexes new lines of the file at path, starting over if the file
+// no longer begins with what was indexed. Reports whether anything changed.
+func (ix *FileIndex) update(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	// Same content as before, possibly with lines appended
+	if ix.Size > 0 {
+		head, err := headSum(f, ix.Size)
+		if err != nil {
+			return false, err
+		}
+		if info.Size() < ix.Size || head != ix.Head {
+			*ix = FileIndex{Version: indexVersion, Path: ix.Path}
+		}
+	}
+	if info.Size() == ix.Size {
+		return false, nil
+	}
+	if ix.Levels == nil {
+		ix.Levels = make(map[Level]Bitmap)
+	}
+
+	if _, err := f.Seek(ix.Size, io.SeekStart); err != nil {
+		return false, err
+	}
+	reader := bufio.NewReaderSize(f, 256*1024)
+	offset := ix.Size
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// Leave an unterminated last line for the next update
+			break
+		}
+		n := len(ix.Offsets)
+		ix.Offsets = append(ix.Offsets, offset)
+		offset += int64(len(line))
+
+		if n%indexBlock == 0 {
+			ix.Checkpoints = append(ix.Checkpoints, Checkpoint{Line: n + 1, Offset: ix.Offsets[n]})
+		}
+		rec := ParseLine(line)
+		bm := ix.Levels[rec.Level]
+		bm.Set(n)
+		ix.Levels[rec.Level] = bm
+
+		if !rec.Time.IsZero() {
+			c := &ix.Checkpoints[len(ix.Checkpoints)-1]
+			if c.MinTime.IsZero() || rec.Time.Before(c.MinTime) {
+				c.MinTime = rec.Time
+			}
+			if rec.Time.After(c.MaxTime) {
+				c.MaxTime = rec.Time
+			}
+		}
+	}
+
+	ix.Size = offset
+	ix.ModTime = info.ModTime()
+	ix.Head, err = headSum(f, ix.Size)
+	return true, err
+}
+
+// Search reads only the lines of the file that can satisfy the time range
+// and levels; zero times and nil levels do not restrict
+func (ix *FileIndex) Search(path string, from, to time.Time, levels []Level, fn func(Record) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Lines allowed by the level bitmaps
+	var allowed []Bitmap
+	for _, l := range levels {
+		allowed = append(allowed, ix.Levels[l])
+	}
+	wanted := func(i int) bool {
+		if levels == nil {
+			return true
+		}
+		for _, bm := range allowed {
+			if bm.Has(i) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Skip whole blocks outside the time range. Blocks are checked one by
+	// one, as blocks without times or with out of order lines break any
+	// ordering a binary search would need.
+	buf := make([]byte, 0, 4096)
+	for _, c := range ix.Checkpoints {
+		if !from.IsZero() || !to.IsZero() {
+			if !c.HasTime() || c.MaxTime.Before(from) {
+				continue
+			}
+			if !to.IsZero() && c.MinTime.After(to) {
+				continue
+			}
+		}
+
+		end := c.Line - 1 + indexBlock
+		if end > len(ix.Offsets) {
+			end = len(ix.Offsets)
+		}
+		for i := c.Line - 1; i < end; i++ {
+			if !wanted(i) {
+				continue
+			}
+			next := ix.Size
+			if i+1 < len(ix.Offsets) {
+				next = ix.Offsets[i+1]
+			}
+			n := int(next - ix.Offsets[i])
+			if cap(buf) < n {
+				buf = make([]byte, n)
+			}
+			buf = buf[:n]
+			if _, err := f.ReadAt(buf, ix.Offsets[i]); err != nil {
+				return fmt.Errorf("%s: line %d: %w", path, i+1, err)
+			}
+			if strings.TrimSpace(string(buf)) == "" {
+				continue
+			}
+			rec := ParseLine(string(buf))
+			rec.File = path
+			rec.Line = i + 1
+			if !fn(rec) {
+				return nil
+			}
+		}
+	}
+
+	// Lines appended since the index was updated
+	if _, err := f.Seek(ix.Size, io.SeekStart); err != nil {
+		return err
+	}
+	reader := NewReader(f, path)
+	for {
+		rec, ok := reader.Next()
+		if !ok {
+			break
+		}
+		rec.Line += ix.Lines()
+		if !fn(rec) {
+			break
+		}
+	}
+	return reader.Err()
+}
+
+// SetRecordsIndexed loads the records matching q, reading only candidate
+// lines of indexed files and scanning the others
+func (m *Logger) SetRecordsIndexed(idx *Index, q *Query) {
+	m.Records = nil
+	from, to, levels := q.Bounds()
+	collect := func(r Record) bool {
+		if q.Match(r) {
+			m.Records = append(m.Records, r)
+		}
+		return true
+	}
+
+	for _, f := range m.LogFiles {
+		if err := m.readIndexed(idx, f, from, to, levels, collect); err != nil {
+			log.Println("Could not read log file: ", f.Path, err)
+		}
+	}
+}
+
+// Records each file reads ahead of MergeIndexed
+const mergeAhead = 64
+
+// MergeIndexed streams the records matching q as one timeline like Merge,
+// reading only candidate lines of indexed files, until fn returns false.
+// Each file is read by its own goroutine a few records ahead of the merge.
+func (m *Logger) MergeIndexed(idx *Index, q *Query, fn func(Record) bool) {
+	from, to, levels := q.Bounds()
+	done := make(chan bool)
+	defer close(done)
+
+	var sources []func() (Record, bool)
+	for _, f := range m.LogFiles {
+		records := make(chan Record, mergeAhead)
+		go func(f LogFile) {
+			defer close(records)
+			err := m.readIndexed(idx, f, from, to, levels, func(r Record) bool {
+				if !q.Match(r) {
+					return true
+				}
+				select {
+				case records <- r:
+					return true
+				case <-done:
+					return false
+				}
+			})
+			if err != nil {
+				log.Println("Could not read log file: ", f.Path, err)
+			}
+		}(f)
+		sources = append(sources, func() (Record, bool) {
+			r, ok := <-records
+			return r, ok
+		})
+	}
+	mergeSources(sources, fn)
+}
+
+// readIndexed reads the candidate lines of f when it is indexed, else scans
+// all of it
+func (m *Logger) readIndexed(idx *Index, f LogFile, from, to time.Time, levels []Level, fn func(Record) bool) error {
+	if ix, ok := idx.Files[f.Path]; ok {
+		return ix.Search(m.FilePath(f), from, to, levels, fn)
+	}
+	r, err := OpenLogFile(m.FilePath(f))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return ScanRecords(r, m.FilePath(f), fn)
+}
+
+// Print
+func (idx *Index) String() string {
+	paths := make([]string, 0, len(idx.Files))
+	for p := range idx.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	s := fmt.Sprintf("Index: %s", idx.Dir)
+	for _, p := range paths {
+		ix := idx.Files[p]
+		s += fmt.Sprintf("\n\t%10d lines %6d blocks %12d bytes  %s", ix.Lines(), len(ix.Checkpoints), ix.Size, p)
+	}
+	return s
+}
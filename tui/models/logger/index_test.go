@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runLog has lines a second apart cycling through the levels, and an
+// untimed continuation after every tenth
+func runLog(lines int, start time.Time) string {
+	levels := []string{"debug", "info", "warn", "error"}
+	var b strings.Builder
+	for i := 0; i < lines; i++ {
+		ts := start.Add(time.Duration(i) * time.Second).Format(time.RFC3339)
+		fmt.Fprintf(&b, "time=%s level=%s msg=step n=%d\n", ts, levels[i%len(levels)], i)
+		if i%10 == 0 {
+			b.WriteString("  continued\n")
+		}
+	}
+	return b.String()
+}
+
+func recordLines(records []Record) []string {
+	lines := make([]string, len(records))
+	for i, r := range records {
+		lines[i] = fmt.Sprintf("%s:%d", filepath.Base(r.File), r.Line)
+	}
+	return lines
+}
+
+func TestSetRecordsIndexed(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	m := writeLogs(t, map[string]string{
+		"a.log": runLog(3000, start),
+		"b.log": runLog(500, start.Add(time.Hour)),
+	})
+	idx, err := m.UpdateIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 3850},
+		{"level>=warn", 1750},
+		{"level=error", 875},
+		{`time>="2024-01-01T10:20:00Z" and time<"2024-01-01T10:30:00Z"`, 600},
+		{`time>="2024-01-01T11:00:00Z" and level>=error`, 125},
+		{`time>="2024-01-02T00:00:00Z"`, 0},
+		{"msg~step and n>2990", 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Reading through the index finds what a full scan does
+			m.SetRecords()
+			scanned := q.Filter(m.Records)
+			if len(scanned) != tt.want {
+				t.Fatalf("full scan found %d records, want %d", len(scanned), tt.want)
+			}
+			m.SetRecordsIndexed(idx, q)
+			got, want := strings.Join(recordLines(m.Records), " "), strings.Join(recordLines(scanned), " ")
+			if got != want {
+				t.Errorf("indexed %d records, full scan %d", len(m.Records), len(scanned))
+			}
+		})
+	}
+}
+
+func TestUpdateIndex(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	m := writeLogs(t, map[string]string{"a.log": runLog(100, start)})
+	path := m.FilePath(m.LogFiles[0])
+	lines := func() int {
+		t.Helper()
+		idx, err := m.UpdateIndex()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return idx.Files[m.LogFiles[0].Path].Lines()
+	}
+
+	tests := []struct {
+		name   string
+		change func() error
+		want   int
+	}{
+		{
+			name:   "new",
+			change: func() error { return nil },
+			want:   110,
+		},
+		{
+			name: "appended",
+			change: func() error {
+				f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				_, err = f.WriteString(runLog(20, start.Add(time.Hour)))
+				return err
+			},
+			want: 132,
+		},
+		{
+			name: "unterminated line left for later",
+			change: func() error {
+				f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				_, err = f.WriteString("msg=partial")
+				return err
+			},
+			want: 132,
+		},
+		{
+			name:   "rewritten",
+			change: func() error { return os.WriteFile(path, []byte(runLog(5, start.Add(2*time.Hour))), 0o644) },
+			want:   6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err != nil {
+				t.Fatal(err)
+			}
+			if got := lines(); got != tt.want {
+				t.Errorf("%d lines indexed, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// Only the index itself is left next to the datadir
+	var stored []string
+	filepath.WalkDir(m.IndexDir(), func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			stored = append(stored, filepath.Base(p))
+		}
+		return nil
+	})
+	if strings.Join(stored, " ") != "a.log.idx" {
+		t.Errorf("index dir has %v, want only a.log.idx", stored)
+	}
+}
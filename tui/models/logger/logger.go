@@ -3,58 +3,91 @@ package logger
 import (
 	"fmt"
 	"log"
-	"os"
 	"path/filepath"
 )
 
 // Logger data
 type Logger struct {
 	Datadir  string
-	FileExt  string
+	FileExts []string
 	Loaded   chan bool
-	LogFiles []string
+	LogFiles []LogFile
+	Records  []Record
+	Metrics  []Series
+	Stats    Stats
+
+	// Discovery options, globs match paths relative to Datadir
+	Recursive bool
+	Include   []string
+	Exclude   []string
 }
 
 // From args
-func New(datadir, fileext string) *Logger {
+func New(datadir string, fileexts []string) *Logger {
 	m := Logger{
-		Datadir: datadir,
-		FileExt: fileext,
-		Loaded:  make(chan bool),
+		Datadir:  datadir,
+		FileExts: fileexts,
+		Loaded:   make(chan bool),
 	}
 	return &m
 }
 
 // Defaults
 func Default() *Logger {
-	return New("./.log", ".log")
+	return New("./.log", []string{".log"})
 }
 
 // Print
 func (m Logger) String() string {
+	files := ""
+	for _, f := range m.LogFiles {
+		files += "\n\t\t" + f.String()
+	}
 	return fmt.Sprintf(
 		`Logger:
 	Datadir: %s
-	FileExt: %s
-	  LogFiles: %v`, m.Datadir, m.FileExt, m.LogFiles)
+	FileExts: %v
+	  LogFiles: %d%s
+	   Records: %d`, m.Datadir, m.FileExts, len(m.LogFiles), files, len(m.Records))
 }
 
 func (m *Logger) SetLogFiles() {
+	// Inform that load is finished, even if nothing was found
+	defer func() { m.Loaded <- true }()
+
 	// Check if logdir exists, else return without error
-	entries, err := os.ReadDir(m.Datadir)
+	files, err := m.ListLogFiles()
 	if err != nil {
 		log.Println("Could not read datadir: ", m.Datadir, err)
 		return
 	}
+	m.LogFiles = files
+}
+
+func (m *Logger) SetRecords() {
+	m.Records = nil
 
-	// Iterate over log directory and append to LogFiles
-	for _, e := range entries {
-		fmt.Println(e.Name())
-		if filepath.Ext(e.Name()) == m.FileExt {
-			m.LogFiles = append(m.LogFiles, e.Name())
+	// Parse every log file, skipping unreadable ones
+	for _, f := range m.LogFiles {
+		records, err := ParseFile(m.FilePath(f))
+		if err != nil {
+			log.Println("Could not parse log file: ", f.Path, err)
+			continue
 		}
+		m.Records = append(m.Records, records...)
 	}
+}
+
+// FilePath joins Datadir and the relative path of f
+func (m *Logger) FilePath(f LogFile) string {
+	return filepath.Join(m.Datadir, filepath.FromSlash(f.Path))
+}
 
-	// Inform that load is finished
-	m.Loaded <- true
+// Paths of all LogFiles
+func (m *Logger) Paths() []string {
+	paths := make([]string, len(m.LogFiles))
+	for i, f := range m.LogFiles {
+		paths[i] = m.FilePath(f)
+	}
+	return paths
 }
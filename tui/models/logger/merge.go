@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"container/heap"
+	"log"
+	"time"
+)
+
+// Next record of one source in a merge
+type mergeCursor struct {
+	next  func() (Record, bool)
+	rec   Record
+	order int
+
+	// Lines without a timestamp sort with the line before them
+	key time.Time
+}
+
+func (c *mergeCursor) advance() bool {
+	rec, ok := c.next()
+	if !ok {
+		return false
+	}
+	c.rec = rec
+	if !rec.Time.IsZero() {
+		c.key = rec.Time
+	}
+	return true
+}
+
+// Min-heap on timestamp, then file order, then line
+type mergeHeap []*mergeCursor
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if !h[i].key.Equal(h[j].key) {
+		return h[i].key.Before(h[j].key)
+	}
+	if h[i].order != h[j].order {
+		return h[i].order < h[j].order
+	}
+	return h[i].rec.Line < h[j].rec.Line
+}
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(*mergeCursor)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// MergeFiles streams the records of all files ordered by timestamp, calling
+// fn until it returns false. Only one line per file is held in memory, and
+// files that cannot be opened are skipped.
+func MergeFiles(paths []string, fn func(Record) bool) error {
+	var readers []*Reader
+	var sources []func() (Record, bool)
+	for _, path := range paths {
+		f, err := OpenLogFile(path)
+		if err != nil {
+			log.Println("Could not open log file: ", path, err)
+			continue
+		}
+		defer f.Close()
+
+		reader := NewReader(f, path)
+		readers = append(readers, reader)
+		sources = append(sources, reader.Next)
+	}
+	mergeSources(sources, fn)
+
+	// Report the first read error
+	for _, r := range readers {
+		if err := r.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeSources calls fn with the records pulled from every source, each in
+// order, as one timeline until fn returns false
+func mergeSources(sources []func() (Record, bool), fn func(Record) bool) {
+	h := make(mergeHeap, 0, len(sources))
+	for i, next := range sources {
+		c := &mergeCursor{next: next, order: i}
+		if c.advance() {
+			h = append(h, c)
+		}
+	}
+	heap.Init(&h)
+
+	for h.Len() > 0 {
+		c := h[0]
+		if !fn(c.rec) {
+			return
+		}
+		if c.advance() {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+}
+
+// Merge streams the records of all LogFiles as one timeline
+func (m *Logger) Merge(fn func(Record) bool) error {
+	return MergeFiles(m.Paths(), fn)
+}
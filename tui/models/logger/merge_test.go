@@ -0,0 +1,148 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeLogs writes each file under a new datadir and lists them in a Logger
+func writeLogs(t *testing.T, files map[string]string) *Logger {
+	t.Helper()
+	root := t.TempDir()
+	dir := filepath.Join(root, "log")
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := New(dir, []string{".log"})
+	m.Recursive = true
+	logFiles, err := m.ListLogFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(logFiles, func(i, j int) bool { return logFiles[i].Path < logFiles[j].Path })
+	m.LogFiles = logFiles
+	return m
+}
+
+// mergedLines is file:line of every record merged from m
+func mergedLines(m *Logger, q *Query, idx *Index) []string {
+	var lines []string
+	fn := func(r Record) bool {
+		lines = append(lines, fmt.Sprintf("%s:%d", filepath.Base(r.File), r.Line))
+		return true
+	}
+	if idx != nil {
+		m.MergeIndexed(idx, q, fn)
+	} else {
+		m.Merge(func(r Record) bool {
+			if q.Match(r) {
+				return fn(r)
+			}
+			return true
+		})
+	}
+	return lines
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		query string
+		want  string
+	}{
+		{
+			name: "interleaved",
+			files: map[string]string{
+				"a.log": "time=2024-01-01T10:00:00Z msg=a1\ntime=2024-01-01T10:00:02Z msg=a2\n",
+				"b.log": "time=2024-01-01T10:00:01Z msg=b1\ntime=2024-01-01T10:00:03Z msg=b2\n",
+			},
+			want: "a.log:1 b.log:1 a.log:2 b.log:2",
+		},
+		{
+			name: "untimed lines follow their record",
+			files: map[string]string{
+				"a.log": "time=2024-01-01T10:00:00Z msg=a1\ntime=2024-01-01T10:00:02Z msg=a2\n",
+				"b.log": "time=2024-01-01T10:00:01Z msg=b1\n  trace one\n  trace two\n",
+			},
+			want: "a.log:1 b.log:1 b.log:2 b.log:3 a.log:2",
+		},
+		{
+			name: "ties keep file order",
+			files: map[string]string{
+				"a.log": "time=2024-01-01T10:00:00Z msg=a1\n",
+				"b.log": "time=2024-01-01T10:00:00Z msg=b1\n",
+			},
+			want: "a.log:1 b.log:1",
+		},
+		{
+			name: "query",
+			files: map[string]string{
+				"a.log": "time=2024-01-01T10:00:00Z level=info msg=a1\ntime=2024-01-01T10:00:02Z level=error msg=a2\n",
+				"b.log": "time=2024-01-01T10:00:01Z level=warn msg=b1\ntime=2024-01-01T10:00:03Z level=debug msg=b2\n",
+			},
+			query: "level>=warn",
+			want:  "b.log:1 a.log:2",
+		},
+		{
+			name:  "empty",
+			files: map[string]string{"a.log": ""},
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := writeLogs(t, tt.files)
+			q, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(mergedLines(m, q, nil), " "); got != tt.want {
+				t.Errorf("Merge = %q, want %q", got, tt.want)
+			}
+
+			// Reading through the index merges the same timeline
+			idx, err := m.UpdateIndex()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(mergedLines(m, q, idx), " "); got != tt.want {
+				t.Errorf("MergeIndexed = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeStops(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&a, "time=2024-01-01T10:%02d:%02dZ msg=a%d\n", i/60%60, i%60, i)
+		fmt.Fprintf(&b, "time=2024-01-01T10:%02d:%02dZ msg=b%d\n", i/60%60, i%60, i)
+	}
+	m := writeLogs(t, map[string]string{"a.log": a.String(), "b.log": b.String()})
+	idx, err := m.UpdateIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, _ := ParseQuery("")
+
+	// Readers still ahead of the merge must not block it from returning
+	n := 0
+	m.MergeIndexed(idx, q, func(r Record) bool {
+		n++
+		return n < 3
+	})
+	if n != 3 {
+		t.Errorf("fn called %d times, want 3", n)
+	}
+}
@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Point of a metric series
+type Point struct {
+	X     float64 `json:"x" yaml:"x"`
+	Value float64 `json:"value" yaml:"value"`
+}
+
+// Series of one metric, in record order
+type Series struct {
+	Name   string  `json:"name" yaml:"name"`
+	Points []Point `json:"points" yaml:"points"`
+}
+
+// Print
+func (s Series) String() string {
+	if len(s.Points) == 0 {
+		return fmt.Sprintf("%s: no points", s.Name)
+	}
+	lo, hi := s.Range()
+	return fmt.Sprintf("%s: %d points, last %g, min %g, max %g",
+		s.Name, len(s.Points), s.Points[len(s.Points)-1].Value, lo, hi)
+}
+
+// Range returns the smallest and largest value
+func (s Series) Range() (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, p := range s.Points {
+		lo = math.Min(lo, p.Value)
+		hi = math.Max(hi, p.Value)
+	}
+	return lo, hi
+}
+
+// X axis choices besides a metric name
+const (
+	XIndex = ""
+	XTime  = "time"
+)
+
+// Extractor pulls numeric series out of records
+type Extractor struct {
+	// Fields of structured records to keep; empty keeps every numeric field
+	Keys []string
+
+	// Applied to messages. Each named group is a metric, except that a pair
+	// of groups named "key" and "value" yields a metric named by key.
+	Patterns []*regexp.Regexp
+
+	// Metric used as x axis, XTime for timestamps or XIndex for the
+	// position of the point in its series
+	XKey string
+}
+
+// Pairs like loss=0.21, acc: 0.9 in free text
+var defaultPattern = regexp.MustCompile(
+	`(?P<key>[A-Za-z_][\w./-]*)\s*[=:]\s*(?P<value>[-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?)\b`)
+
+// NewExtractor compiles patterns; without keys and patterns every numeric
+// field and every key=value pair in messages is extracted
+func NewExtractor(keys, patterns []string, xkey string) (*Extractor, error) {
+	e := &Extractor{Keys: keys, XKey: xkey}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		e.Patterns = append(e.Patterns, re)
+	}
+	if len(keys) == 0 && len(patterns) == 0 {
+		e.Patterns = []*regexp.Regexp{defaultPattern}
+	}
+	return e, nil
+}
+
+// Extract returns the metrics of one record
+func (e *Extractor) Extract(rec Record) map[string]float64 {
+	values := make(map[string]float64)
+
+	// Structured fields
+	if len(e.Keys) > 0 {
+		for _, k := range e.Keys {
+			if v, err := strconv.ParseFloat(rec.Fields[k], 64); err == nil {
+				values[k] = v
+			}
+		}
+	} else if len(e.Patterns) == 1 && e.Patterns[0] == defaultPattern {
+		for k, s := range rec.Fields {
+			if v, err := strconv.ParseFloat(s, 64); err == nil {
+				values[k] = v
+			}
+		}
+	}
+
+	// Patterns on the message
+	for _, re := range e.Patterns {
+		names := re.SubexpNames()
+		for _, m := range re.FindAllStringSubmatch(rec.Message, -1) {
+			key, value := "", ""
+			for i, name := range names {
+				switch name {
+				case "":
+				case "key":
+					key = m[i]
+				case "value":
+					value = m[i]
+				default:
+					if v, err := strconv.ParseFloat(m[i], 64); err == nil {
+						values[name] = v
+					}
+				}
+			}
+			if key != "" {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					values[key] = v
+				}
+			}
+		}
+	}
+	return values
+}
+
+// Series extracts every metric of records, sorted by name
+func (e *Extractor) Series(records []Record) []Series {
+	b := e.NewSeriesBuilder()
+	for _, rec := range records {
+		b.Add(rec)
+	}
+	return b.Series()
+}
+
+// SeriesBuilder extracts metrics from records one at a time, for records
+// that are streamed rather than held in memory
+type SeriesBuilder struct {
+	e      *Extractor
+	byName map[string]*Series
+}
+
+func (e *Extractor) NewSeriesBuilder() *SeriesBuilder {
+	return &SeriesBuilder{e: e, byName: make(map[string]*Series)}
+}
+
+// Add extracts the metrics of rec
+func (b *SeriesBuilder) Add(rec Record) {
+	e := b.e
+	values := e.Extract(rec)
+	if len(values) == 0 {
+		return
+	}
+
+	x, hasX := 0.0, e.XKey == XIndex
+	switch e.XKey {
+	case XIndex:
+	case XTime:
+		if !rec.Time.IsZero() {
+			x, hasX = float64(rec.Time.UnixNano())/1e9, true
+		}
+	default:
+		x, hasX = values[e.XKey]
+	}
+	if !hasX {
+		return
+	}
+
+	for name, v := range values {
+		if name == e.XKey {
+			continue
+		}
+		s, ok := b.byName[name]
+		if !ok {
+			s = &Series{Name: name}
+			b.byName[name] = s
+		}
+		px := x
+		if e.XKey == XIndex {
+			px = float64(len(s.Points))
+		}
+		s.Points = append(s.Points, Point{X: px, Value: v})
+	}
+}
+
+// Series returns the metrics so far, sorted by name
+func (b *SeriesBuilder) Series() []Series {
+	series := make([]Series, 0, len(b.byName))
+	for _, s := range b.byName {
+		series = append(series, *s)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Name < series[j].Name })
+	return series
+}
+
+// SetMetrics extracts series from Records
+func (m *Logger) SetMetrics(e *Extractor) {
+	m.Metrics = e.Series(m.Records)
+}
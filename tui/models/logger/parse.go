@@ -0,0 +1,453 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Level of a log record
+type Level int
+
+const (
+	LevelUnknown Level = iota
+	LevelTrace
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+// Levels in increasing severity, without LevelUnknown
+var Levels = []Level{LevelTrace, LevelDebug, LevelInfo, LevelWarn, LevelError, LevelFatal}
+
+func (l Level) String() string {
+	switch l {
+	case LevelTrace:
+		return "TRACE"
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	case LevelFatal:
+		return "FATAL"
+	default:
+		return "-"
+	}
+}
+
+// MarshalText writes the level name, used by JSON and YAML output
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// ParseLevel maps common level spellings to a Level
+func ParseLevel(s string) Level {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "TRACE", "TRC":
+		return LevelTrace
+	case "DEBUG", "DBG":
+		return LevelDebug
+	case "INFO", "INF", "NOTICE":
+		return LevelInfo
+	case "WARN", "WARNING", "WRN":
+		return LevelWarn
+	case "ERROR", "ERR":
+		return LevelError
+	case "FATAL", "CRITICAL", "CRIT", "PANIC":
+		return LevelFatal
+	default:
+		return LevelUnknown
+	}
+}
+
+// Format of a log line
+type Format int
+
+const (
+	FormatText Format = iota
+	FormatStdlib
+	FormatLogfmt
+	FormatJSON
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatStdlib:
+		return "stdlib"
+	case FormatLogfmt:
+		return "logfmt"
+	case FormatJSON:
+		return "json"
+	default:
+		return "text"
+	}
+}
+
+// MarshalText writes the format name, used by JSON and YAML output
+func (f Format) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// Record is one parsed log line
+type Record struct {
+	Time    time.Time         `json:"time" yaml:"time"`
+	Level   Level             `json:"level" yaml:"level"`
+	Source  string            `json:"source,omitempty" yaml:"source,omitempty"`
+	Message string            `json:"msg" yaml:"msg"`
+	Fields  map[string]string `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Format  Format            `json:"format" yaml:"format"`
+
+	// Origin of the line
+	File string `json:"file" yaml:"file"`
+	Line int    `json:"line" yaml:"line"`
+	Raw  string `json:"-" yaml:"-"`
+}
+
+// Print
+func (r Record) String() string {
+	ts := "-"
+	if !r.Time.IsZero() {
+		ts = r.Time.Format("2006-01-02 15:04:05.000")
+	}
+	line := fmt.Sprintf("%s %-5s", ts, r.Level)
+	if r.Source != "" {
+		line += " " + r.Source
+	}
+	if r.Message != "" {
+		line += " " + r.Message
+	}
+	keys := make([]string, 0, len(r.Fields))
+	for k := range r.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		line += fmt.Sprintf(" %s=%s", k, quoteLogfmt(r.Fields[k]))
+	}
+	return line
+}
+
+var (
+	// 2009/01/23 01:23:23.123123 file.go:23: message
+	stdlibRe = regexp.MustCompile(
+		`^(?:(\d{4}/\d{2}/\d{2}) )?(?:(\d{2}:\d{2}:\d{2}(?:[.,]\d{1,9})?) )?(?:(\S+\.go:\d+): ?)?(.*)$`)
+
+	// [INFO] message, INFO: message, INFO message
+	levelPrefixRe = regexp.MustCompile(
+		`(?i)^\[?(trace|debug|info|notice|warn|warning|error|err|fatal|critical|panic)\]?:?\s+`)
+
+	// Keys used by common structured loggers
+	timeKeys    = []string{"time", "ts", "timestamp", "@timestamp", "t"}
+	levelKeys   = []string{"level", "lvl", "severity", "levelname"}
+	messageKeys = []string{"msg", "message", "@message"}
+	sourceKeys  = []string{"source", "caller", "logger", "file", "name"}
+
+	timeLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02 15:04:05.999999999Z07:00",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02 15:04:05,999",
+		"2006/01/02 15:04:05.999999999",
+	}
+)
+
+// ParseLine detects the format of a single line and parses it
+func ParseLine(raw string) Record {
+	line := strings.TrimRight(raw, "\r\n")
+	trimmed := strings.TrimSpace(line)
+
+	if strings.HasPrefix(trimmed, "{") {
+		if r, ok := parseJSON(trimmed); ok {
+			r.Raw = line
+			return r
+		}
+	}
+	if r, ok := parseStdlib(trimmed); ok {
+		r.Raw = line
+		return r
+	}
+	if fields, ok := parseLogfmt(trimmed); ok {
+		r := recordFromFields(fields)
+		r.Format = FormatLogfmt
+		r.Raw = line
+		return r
+	}
+
+	r := Record{Format: FormatText, Raw: line}
+	r.Level, r.Message = splitLevel(trimmed)
+	return r
+}
+
+// Reader parses records one line at a time
+type Reader struct {
+	scanner *bufio.Scanner
+	file    string
+	line    int
+}
+
+func NewReader(r io.Reader, file string) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &Reader{scanner: scanner, file: file}
+}
+
+// Next returns the next non-empty record, false at the end or on error
+func (r *Reader) Next() (Record, bool) {
+	for r.scanner.Scan() {
+		r.line++
+		if strings.TrimSpace(r.scanner.Text()) == "" {
+			continue
+		}
+		rec := ParseLine(r.scanner.Text())
+		rec.File = r.file
+		rec.Line = r.line
+		return rec, true
+	}
+	return Record{}, false
+}
+
+// Err returns the first read error, if any
+func (r *Reader) Err() error {
+	return r.scanner.Err()
+}
+
+// ScanRecords parses every line of r, calling fn until it returns false
+func ScanRecords(r io.Reader, file string, fn func(Record) bool) error {
+	reader := NewReader(r, file)
+	for {
+		rec, ok := reader.Next()
+		if !ok || !fn(rec) {
+			break
+		}
+	}
+	return reader.Err()
+}
+
+// ParseFile reads all records of a log file
+func ParseFile(path string) ([]Record, error) {
+	f, err := OpenLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	err = ScanRecords(f, path, func(r Record) bool {
+		records = append(records, r)
+		return true
+	})
+	return records, err
+}
+
+func parseJSON(line string) (Record, bool) {
+	var obj map[string]interface{}
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&obj); err != nil {
+		return Record{}, false
+	}
+	fields := make(map[string]string, len(obj))
+	for k, v := range obj {
+		switch v := v.(type) {
+		case string:
+			fields[k] = v
+		case nil:
+			fields[k] = ""
+		case json.Number, bool:
+			fields[k] = fmt.Sprint(v)
+		default:
+			b, _ := json.Marshal(v)
+			fields[k] = string(b)
+		}
+	}
+	r := recordFromFields(fields)
+	r.Format = FormatJSON
+	return r, true
+}
+
+func parseStdlib(line string) (Record, bool) {
+	// log.New may put a prefix in front of the timestamp
+	prefix := ""
+	m := stdlibRe.FindStringSubmatch(line)
+	if m[1] == "" && m[2] == "" {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			return Record{}, false
+		}
+		prefix = line[:i]
+		m = stdlibRe.FindStringSubmatch(line[i+1:])
+		if m[1] == "" && m[2] == "" {
+			return Record{}, false
+		}
+	}
+	date, clock, source, msg := m[1], m[2], m[3], m[4]
+
+	r := Record{Format: FormatStdlib, Source: source}
+	r.Time = parseStdlibTime(date, clock)
+
+	// An ISO date in front of the clock is a date, not a prefix
+	if t := ParseTime(prefix + " " + clock); date == "" && prefix != "" && !t.IsZero() {
+		r.Time, prefix = t, ""
+	}
+	r.Level, r.Message = splitLevel(msg)
+	if r.Level == LevelUnknown && prefix != "" {
+		r.Level = ParseLevel(strings.Trim(prefix, "[]:"))
+	}
+
+	// Keep structured key=value pairs written through log.Printf
+	if fields, ok := parseLogfmt(r.Message); ok {
+		r.Fields = fields
+		if msg, ok := takeField(fields, messageKeys); ok {
+			r.Message = msg
+		} else {
+			r.Message = ""
+		}
+		if lvl, ok := takeField(fields, levelKeys); ok && r.Level == LevelUnknown {
+			r.Level = ParseLevel(lvl)
+		}
+	}
+	return r, true
+}
+
+func parseStdlibTime(date, clock string) time.Time {
+	switch {
+	case date != "" && clock != "":
+		t, _ := time.ParseInLocation("2006/01/02 15:04:05.999999999", date+" "+clock, time.Local)
+		return t
+	case date != "":
+		t, _ := time.ParseInLocation("2006/01/02", date, time.Local)
+		return t
+	default:
+		t, _ := time.ParseInLocation("15:04:05.999999999", clock, time.Local)
+		return t
+	}
+}
+
+// parseLogfmt accepts a line only if every token is a key=value pair
+func parseLogfmt(line string) (map[string]string, bool) {
+	fields := make(map[string]string)
+	i := 0
+	for i < len(line) {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		if i == len(line) {
+			break
+		}
+
+		// Key
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '"' {
+			i++
+		}
+		if i == start || i == len(line) || line[i] != '=' {
+			return nil, false
+		}
+		key := line[start:i]
+		i++
+
+		// Value, possibly quoted
+		var value string
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, false
+			}
+			v, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, false
+			}
+			value = v
+			i = end + 1
+		} else {
+			start = i
+			for i < len(line) && line[i] != ' ' {
+				i++
+			}
+			value = line[start:i]
+		}
+		fields[key] = value
+	}
+	return fields, len(fields) > 0
+}
+
+func recordFromFields(fields map[string]string) Record {
+	r := Record{}
+	if ts, ok := takeField(fields, timeKeys); ok {
+		r.Time = ParseTime(ts)
+	}
+	if lvl, ok := takeField(fields, levelKeys); ok {
+		r.Level = ParseLevel(lvl)
+	}
+	if msg, ok := takeField(fields, messageKeys); ok {
+		r.Message = msg
+	}
+	if src, ok := takeField(fields, sourceKeys); ok {
+		r.Source = src
+	}
+	if len(fields) > 0 {
+		r.Fields = fields
+	}
+	return r
+}
+
+// takeField removes and returns the first present key
+func takeField(fields map[string]string, keys []string) (string, bool) {
+	for _, k := range keys {
+		if v, ok := fields[k]; ok {
+			delete(fields, k)
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// ParseTime accepts RFC3339 and common date-time layouts, or unix seconds
+func ParseTime(s string) time.Time {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t
+		}
+	}
+
+	// Unix seconds, possibly fractional
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f > 0 {
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9))
+	}
+	return time.Time{}
+}
+
+func splitLevel(msg string) (Level, string) {
+	m := levelPrefixRe.FindStringSubmatch(msg)
+	if m == nil {
+		return LevelUnknown, msg
+	}
+	return ParseLevel(m[1]), msg[len(m[0]):]
+}
+
+func quoteLogfmt(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	local := func(s string) time.Time {
+		ts, err := time.ParseInLocation("2006-01-02 15:04:05.999", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	utc := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	tests := []struct {
+		name string
+		line string
+		want Record
+	}{
+		{
+			name: "json",
+			line: `{"time": "2024-01-02T03:04:05Z", "level": "warning", "msg": "slow step", "caller": "train.py:10", "loss": 0.25, "ok": true}`,
+			want: Record{
+				Time: utc("2024-01-02T03:04:05Z"), Level: LevelWarn, Source: "train.py:10", Message: "slow step",
+				Fields: map[string]string{"loss": "0.25", "ok": "true"}, Format: FormatJSON,
+			},
+		},
+		{
+			name: "json with nested value",
+			line: `{"msg": "done", "shape": [1, 2]}`,
+			want: Record{Message: "done", Fields: map[string]string{"shape": "[1,2]"}, Format: FormatJSON},
+		},
+		{
+			name: "logfmt",
+			line: `ts=2024-01-02T03:04:05.5Z lvl=err msg="out of memory" gpu=1`,
+			want: Record{
+				Time: utc("2024-01-02T03:04:05.5Z"), Level: LevelError, Message: "out of memory",
+				Fields: map[string]string{"gpu": "1"}, Format: FormatLogfmt,
+			},
+		},
+		{
+			name: "stdlib",
+			line: "2024/01/02 03:04:05.123 main.go:42: ERROR cannot open file",
+			want: Record{
+				Time: local("2024-01-02 03:04:05.123"), Level: LevelError, Source: "main.go:42",
+				Message: "cannot open file", Format: FormatStdlib,
+			},
+		},
+		{
+			name: "stdlib with prefix",
+			line: "[worker] 2024/01/02 03:04:05 started",
+			want: Record{Time: local("2024-01-02 03:04:05"), Message: "started", Format: FormatStdlib},
+		},
+		{
+			name: "stdlib with level prefix",
+			line: "WARN: 2024/01/02 03:04:05 disk almost full",
+			want: Record{Time: local("2024-01-02 03:04:05"), Level: LevelWarn, Message: "disk almost full", Format: FormatStdlib},
+		},
+		{
+			name: "stdlib with fields",
+			line: "2024/01/02 03:04:05 epoch=3 loss=0.5",
+			want: Record{Time: local("2024-01-02 03:04:05"), Fields: map[string]string{"epoch": "3", "loss": "0.5"}, Format: FormatStdlib},
+		},
+		{
+			name: "iso date",
+			line: "2024-01-02 03:04:05,250 INFO loading data",
+			want: Record{Time: local("2024-01-02 03:04:05.250"), Level: LevelInfo, Message: "loading data", Format: FormatStdlib},
+		},
+		{
+			name: "text with level",
+			line: "[error] something broke",
+			want: Record{Level: LevelError, Message: "something broke", Format: FormatText},
+		},
+		{
+			name: "text",
+			line: "  at foo (bar.js:1:2)",
+			want: Record{Message: "at foo (bar.js:1:2)", Format: FormatText},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseLine(tt.line)
+			tt.want.Raw = tt.line
+			if !got.Time.Equal(tt.want.Time) {
+				t.Errorf("time %v, want %v", got.Time, tt.want.Time)
+			}
+			got.Time, tt.want.Time = time.Time{}, time.Time{}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLine(%q)\n got %#v\nwant %#v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]Level{
+		"trace": LevelTrace, "DBG": LevelDebug, "notice": LevelInfo, " Warning ": LevelWarn,
+		"err": LevelError, "CRITICAL": LevelFatal, "panic": LevelFatal, "verbose": LevelUnknown, "": LevelUnknown,
+	}
+	for s, want := range tests {
+		if got := ParseLevel(s); got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestScanRecords(t *testing.T) {
+	input := "msg=one\n\n   \nmsg=two\r\nmsg=three"
+	var got []string
+	err := ScanRecords(strings.NewReader(input), "x.log", func(r Record) bool {
+		if r.File != "x.log" {
+			t.Errorf("file %q, want x.log", r.File)
+		}
+		got = append(got, fmt.Sprintf("%s@%d", r.Message, r.Line))
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Blank lines are skipped but counted
+	if want := "one@1 two@4 three@5"; strings.Join(got, " ") != want {
+		t.Errorf("records %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,349 @@
+package logger
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query selects records with an expression such as
+//
+//	level>=warn and time>="2023-01-02 15:04" and file~worker-[01] or msg~"out of memory"
+//
+// Terms compare a key with a value using =, !=, <, <=, >, >=, ~ (regex
+// match) or !~. Keys are level, time, msg, source, file, format or any
+// structured field. "and" binds tighter than "or". Values compare as levels,
+// timestamps or numbers when both sides parse as such, else as text. A time
+// value like -1h is relative to now.
+type Query struct {
+	Source string
+
+	// Any group matches when all of its terms match
+	groups [][]term
+}
+
+type term struct {
+	key   string
+	op    string
+	value string
+	re    *regexp.Regexp
+	level Level
+	time  time.Time
+}
+
+var operators = []string{"!=", "<=", ">=", "!~", "=", "<", ">", "~"}
+
+// ParseQuery compiles an expression; an empty expression matches everything
+func ParseQuery(s string) (*Query, error) {
+	q := &Query{Source: s}
+	tokens, err := tokenizeQuery(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var group []term
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch strings.ToLower(tok) {
+		case "and", "&&":
+			continue
+		case "or", "||":
+			if len(group) == 0 {
+				return nil, fmt.Errorf("query: %q without a term before it", tok)
+			}
+			q.groups = append(q.groups, group)
+			group = nil
+			continue
+		}
+
+		// key op value, possibly split over several tokens
+		t, n, err := parseTerm(tokens[i:])
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, t)
+		i += n - 1
+	}
+	if len(group) > 0 {
+		q.groups = append(q.groups, group)
+	}
+	return q, nil
+}
+
+// tokenizeQuery splits on spaces, keeping quoted strings together
+func tokenizeQuery(s string) ([]string, error) {
+	var tokens []string
+	var b strings.Builder
+	inToken := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("query: unterminated quote at %d", i)
+			}
+			b.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inToken = true
+		case c == ' ' || c == '\t':
+			if inToken {
+				tokens = append(tokens, b.String())
+				b.Reset()
+				inToken = false
+			}
+		default:
+			b.WriteByte(c)
+			inToken = true
+		}
+	}
+	if inToken {
+		tokens = append(tokens, b.String())
+	}
+	return tokens, nil
+}
+
+// parseTerm reads "key op value" from one to three tokens
+func parseTerm(tokens []string) (term, int, error) {
+	joined := ""
+	for n := 1; n <= 3 && n <= len(tokens); n++ {
+		joined += tokens[n-1]
+
+		// Earliest operator, longest first at the same position
+		at, op := -1, ""
+		for _, o := range operators {
+			if i := strings.Index(joined, o); i > 0 && (at < 0 || i < at) {
+				at, op = i, o
+			}
+		}
+		if at < 0 {
+			continue
+		}
+		value := joined[at+len(op):]
+		if value == "" && n < len(tokens) && n < 3 {
+			continue
+		}
+		t, err := newTerm(strings.ToLower(joined[:at]), op, value)
+		return t, n, err
+	}
+	return term{}, 0, fmt.Errorf("query: expected key, operator and value at %q", tokens[0])
+}
+
+func newTerm(key, op, value string) (term, error) {
+	t := term{key: key, op: op, value: value}
+	switch op {
+	case "~", "!~":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return t, fmt.Errorf("query: %s: %v", key, err)
+		}
+		t.re = re
+	}
+	switch key {
+	case "level", "lvl":
+		t.key = "level"
+		if t.re == nil {
+			t.level = ParseLevel(value)
+			if t.level == LevelUnknown && value != "-" {
+				return t, fmt.Errorf("query: unknown level %q", value)
+			}
+		}
+	case "time", "ts":
+		t.key = "time"
+		if t.re == nil {
+			t.time = parseQueryTime(value)
+			if t.time.IsZero() {
+				return t, fmt.Errorf("query: cannot parse time %q", value)
+			}
+		}
+	case "message":
+		t.key = "msg"
+	}
+	return t, nil
+}
+
+// parseQueryTime accepts ParseTime layouts, dates and durations ago
+func parseQueryTime(s string) time.Time {
+	if strings.HasPrefix(s, "-") {
+		if d, err := time.ParseDuration(s); err == nil {
+			return time.Now().Add(d)
+		}
+	}
+	if t := ParseTime(s); !t.IsZero() {
+		return t
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Empty reports whether the query matches everything
+func (q *Query) Empty() bool {
+	return q == nil || len(q.groups) == 0
+}
+
+// Match reports whether the record satisfies the query
+func (q *Query) Match(r Record) bool {
+	if q.Empty() {
+		return true
+	}
+	for _, group := range q.groups {
+		all := true
+		for _, t := range group {
+			if !t.match(r) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+func (t term) match(r Record) bool {
+	var actual string
+	var present bool
+	switch t.key {
+	case "level":
+		if t.re == nil {
+			return compare(int(r.Level)-int(t.level), t.op)
+		}
+		actual, present = r.Level.String(), true
+	case "time":
+		if t.re == nil {
+			if r.Time.IsZero() {
+				return false
+			}
+			return compare(r.Time.Compare(t.time), t.op)
+		}
+		actual, present = r.Time.Format(time.RFC3339Nano), !r.Time.IsZero()
+	case "msg":
+		actual, present = r.Message, true
+	case "source":
+		actual, present = r.Source, true
+	case "file":
+		actual, present = r.File, true
+		if t.re == nil {
+			// Plain comparisons on the base name, so file=worker-0.log works
+			actual = filepath.Base(r.File)
+		}
+	case "format":
+		actual, present = r.Format.String(), true
+	default:
+		actual, present = r.Fields[t.key]
+	}
+
+	switch t.op {
+	case "~":
+		return present && t.re.MatchString(actual)
+	case "!~":
+		return !present || !t.re.MatchString(actual)
+	case "!=":
+		if !present {
+			return true
+		}
+	default:
+		if !present {
+			return false
+		}
+	}
+
+	// Numbers compare numerically, everything else as text
+	a, errA := strconv.ParseFloat(actual, 64)
+	b, errB := strconv.ParseFloat(t.value, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case a < b:
+			return compare(-1, t.op)
+		case a > b:
+			return compare(1, t.op)
+		default:
+			return compare(0, t.op)
+		}
+	}
+	return compare(strings.Compare(actual, t.value), t.op)
+}
+
+// compare applies op to the sign of a three-way comparison
+func compare(c int, op string) bool {
+	switch op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// Bounds returns the time range and levels every match must fall in, for
+// reading only part of an index. Zero times and nil levels do not restrict.
+func (q *Query) Bounds() (from, to time.Time, levels []Level) {
+	if q.Empty() || len(q.groups) > 1 {
+		return from, to, nil
+	}
+	allowed := append([]Level{LevelUnknown}, Levels...)
+	restricted := false
+	for _, t := range q.groups[0] {
+		if t.re != nil {
+			continue
+		}
+		switch t.key {
+		case "time":
+			if t.op == "=" || t.op == ">" || t.op == ">=" {
+				if from.IsZero() || t.time.After(from) {
+					from = t.time
+				}
+			}
+			if t.op == "=" || t.op == "<" || t.op == "<=" {
+				if to.IsZero() || t.time.Before(to) {
+					to = t.time
+				}
+			}
+		case "level":
+			var kept []Level
+			for _, l := range allowed {
+				if compare(int(l)-int(t.level), t.op) {
+					kept = append(kept, l)
+				}
+			}
+			allowed, restricted = kept, true
+		}
+	}
+	if restricted {
+		levels = allowed
+		if levels == nil {
+			levels = []Level{}
+		}
+	}
+	return from, to, levels
+}
+
+// Filter returns the records matching q
+func (q *Query) Filter(records []Record) []Record {
+	if q.Empty() {
+		return records
+	}
+	var out []Record
+	for _, r := range records {
+		if q.Match(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
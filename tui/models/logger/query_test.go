@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueryMatch(t *testing.T) {
+	at := time.Date(2024, 1, 2, 15, 0, 0, 0, time.Local)
+	records := map[string]Record{
+		"warn": {
+			Time: at, Level: LevelWarn, Message: "disk almost full", File: "/logs/worker-0.log",
+			Fields: map[string]string{"epoch": "10", "host": "gpu1"},
+		},
+		"error": {
+			Time: at.Add(time.Hour), Level: LevelError, Message: "out of memory", Source: "train.py:88",
+			File: "/logs/worker-1.log", Fields: map[string]string{"epoch": "9"},
+		},
+		"untimed": {Message: "  continued", File: "/logs/worker-1.log"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"error", "untimed", "warn"}},
+		{"level>=warn", []string{"error", "warn"}},
+		{"level = error", []string{"error"}},
+		{"level=-", []string{"untimed"}},
+		{"level~^W", []string{"warn"}},
+		{`time>="2024-01-02 15:30"`, []string{"error"}},
+		{`time<="2024-01-02 15:00"`, []string{"warn"}},
+		{`msg~"out of memory"`, []string{"error"}},
+		{"msg!~memory", []string{"untimed", "warn"}},
+		{"file=worker-1.log", []string{"error", "untimed"}},
+		{"file~/logs/worker-[0]", []string{"warn"}},
+		{"source=train.py:88", []string{"error"}},
+		{"format=text", []string{"error", "untimed", "warn"}},
+
+		// Fields compare as numbers, missing fields only match negations
+		{"epoch>9", []string{"warn"}},
+		{"epoch >= 9", []string{"error", "warn"}},
+		{"host!=gpu1", []string{"error", "untimed"}},
+		{"host=gpu1", []string{"warn"}},
+
+		// and binds tighter than or
+		{"level=error or host=gpu1 and epoch=10", []string{"error", "warn"}},
+		{"level=error and epoch=10 or msg~continued", []string{"untimed"}},
+		{"level>=warn && msg~disk || level=-", []string{"untimed", "warn"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, name := range []string{"error", "untimed", "warn"} {
+				if q.Match(records[name]) {
+					got = append(got, name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matched %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []string{
+		"level",
+		"level>=loud",
+		"time>=yesterday",
+		`msg~"unterminated`,
+		"msg~[",
+		"or level=warn",
+	}
+	for _, query := range tests {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%q) succeeded, want an error", query)
+		}
+	}
+}
+
+func TestQueryBounds(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.Local) }
+	tests := []struct {
+		query    string
+		from, to time.Time
+		levels   []Level
+	}{
+		{query: ""},
+		{query: "msg~oom"},
+		{query: "time>=2024-01-02 and time<2024-01-05", from: day(2), to: day(5)},
+		{query: "time>2024-01-02 and time>2024-01-03", from: day(3)},
+		{query: "time=2024-01-02", from: day(2), to: day(2)},
+		{query: "level>=error", levels: []Level{LevelError, LevelFatal}},
+		{query: "level>warn and level<fatal", levels: []Level{LevelError}},
+		{query: "level>fatal", levels: []Level{}},
+		{query: "level~ERR"},
+
+		// Either side of an or may match anything the other excludes
+		{query: "level>=error or time>=2024-01-02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from, to, levels := q.Bounds()
+			if !from.Equal(tt.from) || !to.Equal(tt.to) {
+				t.Errorf("range %v - %v, want %v - %v", from, to, tt.from, tt.to)
+			}
+			if !reflect.DeepEqual(levels, tt.levels) {
+				t.Errorf("levels %v, want %v", levels, tt.levels)
+			}
+		})
+	}
+}
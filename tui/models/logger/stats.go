@@ -0,0 +1,234 @@
+package logger
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bucket of the error rate histogram
+type Bucket struct {
+	Start  time.Time `json:"start" yaml:"start"`
+	End    time.Time `json:"end" yaml:"end"`
+	Total  int       `json:"total" yaml:"total"`
+	Errors int       `json:"errors" yaml:"errors"`
+}
+
+// Rate of error and fatal records in the bucket
+func (b Bucket) Rate() float64 {
+	if b.Total == 0 {
+		return 0
+	}
+	return float64(b.Errors) / float64(b.Total)
+}
+
+// Template is a message with numbers and IDs masked, and how often it occurs
+type Template struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+	Count   int    `json:"count" yaml:"count"`
+	Example string `json:"example" yaml:"example"`
+}
+
+// Stats summarize a set of records
+type Stats struct {
+	Total     int            `json:"total" yaml:"total"`
+	ByLevel   map[Level]int  `json:"by_level" yaml:"by_level"`
+	ByFile    map[string]int `json:"by_file" yaml:"by_file"`
+	First     time.Time      `json:"first" yaml:"first"`
+	Last      time.Time      `json:"last" yaml:"last"`
+	Histogram []Bucket       `json:"histogram" yaml:"histogram"`
+	Templates []Template     `json:"templates" yaml:"templates"`
+}
+
+// Masks applied in order when grouping messages into templates
+var templateMasks = []struct {
+	re   *regexp.Regexp
+	mask string
+}{
+	{regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<hex>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]*\d[0-9a-fA-F]*[a-fA-F][0-9a-fA-F]*\b|\b[0-9a-fA-F]*[a-fA-F][0-9a-fA-F]*\d[0-9a-fA-F]*\b`), "<id>"},
+	{regexp.MustCompile(`[-+]?\d+(?:\.\d+)?(?:[eE][-+]?\d+)?`), "<num>"},
+}
+
+// TemplateOf masks variable parts of a record's message and field values
+func TemplateOf(r Record) string {
+	msg := r.Message
+	for _, m := range templateMasks {
+		msg = m.re.ReplaceAllString(msg, m.mask)
+	}
+	if len(r.Fields) == 0 {
+		return msg
+	}
+	keys := make([]string, 0, len(r.Fields))
+	for k := range r.Fields {
+		keys = append(keys, k+"=*")
+	}
+	sort.Strings(keys)
+	return strings.TrimSpace(msg + " " + strings.Join(keys, " "))
+}
+
+// ComputeStats counts records per level and file, builds an error rate
+// histogram with the given number of buckets and keeps the topN templates
+func ComputeStats(records []Record, buckets, topN int) Stats {
+	s := Stats{
+		ByLevel: make(map[Level]int),
+		ByFile:  make(map[string]int),
+	}
+	counts := make(map[string]*Template)
+	for _, r := range records {
+		s.Total++
+		s.ByLevel[r.Level]++
+		s.ByFile[r.File]++
+		if !r.Time.IsZero() {
+			if s.First.IsZero() || r.Time.Before(s.First) {
+				s.First = r.Time
+			}
+			if r.Time.After(s.Last) {
+				s.Last = r.Time
+			}
+		}
+
+		pattern := TemplateOf(r)
+		t, ok := counts[pattern]
+		if !ok {
+			t = &Template{Pattern: pattern, Example: r.String()}
+			counts[pattern] = t
+		}
+		t.Count++
+	}
+
+	s.Histogram = histogram(records, s.First, s.Last, buckets)
+
+	for _, t := range counts {
+		s.Templates = append(s.Templates, *t)
+	}
+	sort.Slice(s.Templates, func(i, j int) bool {
+		if s.Templates[i].Count != s.Templates[j].Count {
+			return s.Templates[i].Count > s.Templates[j].Count
+		}
+		return s.Templates[i].Pattern < s.Templates[j].Pattern
+	})
+	if topN >= 0 && len(s.Templates) > topN {
+		s.Templates = s.Templates[:topN]
+	}
+	return s
+}
+
+func histogram(records []Record, first, last time.Time, n int) []Bucket {
+	if n <= 0 || first.IsZero() {
+		return nil
+	}
+	span := last.Sub(first)
+	width := span / time.Duration(n)
+	if width <= 0 {
+		width = time.Second
+	}
+
+	hist := make([]Bucket, n)
+	for i := range hist {
+		hist[i].Start = first.Add(time.Duration(i) * width)
+		hist[i].End = hist[i].Start.Add(width)
+	}
+	for _, r := range records {
+		if r.Time.IsZero() {
+			continue
+		}
+		i := int(r.Time.Sub(first) / width)
+		if i >= n {
+			i = n - 1
+		}
+		hist[i].Total++
+		if r.Level >= LevelError {
+			hist[i].Errors++
+		}
+	}
+	return hist
+}
+
+// SetStats summarizes Records, with files relative to Datadir
+func (m *Logger) SetStats(buckets, topN int) {
+	m.Stats = ComputeStats(m.Records, buckets, topN)
+	byFile := make(map[string]int, len(m.Stats.ByFile))
+	for path, n := range m.Stats.ByFile {
+		if rel, err := filepath.Rel(m.Datadir, path); err == nil {
+			path = filepath.ToSlash(rel)
+		}
+		byFile[path] += n
+	}
+	m.Stats.ByFile = byFile
+}
+
+// Files in ByFile, sorted
+func (s Stats) Files() []string {
+	files := make([]string, 0, len(s.ByFile))
+	for f := range s.ByFile {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Print
+func (s Stats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Records: %d\n", s.Total)
+	if !s.First.IsZero() {
+		fmt.Fprintf(&b, "  First: %s\n   Last: %s (%s)\n",
+			s.First.Format(time.RFC3339), s.Last.Format(time.RFC3339), s.Last.Sub(s.First).Round(time.Second))
+	}
+
+	b.WriteString("\nLevels:\n")
+	for _, level := range append([]Level{LevelUnknown}, Levels...) {
+		if n := s.ByLevel[level]; n > 0 {
+			fmt.Fprintf(&b, "  %-5s %8d %6.2f%%\n", level, n, percent(n, s.Total))
+		}
+	}
+
+	b.WriteString("\nFiles:\n")
+	for _, f := range s.Files() {
+		fmt.Fprintf(&b, "  %8d  %s\n", s.ByFile[f], f)
+	}
+
+	if len(s.Histogram) > 0 {
+		b.WriteString("\nError rate:\n")
+		b.WriteString(s.HistogramString(30))
+	}
+
+	if len(s.Templates) > 0 {
+		b.WriteString("\nTop messages:\n")
+		for _, t := range s.Templates {
+			fmt.Fprintf(&b, "  %8d  %s\n", t.Count, t.Pattern)
+		}
+	}
+	return b.String()
+}
+
+// HistogramString draws the error rate per bucket as bars of the given width
+func (s Stats) HistogramString(width int) string {
+	var b strings.Builder
+	layout := "15:04:05"
+	if s.Last.Sub(s.First) > 24*time.Hour {
+		layout = "01-02 15:04"
+	}
+	for _, h := range s.Histogram {
+		bar := int(h.Rate()*float64(width) + 0.5)
+		if h.Errors > 0 && bar == 0 {
+			bar = 1
+		}
+		fmt.Fprintf(&b, "  %s |%s%s| %d/%d %5.1f%%\n", h.Start.Format(layout),
+			strings.Repeat("█", bar), strings.Repeat(" ", width-bar), h.Errors, h.Total, 100*h.Rate())
+	}
+	return b.String()
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(total)
+}
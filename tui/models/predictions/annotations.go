@@ -0,0 +1,306 @@
+package predictions
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Point in the coordinates of the annotations, slide pixels for QuPath
+type Point [2]float64
+
+// Feature of a GeoJSON annotation file
+type Feature struct {
+	Class string `json:"class" yaml:"class"`
+	Name  string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Geometry type, such as Polygon or Point
+	Geometry string  `json:"geometry" yaml:"geometry"`
+	Area     float64 `json:"area" yaml:"area"`
+
+	// Class color as RGB, when the file gives one
+	Color    [3]uint8 `json:"-" yaml:"-"`
+	HasColor bool     `json:"-" yaml:"-"`
+
+	// Polygon rings and lines as point lists, single points as one point
+	Paths [][]Point `json:"-" yaml:"-"`
+}
+
+// ClassSummary counts the features of a class
+type ClassSummary struct {
+	Class string  `json:"class" yaml:"class"`
+	Count int     `json:"count" yaml:"count"`
+	Area  float64 `json:"area" yaml:"area"`
+}
+
+// Unclassified is the class of features without one
+const Unclassified = "Unclassified"
+
+// ReadAnnotations parses a GeoJSON FeatureCollection, a single Feature or a
+// list of features as exported by QuPath
+func ReadAnnotations(path string) ([]Feature, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw []geoFeature
+	switch t := strings.TrimSpace(string(data)); {
+	case strings.HasPrefix(t, "["):
+		err = json.Unmarshal(data, &raw)
+	default:
+		var doc struct {
+			geoFeature
+			Features []geoFeature `json:"features"`
+		}
+		err = json.Unmarshal(data, &doc)
+		raw = doc.Features
+		if doc.Type == "Feature" {
+			raw = []geoFeature{doc.geoFeature}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	features := make([]Feature, 0, len(raw))
+	for _, g := range raw {
+		f, err := g.feature()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		features = append(features, f)
+	}
+	return features, nil
+}
+
+type geoGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometries  []geoGeometry   `json:"geometries"`
+}
+
+type geoFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   *geoGeometry           `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Property keys holding the class of a feature, in order
+var classKeys = []string{"classification", "class", "classname", "label", "category"}
+
+func (g geoFeature) feature() (Feature, error) {
+	f := Feature{Class: Unclassified}
+	if name, ok := g.Properties["name"].(string); ok {
+		f.Name = name
+	}
+	for _, k := range classKeys {
+		switch v := g.Properties[k].(type) {
+		case string:
+			f.Class = v
+		case map[string]interface{}:
+			// QuPath: {"name": "Tumor", "color": [200, 0, 0]}
+			if name, ok := v["name"].(string); ok {
+				f.Class = name
+			}
+			if c, ok := v["color"].([]interface{}); ok && len(c) == 3 {
+				for i := range c {
+					n, _ := c[i].(float64)
+					f.Color[i] = uint8(n)
+				}
+				f.HasColor = true
+			}
+		default:
+			continue
+		}
+		break
+	}
+	if g.Geometry == nil {
+		return f, nil
+	}
+	f.Geometry = g.Geometry.Type
+	area, err := g.Geometry.paths(&f.Paths)
+	f.Area = area
+	return f, err
+}
+
+// paths appends the rings, lines and points of the geometry, returning its
+// area
+func (g geoGeometry) paths(out *[][]Point) (float64, error) {
+	var err error
+	switch g.Type {
+	case "Point":
+		var p Point
+		if err = json.Unmarshal(g.Coordinates, &p); err == nil {
+			*out = append(*out, []Point{p})
+		}
+	case "MultiPoint", "LineString":
+		var ps []Point
+		if err = json.Unmarshal(g.Coordinates, &ps); err == nil {
+			if g.Type == "MultiPoint" {
+				for _, p := range ps {
+					*out = append(*out, []Point{p})
+				}
+			} else {
+				*out = append(*out, ps)
+			}
+		}
+	case "MultiLineString":
+		var ls [][]Point
+		if err = json.Unmarshal(g.Coordinates, &ls); err == nil {
+			*out = append(*out, ls...)
+		}
+	case "Polygon":
+		var rings [][]Point
+		if err = json.Unmarshal(g.Coordinates, &rings); err == nil {
+			*out = append(*out, rings...)
+			return polygonArea(rings), nil
+		}
+	case "MultiPolygon":
+		var polygons [][][]Point
+		if err = json.Unmarshal(g.Coordinates, &polygons); err == nil {
+			area := 0.0
+			for _, rings := range polygons {
+				*out = append(*out, rings...)
+				area += polygonArea(rings)
+			}
+			return area, nil
+		}
+	case "GeometryCollection":
+		area := 0.0
+		for _, child := range g.Geometries {
+			a, err := child.paths(out)
+			if err != nil {
+				return area, err
+			}
+			area += a
+		}
+		return area, nil
+	default:
+		return 0, fmt.Errorf("unknown geometry %q", g.Type)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", g.Type, err)
+	}
+	return 0, nil
+}
+
+// polygonArea is the area of the outer ring less its holes
+func polygonArea(rings [][]Point) float64 {
+	area := 0.0
+	for i, ring := range rings {
+		a := 0.0
+		for j := range ring {
+			p, q := ring[j], ring[(j+1)%len(ring)]
+			a += p[0]*q[1] - q[0]*p[1]
+		}
+		a = math.Abs(a) / 2
+		if i == 0 {
+			area += a
+		} else {
+			area -= a
+		}
+	}
+	return area
+}
+
+// SummarizeFeatures counts features and sums their areas per class, largest
+// area first
+func SummarizeFeatures(features []Feature) []ClassSummary {
+	byClass := make(map[string]*ClassSummary)
+	var out []ClassSummary
+	for _, f := range features {
+		c, ok := byClass[f.Class]
+		if !ok {
+			c = &ClassSummary{Class: f.Class}
+			byClass[f.Class] = c
+		}
+		c.Count++
+		c.Area += f.Area
+	}
+	for _, c := range byClass {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Area != out[j].Area {
+			return out[i].Area > out[j].Area
+		}
+		return out[i].Class < out[j].Class
+	})
+	return out
+}
+
+// FeatureBounds returns the largest coordinates of the features
+func FeatureBounds(features []Feature) (maxX, maxY float64) {
+	for _, f := range features {
+		for _, path := range f.Paths {
+			for _, p := range path {
+				maxX, maxY = math.Max(maxX, p[0]), math.Max(maxY, p[1])
+			}
+		}
+	}
+	return maxX, maxY
+}
+
+// Annotations reads the features of every annotation artifact of the slide
+func (s Slide) Annotations() ([]Feature, error) {
+	var features []Feature
+	for _, a := range s.ByKind(KindAnnotations) {
+		f, err := ReadAnnotations(filepath.Join(s.Path, filepath.FromSlash(a.Name)))
+		if err != nil {
+			return features, err
+		}
+		features = append(features, f...)
+	}
+	return features, nil
+}
+
+// Keys looked up in JSON summaries for the full slide size, in order
+var (
+	widthKeys      = []string{"width", "slide_width", "level0_width"}
+	heightKeys     = []string{"height", "slide_height", "level0_height"}
+	dimensionsKeys = []string{"dimensions", "size", "level0_dimensions"}
+)
+
+// Dimensions of the full resolution slide from its summary, when recorded
+func (s Slide) Dimensions() (float64, float64, bool) {
+	for _, a := range s.ByKind(KindSummary) {
+		data, err := os.ReadFile(filepath.Join(s.Path, filepath.FromSlash(a.Name)))
+		if err != nil {
+			continue
+		}
+		var doc map[string]interface{}
+		if json.Unmarshal(data, &doc) != nil {
+			continue
+		}
+		for _, k := range dimensionsKeys {
+			if v, ok := doc[k].([]interface{}); ok && len(v) == 2 {
+				w, _ := v[0].(float64)
+				h, _ := v[1].(float64)
+				if w > 0 && h > 0 {
+					return w, h, true
+				}
+			}
+		}
+		var w, h float64
+		for _, k := range widthKeys {
+			if v, ok := doc[k].(float64); ok {
+				w = v
+				break
+			}
+		}
+		for _, k := range heightKeys {
+			if v, ok := doc[k].(float64); ok {
+				h = v
+				break
+			}
+		}
+		if w > 0 && h > 0 {
+			return w, h, true
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,258 @@
+package predictions
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SlideChange compares one slide, matched by group and name, across two runs
+type SlideChange struct {
+	Group string `json:"group" yaml:"group"`
+	Slide string `json:"slide" yaml:"slide"`
+	InA   bool   `json:"in_a" yaml:"in_a"`
+	InB   bool   `json:"in_b" yaml:"in_b"`
+
+	// Predicted labels and scores, empty without a summary
+	LabelA    string  `json:"label_a" yaml:"label_a"`
+	LabelB    string  `json:"label_b" yaml:"label_b"`
+	ScoreA    float64 `json:"score_a,omitempty" yaml:"score_a,omitempty"`
+	ScoreB    float64 `json:"score_b,omitempty" yaml:"score_b,omitempty"`
+	HasScoreA bool    `json:"has_score_a" yaml:"has_score_a"`
+	HasScoreB bool    `json:"has_score_b" yaml:"has_score_b"`
+
+	// Agreement of the two runs' masks
+	Dice    float64 `json:"dice,omitempty" yaml:"dice,omitempty"`
+	IoU     float64 `json:"iou,omitempty" yaml:"iou,omitempty"`
+	HasMask bool    `json:"has_mask" yaml:"has_mask"`
+	MaskErr string  `json:"mask_error,omitempty" yaml:"mask_error,omitempty"`
+
+	// Dice below the comparison's threshold
+	LowAgreement bool `json:"low_agreement" yaml:"low_agreement"`
+}
+
+// Missing reports whether the slide is in only one run
+func (c SlideChange) Missing() bool {
+	return !c.InA || !c.InB
+}
+
+// LabelChanged reports whether both runs predicted a label and they differ
+func (c SlideChange) LabelChanged() bool {
+	return c.LabelA != "" && c.LabelB != "" && c.LabelA != c.LabelB
+}
+
+// ScoreDelta is the score of B minus A, NaN unless both have one
+func (c SlideChange) ScoreDelta() float64 {
+	if !c.HasScoreA || !c.HasScoreB {
+		return math.NaN()
+	}
+	return c.ScoreB - c.ScoreA
+}
+
+// Flagged reports whether the slide needs a look before signing off
+func (c SlideChange) Flagged() bool {
+	return c.Missing() || c.LabelChanged() || c.LowAgreement || c.MaskErr != ""
+}
+
+// Dice below which masks of two runs are flagged by default
+const DefaultAgreement = 0.9
+
+// Comparison of two prediction runs
+type Comparison struct {
+	A string `json:"a" yaml:"a"`
+	B string `json:"b" yaml:"b"`
+
+	// Masks with a Dice below this are flagged
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+
+	// Every slide of either run, by group then name
+	Slides []SlideChange `json:"slides" yaml:"slides"`
+
+	OnlyA        int `json:"only_a" yaml:"only_a"`
+	OnlyB        int `json:"only_b" yaml:"only_b"`
+	Changed      int `json:"changed" yaml:"changed"`
+	LowAgreement int `json:"low_agreement" yaml:"low_agreement"`
+
+	// Mean and largest absolute score difference over slides scored twice
+	MeanAbsDelta float64 `json:"mean_abs_delta" yaml:"mean_abs_delta"`
+	MaxAbsDelta  float64 `json:"max_abs_delta" yaml:"max_abs_delta"`
+	Scored       int     `json:"scored" yaml:"scored"`
+}
+
+// Compare matches the groups and slides of two runs by name, reading their
+// labels and comparing their first masks
+func Compare(a, b *Predictions, threshold float64) Comparison {
+	c := Comparison{A: a.Datadir, B: b.Datadir, Threshold: threshold}
+
+	type pair struct {
+		a, b        Slide
+		inA, inB    bool
+		group, name string
+	}
+	pairs := make(map[string]*pair)
+	add := func(m *Predictions, inA bool) {
+		for name, g := range m.Groups {
+			for _, s := range g.Slides {
+				id := name + "/" + s.Name
+				p, ok := pairs[id]
+				if !ok {
+					p = &pair{group: name, name: s.Name}
+					pairs[id] = p
+				}
+				if inA {
+					p.a, p.inA = s, true
+				} else {
+					p.b, p.inB = s, true
+				}
+			}
+		}
+	}
+	add(a, true)
+	add(b, false)
+
+	ids := make([]string, 0, len(pairs))
+	for id := range pairs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		p := pairs[id]
+		s := SlideChange{Group: p.group, Slide: p.name, InA: p.inA, InB: p.inB}
+		if l, ok := p.a.Label(); p.inA && ok {
+			s.LabelA, s.ScoreA, s.HasScoreA = l.Class, l.Score, l.HasScore
+		}
+		if l, ok := p.b.Label(); p.inB && ok {
+			s.LabelB, s.ScoreB, s.HasScoreB = l.Class, l.Score, l.HasScore
+		}
+		if p.inA && p.inB {
+			s.compareMasks(p.a, p.b)
+			s.LowAgreement = s.HasMask && s.Dice < threshold
+		}
+
+		switch {
+		case !s.InB:
+			c.OnlyA++
+		case !s.InA:
+			c.OnlyB++
+		}
+		if s.LabelChanged() {
+			c.Changed++
+		}
+		if s.LowAgreement {
+			c.LowAgreement++
+		}
+		if d := s.ScoreDelta(); !math.IsNaN(d) {
+			c.MeanAbsDelta += math.Abs(d)
+			c.MaxAbsDelta = math.Max(c.MaxAbsDelta, math.Abs(d))
+			c.Scored++
+		}
+		c.Slides = append(c.Slides, s)
+	}
+	if c.Scored > 0 {
+		c.MeanAbsDelta /= float64(c.Scored)
+	}
+	return c
+}
+
+// compareMasks fills Dice and IoU when both slides have a readable mask
+func (c *SlideChange) compareMasks(a, b Slide) {
+	ma, mb := a.ByKind(KindMask), b.ByKind(KindMask)
+	if len(ma) == 0 || len(mb) == 0 {
+		return
+	}
+	x, err := readMask(filepath.Join(a.Path, filepath.FromSlash(ma[0].Name)))
+	if err != nil {
+		c.MaskErr = err.Error()
+		return
+	}
+	y, err := readMask(filepath.Join(b.Path, filepath.FromSlash(mb[0].Name)))
+	if err != nil {
+		c.MaskErr = err.Error()
+		return
+	}
+	c.Dice, c.IoU, err = Overlap(x, y)
+	if err != nil {
+		c.MaskErr = err.Error()
+		return
+	}
+	c.HasMask = true
+}
+
+// Flagged returns the slides that are missing, changed label or disagree
+func (c Comparison) Flagged() []SlideChange {
+	var flagged []SlideChange
+	for _, s := range c.Slides {
+		if s.Flagged() {
+			flagged = append(flagged, s)
+		}
+	}
+	return flagged
+}
+
+// Print
+func (s SlideChange) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s/%s", s.Group, s.Slide)
+	switch {
+	case !s.InB:
+		b.WriteString("  only in A")
+		return b.String()
+	case !s.InA:
+		b.WriteString("  only in B")
+		return b.String()
+	}
+	label := func(l string) string {
+		if l == "" {
+			return "-"
+		}
+		return l
+	}
+	if s.LabelChanged() {
+		fmt.Fprintf(&b, "  label %s -> %s", s.LabelA, s.LabelB)
+	} else {
+		fmt.Fprintf(&b, "  label %s", label(s.LabelB))
+	}
+	if d := s.ScoreDelta(); !math.IsNaN(d) {
+		fmt.Fprintf(&b, "  score %.4f -> %.4f (%+.4f)", s.ScoreA, s.ScoreB, d)
+	}
+	if s.HasMask {
+		fmt.Fprintf(&b, "  dice %.4f", s.Dice)
+		if s.LowAgreement {
+			b.WriteString(" low")
+		}
+	}
+	if s.MaskErr != "" {
+		fmt.Fprintf(&b, "  mask: %s", s.MaskErr)
+	}
+	return b.String()
+}
+
+// Summary of the comparison, without the slides
+func (c Comparison) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "A: %s\nB: %s\n\n", c.A, c.B)
+	fmt.Fprintf(&b, "%d slides, %d only in A, %d only in B, %d changed label, %d masks below dice %.2f\n",
+		len(c.Slides), c.OnlyA, c.OnlyB, c.Changed, c.LowAgreement, c.Threshold)
+	if c.Scored > 0 {
+		fmt.Fprintf(&b, "Score difference over %d slides: mean %.4f, max %.4f\n", c.Scored, c.MeanAbsDelta, c.MaxAbsDelta)
+	}
+	return b.String()
+}
+
+// Print with the flagged slides
+func (c Comparison) String() string {
+	var b strings.Builder
+	b.WriteString(c.Summary())
+	flagged := c.Flagged()
+	if len(flagged) == 0 {
+		return b.String()
+	}
+	b.WriteString("\n")
+	for _, s := range flagged {
+		fmt.Fprintln(&b, s)
+	}
+	return b.String()
+}
@@ -0,0 +1,676 @@
+package predictions
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Label of a slide, predicted or true
+type Label struct {
+	Class string `json:"class" yaml:"class"`
+
+	// Probability of Class, when the summary gives a single score
+	Score    float64 `json:"score,omitempty" yaml:"score,omitempty"`
+	HasScore bool    `json:"has_score" yaml:"has_score"`
+
+	// Probability per class, when the summary lists them
+	Scores map[string]float64 `json:"scores,omitempty" yaml:"scores,omitempty"`
+}
+
+// Keys looked up in JSON summaries, in order
+var (
+	labelKeys  = []string{"label", "prediction", "predicted", "predicted_class", "pred", "class", "target", "ground_truth", "gt"}
+	truthKeys  = []string{"ground_truth", "gt", "target", "label", "class", "prediction", "predicted", "predicted_class", "pred"}
+	scoreKeys  = []string{"score", "probability", "prob", "confidence"}
+	scoresKeys = []string{"scores", "probabilities", "probs"}
+)
+
+// ReadSummary reads the label and scores of a JSON summary
+func ReadSummary(path string) (Label, error) {
+	return readSummary(path, labelKeys)
+}
+
+// readSummary reads a JSON summary, taking the class from the first of keys
+// it has
+func readSummary(path string, keys []string) (Label, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Label{}, err
+	}
+	defer f.Close()
+
+	var doc map[string]interface{}
+	dec := json.NewDecoder(f)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return Label{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var l Label
+	for _, k := range keys {
+		if v, ok := doc[k]; ok && v != nil {
+			l.Class = fmt.Sprint(v)
+			break
+		}
+	}
+	for _, k := range scoreKeys {
+		if v, ok := doc[k].(json.Number); ok {
+			if f, err := v.Float64(); err == nil {
+				l.Score, l.HasScore = f, true
+				break
+			}
+		}
+	}
+	for _, k := range scoresKeys {
+		if v, ok := doc[k].(map[string]interface{}); ok {
+			l.Scores = make(map[string]float64)
+			for class, s := range v {
+				if n, ok := s.(json.Number); ok {
+					if f, err := n.Float64(); err == nil {
+						l.Scores[class] = f
+					}
+				}
+			}
+			break
+		}
+	}
+	return l, nil
+}
+
+// Label of a slide from the first summary artifact that has one
+func (s Slide) Label() (Label, bool) {
+	for _, a := range s.ByKind(KindSummary) {
+		l, err := ReadSummary(filepath.Join(s.Path, filepath.FromSlash(a.Name)))
+		if err == nil && l.Class != "" {
+			return l, true
+		}
+	}
+	return Label{}, false
+}
+
+// GroundTruth holds the true class of slides and where their true masks are
+type GroundTruth struct {
+	// Path of the labels CSV or ground-truth directory
+	Path string
+
+	// By "group/slide" or by slide name alone
+	Labels map[string]string
+
+	// Ground-truth directory laid out like the predictions, empty for a CSV
+	Dir    string
+	Layout *Layout
+}
+
+// LoadGroundTruth reads a labels CSV, or a directory laid out like the
+// predictions (or with slide directories only) with JSON summaries and masks
+func LoadGroundTruth(path string) (*GroundTruth, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		labels, err := LoadLabelsCSV(path)
+		if err != nil {
+			return nil, err
+		}
+		return &GroundTruth{Path: path, Labels: labels}, nil
+	}
+
+	layout, err := FindLayout(path)
+	if err != nil {
+		return nil, err
+	}
+	gt := &GroundTruth{Path: path, Dir: path, Labels: make(map[string]string), Layout: layout}
+	depth := len(layout.Levels)
+	err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(path, filepath.Dir(p))
+		if rel == "." {
+			return nil
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+
+		// Split into the slide directory and the artifact below it
+		slide, artifact := parts[0], d.Name()
+		if len(parts) >= depth {
+			slide = parts[depth-1]
+			artifact = strings.Join(append(parts[depth:], d.Name()), "/")
+		}
+		if layout.Classify(artifact) != KindSummary {
+			return nil
+		}
+		// A true summary may carry a prediction too, so truth keys come first
+		l, err := readSummary(p, truthKeys)
+		if err != nil || l.Class == "" {
+			return nil
+		}
+		if len(parts) >= depth && depth > 1 {
+			gt.Labels[strings.Join(parts[:depth], "/")] = l.Class
+		}
+		if _, ok := gt.Labels[slide]; !ok {
+			gt.Labels[slide] = l.Class
+		}
+		return nil
+	})
+	return gt, err
+}
+
+// Columns recognized in label CSVs
+var (
+	slideColumns = []string{"slide", "slide_id", "slidename", "name", "id", "image"}
+	groupColumns = []string{"group", "split", "cohort"}
+	classColumns = []string{"label", "class", "target", "ground_truth", "gt", "diagnosis"}
+)
+
+// LoadLabelsCSV reads slide labels from a CSV with a header naming a slide
+// and a label column, and optionally a group column
+func LoadLabelsCSV(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	column := func(names []string) int {
+		for _, n := range names {
+			for i, h := range header {
+				if strings.EqualFold(strings.TrimSpace(h), n) {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+	slideCol, groupCol, classCol := column(slideColumns), column(groupColumns), column(classColumns)
+	if slideCol < 0 || classCol < 0 {
+		return nil, fmt.Errorf("%s: need a slide and a label column, got %v", path, header)
+	}
+
+	labels := make(map[string]string)
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if slideCol >= len(row) || classCol >= len(row) {
+			continue
+		}
+		slide, class := strings.TrimSpace(row[slideCol]), strings.TrimSpace(row[classCol])
+		if groupCol >= 0 && groupCol < len(row) {
+			labels[strings.TrimSpace(row[groupCol])+"/"+slide] = class
+		}
+		if _, ok := labels[slide]; !ok {
+			labels[slide] = class
+		}
+	}
+	return labels, nil
+}
+
+// Label of a slide, by group and name first
+func (gt *GroundTruth) Label(group, slide string) (string, bool) {
+	if l, ok := gt.Labels[group+"/"+slide]; ok {
+		return l, true
+	}
+	l, ok := gt.Labels[slide]
+	return l, ok
+}
+
+// Mask of a slide in the ground-truth directory, if any
+func (gt *GroundTruth) Mask(group, slide string) (string, bool) {
+	if gt.Dir == "" {
+		return "", false
+	}
+	for _, dir := range []string{filepath.Join(gt.Dir, filepath.FromSlash(group), slide), filepath.Join(gt.Dir, slide)} {
+		s, err := gt.Layout.LoadSlide(dir, group, slide)
+		if err != nil {
+			continue
+		}
+		if masks := s.ByKind(KindMask); len(masks) > 0 {
+			return filepath.Join(dir, filepath.FromSlash(masks[0].Name)), true
+		}
+	}
+	return "", false
+}
+
+// SlideResult compares one slide with its ground truth
+type SlideResult struct {
+	Group     string `json:"group" yaml:"group"`
+	Slide     string `json:"slide" yaml:"slide"`
+	True      string `json:"true" yaml:"true"`
+	Predicted string `json:"predicted" yaml:"predicted"`
+
+	// Probability of the positive class
+	Score    float64 `json:"score,omitempty" yaml:"score,omitempty"`
+	HasScore bool    `json:"has_score" yaml:"has_score"`
+
+	// Overlap of predicted and true masks
+	Dice    float64 `json:"dice,omitempty" yaml:"dice,omitempty"`
+	IoU     float64 `json:"iou,omitempty" yaml:"iou,omitempty"`
+	HasMask bool    `json:"has_mask" yaml:"has_mask"`
+	MaskErr string  `json:"mask_error,omitempty" yaml:"mask_error,omitempty"`
+}
+
+// Correct reports whether the predicted class is the true one
+func (r SlideResult) Correct() bool {
+	return r.True == r.Predicted
+}
+
+// ConfusionMatrix counts slides per true (row) and predicted (column) class
+type ConfusionMatrix struct {
+	Classes []string `json:"classes" yaml:"classes"`
+	Counts  [][]int  `json:"counts" yaml:"counts"`
+}
+
+// NewConfusionMatrix of results over the classes found in them
+func NewConfusionMatrix(results []SlideResult) ConfusionMatrix {
+	seen := make(map[string]bool)
+	for _, r := range results {
+		seen[r.True], seen[r.Predicted] = true, true
+	}
+	var classes []string
+	for c := range seen {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+
+	cm := ConfusionMatrix{Classes: classes, Counts: make([][]int, len(classes))}
+	for i := range cm.Counts {
+		cm.Counts[i] = make([]int, len(classes))
+	}
+	for _, r := range results {
+		cm.Counts[cm.Index(r.True)][cm.Index(r.Predicted)]++
+	}
+	return cm
+}
+
+// Index of a class, -1 if unknown
+func (cm ConfusionMatrix) Index(class string) int {
+	for i, c := range cm.Classes {
+		if c == class {
+			return i
+		}
+	}
+	return -1
+}
+
+// Total number of slides
+func (cm ConfusionMatrix) Total() int {
+	n := 0
+	for _, row := range cm.Counts {
+		for _, c := range row {
+			n += c
+		}
+	}
+	return n
+}
+
+// ClassMetrics are one-vs-rest scores of a class
+type ClassMetrics struct {
+	Class     string  `json:"class" yaml:"class"`
+	Precision float64 `json:"precision" yaml:"precision"`
+	Recall    float64 `json:"recall" yaml:"recall"`
+	F1        float64 `json:"f1" yaml:"f1"`
+	Support   int     `json:"support" yaml:"support"`
+}
+
+// PerClass returns precision, recall and F1 of every class
+func (cm ConfusionMatrix) PerClass() []ClassMetrics {
+	out := make([]ClassMetrics, len(cm.Classes))
+	for i, c := range cm.Classes {
+		tp, predicted, actual := cm.Counts[i][i], 0, 0
+		for j := range cm.Classes {
+			predicted += cm.Counts[j][i]
+			actual += cm.Counts[i][j]
+		}
+		m := ClassMetrics{Class: c, Support: actual}
+		if predicted > 0 {
+			m.Precision = float64(tp) / float64(predicted)
+		}
+		if actual > 0 {
+			m.Recall = float64(tp) / float64(actual)
+		}
+		if m.Precision+m.Recall > 0 {
+			m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// Accuracy is the fraction of slides on the diagonal
+func (cm ConfusionMatrix) Accuracy() float64 {
+	total, correct := cm.Total(), 0
+	for i := range cm.Classes {
+		correct += cm.Counts[i][i]
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(correct) / float64(total)
+}
+
+// CurvePoint of a ROC (x false positive rate, y true positive rate) or PR
+// (x recall, y precision) curve
+type CurvePoint struct {
+	X         float64 `json:"x" yaml:"x"`
+	Y         float64 `json:"y" yaml:"y"`
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+}
+
+// Curves computes ROC and PR curves of scores against positive labels, and
+// the area under the ROC curve. ok is false without both classes present.
+func Curves(scores []float64, positive []bool) (roc, pr []CurvePoint, auc float64, ok bool) {
+	idx := make([]int, len(scores))
+	p, n := 0, 0
+	for i := range scores {
+		idx[i] = i
+		if positive[i] {
+			p++
+		} else {
+			n++
+		}
+	}
+	if p == 0 || n == 0 {
+		return nil, nil, 0, false
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return scores[idx[a]] > scores[idx[b]] })
+
+	roc = []CurvePoint{{X: 0, Y: 0, Threshold: scores[idx[0]] + 1}}
+	pr = []CurvePoint{{X: 0, Y: 1, Threshold: scores[idx[0]] + 1}}
+	tp, fp := 0, 0
+	for k, i := range idx {
+		if positive[i] {
+			tp++
+		} else {
+			fp++
+		}
+		// One point per distinct threshold
+		if k+1 < len(idx) && scores[idx[k+1]] == scores[i] {
+			continue
+		}
+		fpr, tpr := float64(fp)/float64(n), float64(tp)/float64(p)
+		prev := roc[len(roc)-1]
+		auc += (fpr - prev.X) * (tpr + prev.Y) / 2
+		roc = append(roc, CurvePoint{X: fpr, Y: tpr, Threshold: scores[i]})
+		pr = append(pr, CurvePoint{X: tpr, Y: float64(tp) / float64(tp+fp), Threshold: scores[i]})
+	}
+	return roc, pr, auc, true
+}
+
+// Evaluation of a set of slides
+type Evaluation struct {
+	Name     string          `json:"name" yaml:"name"`
+	Slides   []SlideResult   `json:"slides" yaml:"slides"`
+	Matrix   ConfusionMatrix `json:"confusion_matrix" yaml:"confusion_matrix"`
+	Accuracy float64         `json:"accuracy" yaml:"accuracy"`
+	Classes  []ClassMetrics  `json:"classes" yaml:"classes"`
+
+	// Scores of the positive class against the truth
+	Positive string       `json:"positive,omitempty" yaml:"positive,omitempty"`
+	AUC      float64      `json:"auc,omitempty" yaml:"auc,omitempty"`
+	HasAUC   bool         `json:"has_auc" yaml:"has_auc"`
+	ROC      []CurvePoint `json:"roc,omitempty" yaml:"roc,omitempty"`
+	PR       []CurvePoint `json:"pr,omitempty" yaml:"pr,omitempty"`
+
+	// Mean overlap over slides with both masks
+	Dice       float64 `json:"dice,omitempty" yaml:"dice,omitempty"`
+	IoU        float64 `json:"iou,omitempty" yaml:"iou,omitempty"`
+	MaskSlides int     `json:"mask_slides" yaml:"mask_slides"`
+
+	// Slides without a true or a predicted label
+	Unlabeled   []string `json:"unlabeled,omitempty" yaml:"unlabeled,omitempty"`
+	Unpredicted []string `json:"unpredicted,omitempty" yaml:"unpredicted,omitempty"`
+}
+
+// Report of a whole run and of each group
+type Report struct {
+	Truth   string       `json:"truth" yaml:"truth"`
+	Overall Evaluation   `json:"overall" yaml:"overall"`
+	Groups  []Evaluation `json:"groups" yaml:"groups"`
+}
+
+// Evaluate compares every slide of m with the ground truth. Curves use the
+// probability of positive, from the per-class scores when a summary lists
+// them, else from the single score of the predicted class: 1-score when
+// another class is predicted of two, and left out of the curves with more.
+// An empty positive picks the last class in sorted order, such as "1" of
+// "0" and "1".
+func Evaluate(m *Predictions, gt *GroundTruth, positive string) Report {
+	report := Report{Truth: gt.Path}
+	var all []SlideResult
+	var classScores []map[string]float64
+	var unlabeled, unpredicted []string
+	for _, name := range m.GroupNames() {
+		for _, s := range m.Groups[name].Slides {
+			id := name + "/" + s.Name
+			truth, ok := gt.Label(name, s.Name)
+			if !ok {
+				unlabeled = append(unlabeled, id)
+				continue
+			}
+			pred, ok := s.Label()
+			if !ok {
+				unpredicted = append(unpredicted, id)
+				continue
+			}
+			r := SlideResult{Group: name, Slide: s.Name, True: truth, Predicted: pred.Class}
+			r.Score, r.HasScore = pred.Score, pred.HasScore
+			r.compareMasks(s, gt)
+			all = append(all, r)
+			classScores = append(classScores, pred.Scores)
+		}
+	}
+
+	// Positive class over the whole run, so groups agree
+	classes := NewConfusionMatrix(all).Classes
+	if positive == "" && len(classes) > 0 {
+		positive = classes[len(classes)-1]
+	}
+
+	// Prefer the positive class's own probability when summaries list them
+	byGroup := make(map[string][]SlideResult)
+	for i := range all {
+		if p, ok := classScores[i][positive]; ok {
+			all[i].Score, all[i].HasScore = p, true
+		} else if all[i].HasScore && all[i].Predicted != positive {
+			if len(classes) <= 2 {
+				all[i].Score = 1 - all[i].Score
+			} else {
+				all[i].HasScore = false
+			}
+		}
+		byGroup[all[i].Group] = append(byGroup[all[i].Group], all[i])
+	}
+
+	report.Overall = evaluate("all", all, positive)
+	report.Overall.Unlabeled, report.Overall.Unpredicted = unlabeled, unpredicted
+	for _, name := range m.GroupNames() {
+		if results, ok := byGroup[name]; ok {
+			report.Groups = append(report.Groups, evaluate(name, results, positive))
+		}
+	}
+	return report
+}
+
+func evaluate(name string, results []SlideResult, positive string) Evaluation {
+	e := Evaluation{Name: name, Slides: results, Positive: positive}
+	e.Matrix = NewConfusionMatrix(results)
+	e.Accuracy = e.Matrix.Accuracy()
+	e.Classes = e.Matrix.PerClass()
+
+	var scores []float64
+	var labels []bool
+	for _, r := range results {
+		if r.HasScore {
+			scores = append(scores, r.Score)
+			labels = append(labels, r.True == positive)
+		}
+		if r.HasMask {
+			e.Dice += r.Dice
+			e.IoU += r.IoU
+			e.MaskSlides++
+		}
+	}
+	if e.MaskSlides > 0 {
+		e.Dice /= float64(e.MaskSlides)
+		e.IoU /= float64(e.MaskSlides)
+	}
+	e.ROC, e.PR, e.AUC, e.HasAUC = Curves(scores, labels)
+	return e
+}
+
+// compareMasks fills Dice and IoU when both the slide and the truth have a
+// readable mask
+func (r *SlideResult) compareMasks(s Slide, gt *GroundTruth) {
+	masks := s.ByKind(KindMask)
+	truth, ok := gt.Mask(r.Group, r.Slide)
+	if len(masks) == 0 || !ok {
+		return
+	}
+	a, err := readMask(filepath.Join(s.Path, filepath.FromSlash(masks[0].Name)))
+	if err != nil {
+		r.MaskErr = err.Error()
+		return
+	}
+	b, err := readMask(truth)
+	if err != nil {
+		r.MaskErr = err.Error()
+		return
+	}
+	r.Dice, r.IoU, err = Overlap(a, b)
+	if err != nil {
+		r.MaskErr = err.Error()
+		return
+	}
+	r.HasMask = true
+}
+
+// Mask is a binary image
+type Mask struct {
+	Width, Height int
+	Pix           []bool
+}
+
+// MaskFromImage sets pixels that are not black or transparent
+func MaskFromImage(img image.Image) Mask {
+	b := img.Bounds()
+	m := Mask{Width: b.Dx(), Height: b.Dy(), Pix: make([]bool, b.Dx()*b.Dy())}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := img.At(x, y).RGBA()
+			m.Pix[(y-b.Min.Y)*m.Width+x-b.Min.X] = a > 0 && r+g+bl > 0
+		}
+	}
+	return m
+}
+
+func readMask(path string) (Mask, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Mask{}, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return Mask{}, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return MaskFromImage(img), nil
+}
+
+// Overlap returns the Dice coefficient and IoU of two masks of equal size;
+// two empty masks agree perfectly
+func Overlap(a, b Mask) (dice, iou float64, err error) {
+	if a.Width != b.Width || a.Height != b.Height {
+		return 0, 0, fmt.Errorf("mask sizes differ: %dx%d and %dx%d", a.Width, a.Height, b.Width, b.Height)
+	}
+	inter, na, nb := 0, 0, 0
+	for i := range a.Pix {
+		if a.Pix[i] {
+			na++
+		}
+		if b.Pix[i] {
+			nb++
+		}
+		if a.Pix[i] && b.Pix[i] {
+			inter++
+		}
+	}
+	if na+nb == 0 {
+		return 1, 1, nil
+	}
+	return 2 * float64(inter) / float64(na+nb), float64(inter) / float64(na+nb-inter), nil
+}
+
+// Print
+func (e Evaluation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d slides, accuracy %.3f", e.Name, len(e.Slides), e.Accuracy)
+	if e.HasAUC {
+		fmt.Fprintf(&b, ", AUC %.3f (positive %s)", e.AUC, e.Positive)
+	}
+	if e.MaskSlides > 0 {
+		fmt.Fprintf(&b, ", Dice %.3f, IoU %.3f over %d masks", e.Dice, e.IoU, e.MaskSlides)
+	}
+	b.WriteString("\n")
+
+	if len(e.Classes) > 0 {
+		fmt.Fprintf(&b, "  %-16s %9s %9s %9s %8s\n", "class", "precision", "recall", "f1", "support")
+		for _, c := range e.Classes {
+			fmt.Fprintf(&b, "  %-16s %9.3f %9.3f %9.3f %8d\n", c.Class, c.Precision, c.Recall, c.F1, c.Support)
+		}
+		b.WriteString(e.Matrix.String())
+	}
+	if len(e.Unlabeled) > 0 {
+		fmt.Fprintf(&b, "  %d slides without a true label\n", len(e.Unlabeled))
+	}
+	if len(e.Unpredicted) > 0 {
+		fmt.Fprintf(&b, "  %d slides without a predicted label\n", len(e.Unpredicted))
+	}
+	return b.String()
+}
+
+// Print with true classes as rows and predicted classes as columns
+func (cm ConfusionMatrix) String() string {
+	width := 8
+	for _, c := range cm.Classes {
+		if len(c)+1 > width {
+			width = len(c) + 1
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %-*s", width, "true\\pred")
+	for _, c := range cm.Classes {
+		fmt.Fprintf(&b, " %*s", width, c)
+	}
+	b.WriteString("\n")
+	for i, c := range cm.Classes {
+		fmt.Fprintf(&b, "  %-*s", width, c)
+		for j := range cm.Classes {
+			fmt.Fprintf(&b, " %*s", width, strconv.Itoa(cm.Counts[i][j]))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,265 @@
+package predictions
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles writes each file under a new directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// writeMask writes a PNG mask with the given rows, '#' set
+func writeMask(t *testing.T, path string, rows ...string) {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, c := range row {
+			if c == '#' {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// loadDatadir loads every group of dir without touching the user cache
+func loadDatadir(t *testing.T, dir string) *Predictions {
+	t.Helper()
+	m := NewPredictions(dir)
+	m.Manifest = filepath.Join(t.TempDir(), "manifest.json")
+	for e := range m.Load(context.Background(), 1) {
+		if e.Kind == EventError {
+			t.Fatal(e.Err)
+		}
+	}
+	return m
+}
+
+func TestConfusionMatrix(t *testing.T) {
+	results := []SlideResult{
+		{True: "a", Predicted: "a"}, {True: "a", Predicted: "a"}, {True: "a", Predicted: "b"},
+		{True: "b", Predicted: "b"}, {True: "b", Predicted: "c"},
+	}
+	cm := NewConfusionMatrix(results)
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(cm.Classes, want) {
+		t.Fatalf("classes %v, want %v", cm.Classes, want)
+	}
+	if want := [][]int{{2, 1, 0}, {0, 1, 1}, {0, 0, 0}}; !reflect.DeepEqual(cm.Counts, want) {
+		t.Errorf("counts %v, want %v", cm.Counts, want)
+	}
+	if got := cm.Accuracy(); got != 0.6 {
+		t.Errorf("accuracy %v, want 0.6", got)
+	}
+
+	want := []ClassMetrics{
+		{Class: "a", Precision: 1, Recall: 2.0 / 3, F1: 0.8, Support: 3},
+		{Class: "b", Precision: 0.5, Recall: 0.5, F1: 0.5, Support: 2},
+		{Class: "c"},
+	}
+	for i, got := range cm.PerClass() {
+		w := want[i]
+		if got.Class != w.Class || got.Support != w.Support ||
+			!near(got.Precision, w.Precision) || !near(got.Recall, w.Recall) || !near(got.F1, w.F1) {
+			t.Errorf("class %s: %+v, want %+v", w.Class, got, w)
+		}
+	}
+}
+
+func TestCurves(t *testing.T) {
+	tests := []struct {
+		name     string
+		scores   []float64
+		positive []bool
+		auc      float64
+		ok       bool
+		points   int
+	}{
+		{"perfect", []float64{0.9, 0.8, 0.2, 0.1}, []bool{true, true, false, false}, 1, true, 5},
+		{"inverted", []float64{0.9, 0.8, 0.2, 0.1}, []bool{false, false, true, true}, 0, true, 5},
+		{"ties", []float64{0.5, 0.5, 0.5, 0.5}, []bool{true, false, true, false}, 0.5, true, 2},
+		{"mixed", []float64{0.9, 0.7, 0.6, 0.3}, []bool{true, false, true, false}, 0.75, true, 5},
+		{"one class", []float64{0.9, 0.1}, []bool{true, true}, 0, false, 0},
+		{"empty", nil, nil, 0, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roc, pr, auc, ok := Curves(tt.scores, tt.positive)
+			if ok != tt.ok || !near(auc, tt.auc) {
+				t.Fatalf("auc %v ok %v, want %v %v", auc, ok, tt.auc, tt.ok)
+			}
+			if len(roc) != tt.points || len(pr) != tt.points {
+				t.Fatalf("%d ROC and %d PR points, want %d", len(roc), len(pr), tt.points)
+			}
+			if ok {
+				if last := roc[len(roc)-1]; last.X != 1 || last.Y != 1 {
+					t.Errorf("ROC ends at %v,%v, want 1,1", last.X, last.Y)
+				}
+			}
+		})
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	mask := func(w int, pix ...bool) Mask { return Mask{Width: w, Height: len(pix) / w, Pix: pix} }
+	tests := []struct {
+		name      string
+		a, b      Mask
+		dice, iou float64
+		wantErr   bool
+	}{
+		{"same", mask(2, true, false, true, false), mask(2, true, false, true, false), 1, 1, false},
+		{"disjoint", mask(2, true, false, false, false), mask(2, false, true, false, false), 0, 0, false},
+		{"half", mask(2, true, true, false, false), mask(2, true, false, false, false), 2.0 / 3, 0.5, false},
+		{"both empty", mask(2, false, false), mask(2, false, false), 1, 1, false},
+		{"sizes differ", mask(2, true, true), mask(1, true, true), 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dice, iou, err := Overlap(tt.a, tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if !near(dice, tt.dice) || !near(iou, tt.iou) {
+				t.Errorf("dice %v iou %v, want %v %v", dice, iou, tt.dice, tt.iou)
+			}
+		})
+	}
+}
+
+func TestLoadLabelsCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "slide and label",
+			csv:  "slide_id,diagnosis\ns1,tumor\n s2 , normal \n",
+			want: map[string]string{"s1": "tumor", "s2": "normal"},
+		},
+		{
+			name: "with group",
+			csv:  "Split,Name,Label\ntrain,s1,tumor\ntest,s1,normal\n",
+			want: map[string]string{"train/s1": "tumor", "test/s1": "normal", "s1": "tumor"},
+		},
+		{
+			name: "short rows skipped",
+			csv:  "slide,label\ns1\ns2,normal\n",
+			want: map[string]string{"s2": "normal"},
+		},
+		{
+			name:    "no label column",
+			csv:     "slide,score\ns1,0.5\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"labels.csv": tt.csv})
+			got, err := LoadLabelsCSV(filepath.Join(dir, "labels.csv"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("labels %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"train/s1/summary.json": `{"prediction": "tumor", "score": 0.9}`,
+		"train/s2/summary.json": `{"prediction": "normal", "score": 0.8}`,
+		"train/s3/summary.json": `{"prediction": "tumor", "scores": {"tumor": 0.6, "normal": 0.4}}`,
+		"test/s4/summary.json":  `{"prediction": "normal", "score": 0.3}`,
+		"test/s5/summary.json":  `{"prediction": "tumor"}`,
+		"test/s6/other.txt":     "no summary",
+	})
+	writeMask(t, filepath.Join(dir, "train", "s1", "mask.png"), "##..", "##..")
+	m := loadDatadir(t, dir)
+
+	truth := writeFiles(t, map[string]string{
+		"s1/truth.json": `{"ground_truth": "tumor"}`,
+		"s2/truth.json": `{"ground_truth": "tumor", "prediction": "normal"}`,
+		"s3/truth.json": `{"gt": "normal"}`,
+		"s4/truth.json": `{"label": "normal"}`,
+		"s6/truth.json": `{"label": "tumor"}`,
+	})
+	writeMask(t, filepath.Join(truth, "s1", "mask.png"), "#...", "#...")
+	gt, err := LoadGroundTruth(truth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := Evaluate(m, gt, "")
+	all := r.Overall
+	if all.Positive != "tumor" {
+		t.Errorf("positive %q, want tumor", all.Positive)
+	}
+	if len(all.Slides) != 4 {
+		t.Fatalf("%d slides evaluated, want 4", len(all.Slides))
+	}
+	if want := []string{"test/s5"}; !reflect.DeepEqual(all.Unlabeled, want) {
+		t.Errorf("unlabeled %v, want %v", all.Unlabeled, want)
+	}
+	if want := []string{"test/s6"}; !reflect.DeepEqual(all.Unpredicted, want) {
+		t.Errorf("unpredicted %v, want %v", all.Unpredicted, want)
+	}
+	if !near(all.Accuracy, 0.5) {
+		t.Errorf("accuracy %v, want 0.5", all.Accuracy)
+	}
+
+	// Scores are of the positive class: flipped when another class is
+	// predicted, taken from per-class scores when listed
+	want := map[string]float64{"s1": 0.9, "s2": 0.2, "s3": 0.6, "s4": 0.7}
+	for _, s := range all.Slides {
+		if !s.HasScore || !near(s.Score, want[s.Slide]) {
+			t.Errorf("%s: score %v (%v), want %v", s.Slide, s.Score, s.HasScore, want[s.Slide])
+		}
+	}
+	if !all.HasAUC || !near(all.AUC, 0.5) {
+		t.Errorf("auc %v (%v), want 0.5", all.AUC, all.HasAUC)
+	}
+
+	// Masks are compared where both exist
+	if all.MaskSlides != 1 || !near(all.Dice, 2.0/3) || !near(all.IoU, 0.5) {
+		t.Errorf("%d mask slides, dice %v, iou %v; want 1, 0.667, 0.5", all.MaskSlides, all.Dice, all.IoU)
+	}
+
+	if len(r.Groups) != 2 || r.Groups[0].Name != "test" || len(r.Groups[0].Slides) != 1 || len(r.Groups[1].Slides) != 3 {
+		t.Errorf("groups %+v, want test with 1 slide and train with 3", r.Groups)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,320 @@
+package predictions
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LayoutFile is looked up in a datadir and its parents when no layout is given
+const LayoutFile = ".layout.yaml"
+
+// Layout describes how a predictions directory is organized: the nesting of
+// directories down to one per slide and the artifacts each slide should
+// have. For example
+//
+//	levels:
+//	  - name: site
+//	  - name: group
+//	    pattern: 'batch_(?P<batch>\d+)'
+//	  - name: slide
+//	    pattern: '(?P<patient>P\d+)_(?P<stain>HE|IHC)'
+//	artifacts:
+//	  - kind: mask
+//	    pattern: 'masks/.*\.png'
+//	  - kind: summary
+//	    pattern: 'result\.json'
+//	  - kind: overlay
+//	    pattern: '.*_overlay\.jpg'
+//	    optional: true
+//
+// The last level holds slide directories and the levels above it are joined
+// with "/" into the group name, "site/batch_1" above.
+type Layout struct {
+	Levels    []Level        `json:"levels" yaml:"levels"`
+	Artifacts []ArtifactRule `json:"artifacts,omitempty" yaml:"artifacts,omitempty"`
+
+	// File the layout was read from, empty for the default
+	Path string `json:"-" yaml:"-"`
+}
+
+// Level of directories. Names must match the whole pattern, an empty
+// pattern matches any name, and named groups become fields of the slide.
+type Level struct {
+	Name    string `json:"name" yaml:"name"`
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	re      *regexp.Regexp
+}
+
+// ArtifactRule gives a kind to files whose path, relative to the slide
+// directory and with forward slashes, matches the whole pattern. Slides
+// missing a kind that is not optional are incomplete.
+type ArtifactRule struct {
+	Kind     Kind   `json:"kind" yaml:"kind"`
+	Pattern  string `json:"pattern" yaml:"pattern"`
+	Optional bool   `json:"optional,omitempty" yaml:"optional,omitempty"`
+	re       *regexp.Regexp
+}
+
+// DefaultLayout is datadir/group/slide with artifacts classified by name
+func DefaultLayout() *Layout {
+	return &Layout{Levels: []Level{{Name: "group"}, {Name: "slide"}}}
+}
+
+// ReadLayout reads a layout from a YAML (or JSON) file
+func ReadLayout(path string) (*Layout, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var l Layout
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&l); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	l.Path = path
+	if err := l.Compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &l, nil
+}
+
+// FindLayout reads the LayoutFile of dir or its closest parent that has
+// one, or returns the default layout
+func FindLayout(dir string) (*Layout, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return DefaultLayout(), nil
+	}
+	for {
+		p := filepath.Join(abs, LayoutFile)
+		if _, err := os.Stat(p); err == nil {
+			return ReadLayout(p)
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return DefaultLayout(), nil
+		}
+		abs = parent
+	}
+}
+
+// Compile checks the layout and compiles its patterns
+func (l *Layout) Compile() error {
+	if len(l.Levels) == 0 {
+		return errors.New("layout needs at least one level")
+	}
+	for i := range l.Levels {
+		if l.Levels[i].Pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile("^(?:" + l.Levels[i].Pattern + ")$")
+		if err != nil {
+			return fmt.Errorf("level %s: %w", l.Levels[i].Name, err)
+		}
+		l.Levels[i].re = re
+	}
+	for i := range l.Artifacts {
+		if l.Artifacts[i].Kind == "" {
+			return fmt.Errorf("artifact %q has no kind", l.Artifacts[i].Pattern)
+		}
+		re, err := regexp.Compile("^(?:" + l.Artifacts[i].Pattern + ")$")
+		if err != nil {
+			return fmt.Errorf("artifact %s: %w", l.Artifacts[i].Kind, err)
+		}
+		l.Artifacts[i].re = re
+	}
+	return nil
+}
+
+// match reports whether name fits level depth and adds its captures to fields
+func (l *Layout) match(depth int, name string, fields map[string]string) bool {
+	re := l.Levels[depth].re
+	if re == nil {
+		return true
+	}
+	m := re.FindStringSubmatch(name)
+	if m == nil {
+		return false
+	}
+	for i, field := range re.SubexpNames() {
+		if field != "" && m[i] != "" {
+			fields[field] = m[i]
+		}
+	}
+	return true
+}
+
+// Classify returns the kind of an artifact from the first matching rule,
+// or guesses it by name when the layout has no rules
+func (l *Layout) Classify(rel string) Kind {
+	if len(l.Artifacts) == 0 {
+		return ClassifyArtifact(rel)
+	}
+	for _, a := range l.Artifacts {
+		if a.re != nil && a.re.MatchString(rel) {
+			return a.Kind
+		}
+	}
+	return KindOther
+}
+
+// Listed returns the expected kinds, then the optional ones
+func (l *Layout) Listed() []Kind {
+	kinds := l.Expected()
+	if len(l.Artifacts) == 0 {
+		return append(kinds, OptionalKinds...)
+	}
+	seen := make(map[Kind]bool)
+	for _, k := range kinds {
+		seen[k] = true
+	}
+	for _, a := range l.Artifacts {
+		if !seen[a.Kind] {
+			seen[a.Kind] = true
+			kinds = append(kinds, a.Kind)
+		}
+	}
+	return kinds
+}
+
+// Expected returns the kinds every slide should have
+func (l *Layout) Expected() []Kind {
+	if len(l.Artifacts) == 0 {
+		return append([]Kind(nil), ExpectedKinds...)
+	}
+	var kinds []Kind
+	seen := make(map[Kind]bool)
+	for _, a := range l.Artifacts {
+		if !a.Optional && !seen[a.Kind] {
+			seen[a.Kind] = true
+			kinds = append(kinds, a.Kind)
+		}
+	}
+	return kinds
+}
+
+// groupDir is a directory holding slides, relative to the datadir
+type groupDir struct {
+	name   string
+	fields map[string]string
+}
+
+// groups walks every level above the slides, returning the directories
+// that hold slides in sorted order
+func (l *Layout) groups(datadir string) ([]groupDir, error) {
+	if _, err := os.ReadDir(datadir); err != nil {
+		return nil, err
+	}
+	if len(l.Levels) == 1 {
+		return []groupDir{{name: ".", fields: map[string]string{}}}, nil
+	}
+
+	var out []groupDir
+	var walk func(rel string, depth int, fields map[string]string)
+	walk = func(rel string, depth int, fields map[string]string) {
+		entries, err := os.ReadDir(filepath.Join(datadir, filepath.FromSlash(rel)))
+		if err != nil {
+			log.Println("Could not read directory: ", rel, err)
+			return
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			captured := copyFields(fields)
+			if !l.match(depth, e.Name(), captured) {
+				continue
+			}
+			child := path.Join(rel, e.Name())
+			if depth == len(l.Levels)-2 {
+				out = append(out, groupDir{name: child, fields: captured})
+			} else {
+				walk(child, depth+1, captured)
+			}
+		}
+	}
+	walk("", 0, map[string]string{})
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out, nil
+}
+
+// LoadSlide lists the artifacts under a slide directory
+func (l *Layout) LoadSlide(dir, group, name string) (Slide, error) {
+	return l.scanSlide(dir, group, name, nil)
+}
+
+// scanSlide is LoadSlide, also recording the mtime of the slide directory
+// (".") and those below it in dirs unless nil
+func (l *Layout) scanSlide(dir, group, name string, dirs map[string]int64) (Slide, error) {
+	s := Slide{Name: name, Group: group, Path: dir}
+	if len(l.Artifacts) > 0 {
+		s.Expected = l.Expected()
+	}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if dirs != nil {
+				dirs[rel] = info.ModTime().UnixNano()
+			}
+
+			// Tiles of a DeepZoom image belong to its .dzi
+			if p != dir && isDZITiles(p) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		kind := l.Classify(rel)
+
+		// Tile CSVs not named after tiles are told by their header
+		if kind == KindOther && len(l.Artifacts) == 0 && IsTileCSV(p) {
+			kind = KindTiles
+		}
+		s.Artifacts = append(s.Artifacts, Artifact{
+			Name:    rel,
+			Kind:    kind,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	return s, err
+}
+
+// isDZITiles reports whether dir is the _files folder next to a .dzi
+func isDZITiles(dir string) bool {
+	base, ok := strings.CutSuffix(dir, "_files")
+	if !ok {
+		return false
+	}
+	info, err := os.Stat(base + ".dzi")
+	return err == nil && !info.IsDir()
+}
+
+func copyFields(fields map[string]string) map[string]string {
+	out := make(map[string]string, len(fields))
+	for k, v := range fields {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+package predictions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"default levels", "levels: [{name: group}, {name: slide}]", ""},
+		{"no levels", "artifacts: [{kind: mask, pattern: 'm.png'}]", "at least one level"},
+		{"bad level pattern", "levels: [{name: slide, pattern: '(['}]", "level slide"},
+		{"artifact without kind", "levels: [{name: slide}]\nartifacts: [{pattern: 'm.png'}]", "no kind"},
+		{"unknown field", "levels: [{name: slide, regex: 'x'}]", "regex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{LayoutFile: tt.yaml})
+			l, err := ReadLayout(filepath.Join(dir, LayoutFile))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if l.Path == "" {
+					t.Error("layout without path")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindLayout(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		LayoutFile:            "levels: [{name: slide}]",
+		"runs/a/summary.json": "{}",
+	})
+	l, err := FindLayout(filepath.Join(dir, "runs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Levels) != 1 || l.Path != filepath.Join(dir, LayoutFile) {
+		t.Errorf("found %+v, want the layout of the parent", l)
+	}
+
+	l, err = FindLayout(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Path != "" || len(l.Levels) != 2 {
+		t.Errorf("found %+v, want the default layout", l)
+	}
+}
+
+func TestLayoutLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		LayoutFile: `
+levels:
+  - name: site
+  - name: group
+    pattern: 'batch_(?P<batch>\d+)'
+  - name: slide
+    pattern: '(?P<patient>P\d+)_(?P<stain>HE|IHC)'
+artifacts:
+  - kind: mask
+    pattern: 'masks/.*\.png'
+  - kind: summary
+    pattern: 'result\.json'
+  - kind: heatmap
+    pattern: '.*_overlay\.jpg'
+    optional: true
+`,
+		"siteA/batch_1/P01_HE/masks/m.png":      "",
+		"siteA/batch_1/P01_HE/result.json":      "{}",
+		"siteA/batch_1/P01_HE/x_overlay.jpg":    "",
+		"siteA/batch_1/P02_IHC/result.json":     "{}",
+		"siteA/batch_1/P02_IHC/notes.txt":       "",
+		"siteA/batch_1/scratch/result.json":     "{}",
+		"siteA/other/P03_HE/result.json":        "{}",
+		"siteB/batch_20/P04_HE/masks/a/b.png":   "",
+		"siteB/batch_20/P04_HE/result.json":     "{}",
+		"siteB/batch_20/P04_HE/summary.json":    "{}",
+		"siteB/batch_20/P04_HE/masks/readme.md": "",
+	})
+	l, err := FindLayout(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewPredictions(dir)
+	m.Layout = l
+	m.Manifest = filepath.Join(t.TempDir(), "manifest.json")
+	for range m.Load(context.Background(), 1) {
+	}
+
+	// Directories not matching their level are skipped
+	if want := []string{"siteA/batch_1", "siteB/batch_20"}; !reflect.DeepEqual(m.GroupNames(), want) {
+		t.Fatalf("groups %v, want %v", m.GroupNames(), want)
+	}
+	g := m.Groups["siteA/batch_1"]
+	if !reflect.DeepEqual(g.Slidenames, []string{"P01_HE", "P02_IHC"}) {
+		t.Errorf("slides %v, want P01_HE and P02_IHC", g.Slidenames)
+	}
+	if want := map[string]string{"batch": "1"}; !reflect.DeepEqual(g.Fields, want) {
+		t.Errorf("group fields %v, want %v", g.Fields, want)
+	}
+
+	tests := []struct {
+		group, slide string
+		fields       map[string]string
+		kinds        map[string]Kind
+		missing      []Kind
+	}{
+		{
+			group: "siteA/batch_1", slide: "P01_HE",
+			fields:  map[string]string{"batch": "1", "patient": "P01", "stain": "HE"},
+			kinds:   map[string]Kind{"masks/m.png": KindMask, "result.json": KindSummary, "x_overlay.jpg": KindHeatmap},
+			missing: nil,
+		},
+		{
+			group: "siteA/batch_1", slide: "P02_IHC",
+			fields:  map[string]string{"batch": "1", "patient": "P02", "stain": "IHC"},
+			kinds:   map[string]Kind{"notes.txt": KindOther, "result.json": KindSummary},
+			missing: []Kind{KindMask},
+		},
+		{
+			group: "siteB/batch_20", slide: "P04_HE",
+			fields: map[string]string{"batch": "20", "patient": "P04", "stain": "HE"},
+			kinds: map[string]Kind{
+				"masks/a/b.png": KindMask, "masks/readme.md": KindOther,
+				"result.json": KindSummary, "summary.json": KindOther,
+			},
+			missing: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.slide, func(t *testing.T) {
+			s, ok := m.Groups[tt.group].Slide(tt.slide)
+			if !ok {
+				t.Fatalf("no slide %s in %s", tt.slide, tt.group)
+			}
+			if !reflect.DeepEqual(s.Fields, tt.fields) {
+				t.Errorf("fields %v, want %v", s.Fields, tt.fields)
+			}
+			kinds := make(map[string]Kind)
+			for _, a := range s.Artifacts {
+				kinds[a.Name] = a.Kind
+			}
+			if !reflect.DeepEqual(kinds, tt.kinds) {
+				t.Errorf("artifacts %v, want %v", kinds, tt.kinds)
+			}
+			if !reflect.DeepEqual(s.Missing(), tt.missing) {
+				t.Errorf("missing %v, want %v", s.Missing(), tt.missing)
+			}
+		})
+	}
+}
+
+func TestLoadSlide(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"s1/mask.png":                   "",
+		"s1/summary.json":               "{}",
+		"s1/heatmap.dzi":                "<Image/>",
+		"s1/heatmap_files/0/0_0.jpeg":   "",
+		"s1/heatmap_files/1/0_0.jpeg":   "",
+		"s1/debug_files/mask_crop.png":  "",
+		"s1/thumbnail.jpg":              "",
+		"s1/scores.csv":                 "x,y,score\n0,0,0.5\n",
+		"s1/annotations/cells.geojson":  "{}",
+		"s1/heatmap_files_notes.txt":    "",
+		"s1/nested/prob_map.tif":        "",
+		"s1/nested/deeper/notes.txt":    "",
+		"s1/nested/deeper/overview.npy": "",
+	})
+	s, err := LoadSlide(filepath.Join(dir, "s1"), "g", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]Kind)
+	for _, a := range s.Artifacts {
+		got[a.Name] = a.Kind
+	}
+
+	// Tiles of a DeepZoom image are not artifacts of their own
+	want := map[string]Kind{
+		"mask.png":                   KindMask,
+		"summary.json":               KindSummary,
+		"heatmap.dzi":                KindHeatmap,
+		"debug_files/mask_crop.png":  KindMask,
+		"thumbnail.jpg":              KindThumbnail,
+		"scores.csv":                 KindTiles,
+		"annotations/cells.geojson":  KindAnnotations,
+		"heatmap_files_notes.txt":    KindOther,
+		"nested/prob_map.tif":        KindHeatmap,
+		"nested/deeper/notes.txt":    KindOther,
+		"nested/deeper/overview.npy": KindThumbnail,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("artifacts\n got %v\nwant %v", got, want)
+	}
+	if len(s.Missing()) != 0 {
+		t.Errorf("missing %v, want none", s.Missing())
+	}
+
+	if _, err := LoadSlide(filepath.Join(dir, "none"), "g", "none"); !os.IsNotExist(err) {
+		t.Errorf("error %v loading a missing slide, want not exist", err)
+	}
+}
@@ -0,0 +1,264 @@
+package predictions
+
+import (
+	"context"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// Kinds of load events
+const (
+	EventGroup = iota
+	EventDone
+	EventError
+)
+
+// Event reports the progress of Load
+type Event struct {
+	Kind int
+
+	// Group that finished, for EventGroup
+	Group string
+
+	// Groups finished so far and in total
+	Done  int
+	Total int
+
+	// For EventError, and for EventGroup when part of the group was unreadable
+	Err error
+}
+
+// Load reads groups and their slides with a pool of workers, replacing
+// Groups. Events arrive as groups finish and the last one is always
+// EventDone or EventError, after which the channel is closed. Callers must
+// receive until then; cancelling ctx stops the workers early.
+//
+// Unless Rescan is set, slides whose directories kept their mtime are taken
+// from the Manifest, which is written again once everything is loaded.
+// Reviews are read last.
+func (m *Predictions) Load(ctx context.Context, workers int) <-chan Event {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	events := make(chan Event)
+
+	layout := m.Layout
+	if layout == nil {
+		layout = DefaultLayout()
+	}
+
+	go func() {
+		defer close(events)
+		m.Groups = make(map[string]Group)
+		m.Reused, m.Scanned = 0, 0
+
+		names, err := layout.groups(m.Datadir)
+		if err != nil {
+			events <- Event{Kind: EventError, Err: err}
+			return
+		}
+
+		var cache *Manifest
+		if m.Manifest != "" && !m.Rescan {
+			cache, _ = readManifest(m.Manifest, m.Datadir, layout)
+		}
+		abs, _ := filepath.Abs(m.Datadir)
+		next := &Manifest{
+			Version:   manifestVersion,
+			Datadir:   abs,
+			Layout:    layoutKey(layout),
+			GroupDirs: make(map[string]int64),
+			SlideDirs: make(map[string]map[string]int64),
+			Groups:    m.Groups,
+		}
+
+		type result struct {
+			group Group
+			scan  groupScan
+			err   error
+		}
+		jobs := make(chan groupDir)
+		results := make(chan result)
+
+		// Workers
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for dir := range jobs {
+					g, scan, err := m.loadGroup(ctx, layout, dir, cache)
+					results <- result{g, scan, err}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		// Queue groups until done or cancelled
+		go func() {
+			defer close(jobs)
+			for _, name := range names {
+				select {
+				case jobs <- name:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		// Collect, only this goroutine writes Groups
+		for done := 0; done < len(names); {
+			select {
+			case r, ok := <-results:
+				// Workers only stop early when cancelled
+				if !ok {
+					events <- Event{Kind: EventError, Done: done, Total: len(names), Err: ctx.Err()}
+					return
+				}
+				// Groups stopped partway are not kept, nor cached
+				if ctx.Err() != nil && r.err == ctx.Err() {
+					continue
+				}
+				m.Groups[r.group.Name] = r.group
+				next.GroupDirs[r.group.Name] = r.scan.mtime
+				for rel, dirs := range r.scan.slides {
+					next.SlideDirs[rel] = dirs
+				}
+				m.Reused += r.scan.reused
+				m.Scanned += r.scan.scanned
+				done++
+				events <- Event{Kind: EventGroup, Group: r.group.Name, Done: done, Total: len(names), Err: r.err}
+			case <-ctx.Done():
+				// Let running workers finish without a reader
+				go func() {
+					for range results {
+					}
+				}()
+				events <- Event{Kind: EventError, Done: done, Total: len(names), Err: ctx.Err()}
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			events <- Event{Kind: EventError, Done: len(names), Total: len(names), Err: ctx.Err()}
+			return
+		}
+
+		// Only touch the manifest when something had to be read
+		if m.Manifest != "" && (cache == nil || m.Scanned > 0 || len(cache.Groups) != len(m.Groups) || !sameGroupDirs(cache, next)) {
+			// A read-only cache only costs the next load a scan
+			if err := writeManifest(m.Manifest, next); err != nil && !readOnly(err) {
+				log.Println("Could not write manifest: ", m.Manifest, err)
+			}
+		}
+		if err := m.LoadVerdicts(); err != nil {
+			log.Println("Could not read verdicts: ", m.VerdictsPath(), err)
+		}
+		if ctx.Err() != nil {
+			events <- Event{Kind: EventError, Done: len(names), Total: len(names), Err: ctx.Err()}
+			return
+		}
+		events <- Event{Kind: EventDone, Done: len(names), Total: len(names)}
+	}()
+	return events
+}
+
+// groupScan tells how a group was read, for the manifest
+type groupScan struct {
+	mtime   int64
+	slides  map[string]map[string]int64
+	reused  int
+	scanned int
+}
+
+func sameGroupDirs(a, b *Manifest) bool {
+	if len(a.GroupDirs) != len(b.GroupDirs) {
+		return false
+	}
+	for name, mtime := range b.GroupDirs {
+		if a.GroupDirs[name] != mtime {
+			return false
+		}
+	}
+	return true
+}
+
+// loadGroup reads the slides of one group, stopping early when ctx is done.
+// The group directory is only listed when its mtime changed since cache,
+// and slides are only scanned when one of their directories did.
+func (m *Predictions) loadGroup(ctx context.Context, layout *Layout, dir groupDir, cache *Manifest) (Group, groupScan, error) {
+	name := dir.name
+	group := Group{Name: name}
+	scan := groupScan{slides: make(map[string]map[string]int64)}
+	if len(dir.fields) > 0 {
+		group.Fields = dir.fields
+	}
+	info, err := os.Stat(m.SlidePath(name, ""))
+	if err != nil {
+		return group, scan, err
+	}
+	scan.mtime = info.ModTime().UnixNano()
+
+	var names []string
+	cached := make(map[string]Slide)
+	g, ok := cache.group(name)
+	for _, s := range g.Slides {
+		cached[s.Name] = s
+	}
+	if ok && cache.GroupDirs[name] == scan.mtime {
+		names = g.Slidenames
+	} else {
+		entries, err := os.ReadDir(m.SlidePath(name, ""))
+		if err != nil {
+			return group, scan, err
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				names = append(names, e.Name())
+			}
+		}
+	}
+
+	var first error
+	for _, s := range names {
+		if ctx.Err() != nil {
+			return group, scan, ctx.Err()
+		}
+		fields := copyFields(dir.fields)
+		if !layout.match(len(layout.Levels)-1, s, fields) {
+			continue
+		}
+		group.Slidenames = append(group.Slidenames, s)
+
+		rel := path.Join(name, s)
+		slide, ok := cached[s]
+		if ok && cache.slideUnchanged(m.Datadir, rel) {
+			slide.Path = m.SlidePath(name, s)
+			scan.slides[rel] = cache.SlideDirs[rel]
+			scan.reused++
+		} else {
+			dirs := make(map[string]int64)
+			slide, err = layout.scanSlide(m.SlidePath(name, s), name, s, dirs)
+			if err != nil && first == nil {
+				first = err
+			}
+			// Slides read in part are scanned again next time
+			if err == nil {
+				scan.slides[rel] = dirs
+			}
+			scan.scanned++
+		}
+		slide.Fields = nil
+		if len(fields) > 0 {
+			slide.Fields = fields
+		}
+		group.Slides = append(group.Slides, slide)
+	}
+	return group, scan, first
+}
@@ -0,0 +1,105 @@
+package predictions
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeDatadir lays out a group per count with that many slides, each with
+// a summary
+func writeDatadir(t *testing.T, counts ...int) string {
+	t.Helper()
+	dir := t.TempDir()
+	for g, n := range counts {
+		for s := 0; s < n; s++ {
+			slide := filepath.Join(dir, fmt.Sprintf("g%02d", g), fmt.Sprintf("s%04d", s))
+			if err := os.MkdirAll(slide, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			summary := fmt.Sprintf(`{"prediction": "tumor", "score": 0.%d}`, s)
+			if err := os.WriteFile(filepath.Join(slide, "summary.json"), []byte(summary), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeDatadir(t, 4, 4, 4)
+	m := NewPredictions(dir)
+	m.Manifest = filepath.Join(t.TempDir(), "manifest.json")
+
+	var last Event
+	for e := range m.Load(context.Background(), 2) {
+		last = e
+	}
+	if last.Kind != EventDone {
+		t.Fatalf("last event %+v, want EventDone", last)
+	}
+	if len(m.Groups) != 3 {
+		t.Fatalf("%d groups, want 3", len(m.Groups))
+	}
+	for name, g := range m.Groups {
+		if len(g.Slides) != 4 {
+			t.Errorf("group %s has %d slides, want 4", name, len(g.Slides))
+		}
+	}
+	if _, err := os.Stat(m.Manifest); err != nil {
+		t.Errorf("manifest not written: %v", err)
+	}
+
+	// A second load reuses every slide
+	m2 := NewPredictions(dir)
+	m2.Manifest = m.Manifest
+	for range m2.Load(context.Background(), 2) {
+	}
+	if m2.Reused != 12 || m2.Scanned != 0 {
+		t.Errorf("reused %d scanned %d, want 12 and 0", m2.Reused, m2.Scanned)
+	}
+}
+
+func TestLoadCancel(t *testing.T) {
+	// Two small groups finish first; the large ones are still being
+	// scanned when the load is cancelled
+	counts := []int{1, 1, 800, 800, 800, 800}
+	dir := writeDatadir(t, counts...)
+	for i := 0; i < 5; i++ {
+		m := NewPredictions(dir)
+		m.Manifest = filepath.Join(t.TempDir(), "manifest.json")
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// Cancel while the collector is held up sending the next event, so
+		// that stopped workers and their results race the cancellation
+		var last Event
+		for e := range m.Load(ctx, 4) {
+			if e.Kind == EventGroup && ctx.Err() == nil {
+				time.Sleep(5 * time.Millisecond)
+				cancel()
+				time.Sleep(50 * time.Millisecond)
+			}
+			last = e
+		}
+		cancel()
+
+		if last.Kind != EventError {
+			t.Fatalf("last event %+v, want EventError", last)
+		}
+		if _, ok := m.Groups[""]; ok {
+			t.Fatal("empty group stored after cancel")
+		}
+		for g, n := range counts {
+			name := fmt.Sprintf("g%02d", g)
+			if group, ok := m.Groups[name]; ok && len(group.Slidenames) != n {
+				t.Fatalf("group %s kept with %d of %d slides", name, len(group.Slidenames), n)
+			}
+		}
+		if _, err := os.Stat(m.Manifest); !os.IsNotExist(err) {
+			t.Fatalf("manifest written after cancel: %v", err)
+		}
+	}
+}
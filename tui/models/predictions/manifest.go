@@ -0,0 +1,138 @@
+package predictions
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Bumped whenever the manifest layout or slide scanning changes
+const manifestVersion = 3
+
+// Manifest caches a scan of a datadir. A slide is reused as long as its
+// directory and every directory below it keep their mtime; files changed in
+// place without adding or removing entries are not noticed until --rescan.
+type Manifest struct {
+	Version int    `json:"version"`
+	Datadir string `json:"datadir"`
+
+	// Layout the datadir was scanned with, as JSON
+	Layout string `json:"layout"`
+
+	// Mtimes in nanoseconds of group directories by group name, and of each
+	// slide directory (".") and the directories below it by slide path
+	// relative to the datadir, with forward slashes
+	GroupDirs map[string]int64            `json:"group_dirs"`
+	SlideDirs map[string]map[string]int64 `json:"slide_dirs"`
+
+	Groups map[string]Group `json:"groups"`
+}
+
+// ManifestPath is where the manifest of Datadir is stored, in the user's
+// cache directory so that neither the datadir nor its parent is written to.
+// It is empty, and scans are not cached, without a cache directory.
+func (m *Predictions) ManifestPath() string {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	abs, err := filepath.Abs(m.Datadir)
+	if err != nil {
+		abs = filepath.Clean(m.Datadir)
+	}
+	sum := sha256.Sum256([]byte(abs))
+	name := filepath.Base(abs) + "-" + hex.EncodeToString(sum[:8]) + ".json"
+	return filepath.Join(cache, "go-tools", "manifests", name)
+}
+
+// layoutKey identifies a layout in the manifest
+func layoutKey(l *Layout) string {
+	data, _ := json.Marshal(l)
+	return string(data)
+}
+
+// readManifest loads the manifest at path if it was written for this
+// datadir and layout
+func readManifest(path, datadir string, l *Layout) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var mf Manifest
+	if err := json.NewDecoder(bufio.NewReader(f)).Decode(&mf); err != nil {
+		return nil, err
+	}
+	abs, _ := filepath.Abs(datadir)
+	if mf.Version != manifestVersion || mf.Datadir != abs || mf.Layout != layoutKey(l) {
+		return nil, os.ErrNotExist
+	}
+	return &mf, nil
+}
+
+// writeManifest writes through a temporary file so a crash never leaves a
+// half written manifest
+func writeManifest(path string, mf *Manifest) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	w := bufio.NewWriter(f)
+	err = json.NewEncoder(w).Encode(mf)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// readOnly reports whether err comes from a location that cannot be written
+func readOnly(err error) bool {
+	return errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS)
+}
+
+// slideUnchanged reports whether the slide directory and every directory
+// below it keep their mtime
+func (mf *Manifest) slideUnchanged(datadir, rel string) bool {
+	if mf == nil {
+		return false
+	}
+	dirs, ok := mf.SlideDirs[rel]
+	if !ok {
+		return false
+	}
+	for dir, mtime := range dirs {
+		info, err := os.Stat(filepath.Join(datadir, filepath.FromSlash(rel), filepath.FromSlash(dir)))
+		if err != nil || info.ModTime().UnixNano() != mtime {
+			return false
+		}
+	}
+	return true
+}
+
+// group returns a cached group
+func (mf *Manifest) group(name string) (Group, bool) {
+	if mf == nil {
+		return Group{}, false
+	}
+	g, ok := mf.Groups[name]
+	return g, ok
+}
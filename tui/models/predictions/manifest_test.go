@@ -0,0 +1,144 @@
+package predictions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManifest(t *testing.T) {
+	dir := writeDatadir(t, 2, 3)
+	manifest := filepath.Join(t.TempDir(), "manifest.json")
+
+	// Directory mtimes may not move within a clock tick, so changes set
+	// their own
+	stamp := time.Now()
+	touch := func(parts ...string) error {
+		stamp = stamp.Add(time.Second)
+		return os.Chtimes(filepath.Join(append([]string{dir}, parts...)...), stamp, stamp)
+	}
+	write := func(parts ...string) error {
+		path := filepath.Join(append([]string{dir}, parts...)...)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			return err
+		}
+		return touch(parts[:len(parts)-1]...)
+	}
+
+	tests := []struct {
+		name    string
+		change  func(m *Predictions) error
+		reused  int
+		scanned int
+		slides  int
+	}{
+		{
+			name:    "first load",
+			change:  func(m *Predictions) error { return nil },
+			scanned: 5, slides: 5,
+		},
+		{
+			name:   "unchanged",
+			change: func(m *Predictions) error { return nil },
+			reused: 5, slides: 5,
+		},
+		{
+			name:   "file added to a slide",
+			change: func(m *Predictions) error { return write("g00", "s0000", "mask.png") },
+			reused: 4, scanned: 1, slides: 5,
+		},
+		{
+			name: "file added below a slide",
+			change: func(m *Predictions) error {
+				if err := write("g01", "s0001", "masks", "a.png"); err != nil {
+					return err
+				}
+				return write("g01", "s0001", "masks", "b.png")
+			},
+			reused: 4, scanned: 1, slides: 5,
+		},
+		{
+			name: "slide added",
+			change: func(m *Predictions) error {
+				if err := write("g01", "s0009", "summary.json"); err != nil {
+					return err
+				}
+				return touch("g01")
+			},
+			reused: 5, scanned: 1, slides: 6,
+		},
+		{
+			name: "slide removed",
+			change: func(m *Predictions) error {
+				if err := os.RemoveAll(filepath.Join(dir, "g00", "s0001")); err != nil {
+					return err
+				}
+				return touch("g00")
+			},
+			reused: 5, slides: 5,
+		},
+		{
+			name:    "unreadable manifest",
+			change:  func(m *Predictions) error { return os.WriteFile(manifest, []byte("{"), 0o644) },
+			scanned: 5, slides: 5,
+		},
+		{
+			name:   "rewritten",
+			change: func(m *Predictions) error { return nil },
+			reused: 5, slides: 5,
+		},
+		{
+			name: "rescan",
+			change: func(m *Predictions) error {
+				m.Rescan = true
+				return nil
+			},
+			scanned: 5, slides: 5,
+		},
+		{
+			name: "other layout",
+			change: func(m *Predictions) error {
+				m.Layout = &Layout{
+					Levels:    DefaultLayout().Levels,
+					Artifacts: []ArtifactRule{{Kind: KindSummary, Pattern: `summary\.json`}},
+				}
+				return m.Layout.Compile()
+			},
+			scanned: 5, slides: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewPredictions(dir)
+			m.Manifest = manifest
+			if err := tt.change(m); err != nil {
+				t.Fatal(err)
+			}
+			for e := range m.Load(context.Background(), 2) {
+				if e.Kind == EventError {
+					t.Fatal(e.Err)
+				}
+			}
+			if m.Reused != tt.reused || m.Scanned != tt.scanned {
+				t.Errorf("reused %d scanned %d, want %d and %d", m.Reused, m.Scanned, tt.reused, tt.scanned)
+			}
+			if n := len(m.AllSlides()); n != tt.slides {
+				t.Errorf("%d slides, want %d", n, tt.slides)
+			}
+		})
+	}
+
+	// The manifest holds what was scanned, not a leftover temporary file
+	entries, err := os.ReadDir(filepath.Dir(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%d files next to the manifest, want 1", len(entries))
+	}
+}
@@ -1,15 +1,42 @@
 package predictions
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
+	"path/filepath"
+	"sort"
 )
 
 // Group data
 type Group struct {
-	Name       string
-	Slidenames []string
+	Name       string   `json:"name" yaml:"name"`
+	Slidenames []string `json:"slides" yaml:"slides"`
+	Slides     []Slide  `json:"slide_details" yaml:"slide_details"`
+
+	// Captured by the layout's patterns for the levels above the slides
+	Fields map[string]string `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// Slide by name
+func (g Group) Slide(name string) (Slide, bool) {
+	for _, s := range g.Slides {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Slide{}, false
+}
+
+// Incomplete returns the slides missing an expected artifact
+func (g Group) Incomplete() []Slide {
+	var out []Slide
+	for _, s := range g.Slides {
+		if !s.Complete() {
+			out = append(out, s)
+		}
+	}
+	return out
 }
 
 // Predictions data
@@ -17,10 +44,26 @@ type Predictions struct {
 	Datadir string
 	Groups  map[string]Group
 	Loaded  chan bool
+
+	// How Datadir is organized, the default layout unless replaced
+	Layout *Layout
+
+	// Manifest file caching the last scan, none when empty; Rescan ignores
+	// what it holds and scans everything again
+	Manifest string
+	Rescan   bool
+
+	// Slides read from the manifest and scanned by the last Load
+	Reused  int
+	Scanned int
+
+	// Verdicts of reviewers by group/slide, read from VerdictsFile by Load
+	Reviews map[string]Review
 }
 
 func NewPredictions(datadir string) *Predictions {
-	m := Predictions{Datadir: datadir, Loaded: make(chan bool)}
+	m := Predictions{Datadir: datadir, Loaded: make(chan bool), Layout: DefaultLayout()}
+	m.Manifest = m.ManifestPath()
 	return &m
 }
 
@@ -32,44 +75,47 @@ Output Dir: %s
 		`,
 		m.Datadir, len(m.Groups),
 	)
-	for k, g := range m.Groups {
-		base += fmt.Sprintf("\n  %s: %d", k, len(g.Slidenames))
+	for _, k := range m.GroupNames() {
+		g := m.Groups[k]
+		base += fmt.Sprintf("\n  %s: %d slides, %d incomplete", k, len(g.Slidenames), len(g.Incomplete()))
 	}
 	return base
 }
 
-func (m *Predictions) SetGroups() {
-	// Allocate
-	m.Groups = make(map[string]Group)
-
-	// Iterate over directories
-	entries, err := os.ReadDir(m.Datadir)
-	if err != nil {
-		log.Println("Could not read datadir: ", m.Datadir, err)
-		return
+// GroupNames sorted
+func (m *Predictions) GroupNames() []string {
+	names := make([]string, 0, len(m.Groups))
+	for k := range m.Groups {
+		names = append(names, k)
 	}
-	for _, e := range entries {
-		if e.IsDir() {
-			// At least record the name
-			group := Group{Name: e.Name()}
-
-			// Get slides if readable
-			entries, err := os.ReadDir(fmt.Sprintf("%s/%s", m.Datadir, e.Name()))
-			if err != nil {
-				log.Println("Could not read group: ", group.Name, err)
-			} else {
-				for _, s := range entries {
-					if s.IsDir() {
-						group.Slidenames = append(group.Slidenames, s.Name())
-					}
-				}
-			}
-
-			// Update global state
-			m.Groups[group.Name] = group
-		}
+	sort.Strings(names)
+	return names
+}
+
+// AllSlides returns the slides of every group, by group then name
+func (m *Predictions) AllSlides() []Slide {
+	var slides []Slide
+	for _, name := range m.GroupNames() {
+		slides = append(slides, m.Groups[name].Slides...)
 	}
+	return slides
+}
 
-	// Inform that load is finished
-	m.Loaded <- true
+// SlidePath is the directory of a slide, group may be nested with "/"
+func (m *Predictions) SlidePath(group, slide string) string {
+	return filepath.Join(m.Datadir, filepath.FromSlash(group), slide)
+}
+
+// SetGroups loads every group and signals Loaded, also when loading fails
+func (m *Predictions) SetGroups() {
+	defer func() { m.Loaded <- true }()
+
+	for e := range m.Load(context.Background(), 0) {
+		switch {
+		case e.Kind == EventError:
+			log.Println("Could not read datadir: ", m.Datadir, e.Err)
+		case e.Err != nil:
+			log.Println("Could not read group: ", e.Group, e.Err)
+		}
+	}
 }
@@ -0,0 +1,278 @@
+package predictions
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Largest side in pixels of thumbnails embedded in HTML reports
+const reportThumbSide = 256
+
+// WriteHTML writes the report as one HTML page with thumbnails embedded, so
+// it can be shared as a single file
+func (r RunReport) WriteHTML(w io.Writer) error {
+	thumbs := make(map[string]template.URL)
+	for _, c := range r.Attention {
+		if c.Thumbnail == "" {
+			continue
+		}
+		uri, err := thumbnailURI(c.Thumbnail, reportThumbSide)
+		if err != nil {
+			continue
+		}
+		thumbs[c.Thumbnail] = uri
+	}
+	return htmlReport.Execute(w, struct {
+		RunReport
+		Thumbs    map[string]template.URL
+		ThumbSide int
+	}{r, thumbs, reportThumbSide})
+}
+
+// WriteMarkdown writes the report as Markdown, linking thumbnails relative
+// to dir, the directory the report is written to
+func (r RunReport) WriteMarkdown(w io.Writer, dir string) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "# Predictions %s\n\n", mdEscape(r.Datadir))
+	fmt.Fprintf(out, "%d slides in %d groups, %s\n\n", r.Slides, len(r.Groups), r.Created.Format("2006-01-02 15:04"))
+
+	fmt.Fprintf(out, "## Groups\n\n")
+	fmt.Fprintf(out, "| Group | Slides | Incomplete | Unlabeled | Predicted | Mean score |\n")
+	fmt.Fprintf(out, "|---|---:|---:|---:|---|---:|\n")
+	for _, g := range r.Groups {
+		fmt.Fprintf(out, "| %s | %d | %d | %d | %s | %s |\n", mdEscape(g.Name), g.Slides, g.Incomplete, g.Unlabeled,
+			mdEscape(labelCounts(g.Labels)), meanScore(g))
+	}
+
+	if e := r.Evaluation; e != nil {
+		fmt.Fprintf(out, "\n## Evaluation\n\nAgainst %s\n", mdEscape(e.Truth))
+		for _, ev := range append([]Evaluation{e.Overall}, e.Groups...) {
+			fmt.Fprintf(out, "\n### %s\n\n%s\n\n", mdEscape(ev.Name), mdEscape(evaluationLine(ev)))
+			if len(ev.Classes) == 0 {
+				continue
+			}
+			fmt.Fprintf(out, "| Class | Precision | Recall | F1 | Support |\n|---|---:|---:|---:|---:|\n")
+			for _, c := range ev.Classes {
+				fmt.Fprintf(out, "| %s | %.3f | %.3f | %.3f | %d |\n", mdEscape(c.Class), c.Precision, c.Recall, c.F1, c.Support)
+			}
+			fmt.Fprintf(out, "\n| true \\ predicted |")
+			for _, c := range ev.Matrix.Classes {
+				fmt.Fprintf(out, " %s |", mdEscape(c))
+			}
+			fmt.Fprintf(out, "\n|---|%s\n", strings.Repeat("---:|", len(ev.Matrix.Classes)))
+			for i, c := range ev.Matrix.Classes {
+				fmt.Fprintf(out, "| %s |", mdEscape(c))
+				for _, n := range ev.Matrix.Counts[i] {
+					fmt.Fprintf(out, " %d |", n)
+				}
+				fmt.Fprintln(out)
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "\n## Needs attention\n\n")
+	if len(r.Attention) == 0 {
+		fmt.Fprintf(out, "Nothing flagged.\n")
+		return out.Flush()
+	}
+	if r.AttentionTotal > len(r.Attention) {
+		fmt.Fprintf(out, "Top %d of %d flagged slides.\n\n", len(r.Attention), r.AttentionTotal)
+	}
+	fmt.Fprintf(out, "| | Slide | Predicted | True | Score | Why |\n|---|---|---|---|---:|---|\n")
+	for _, c := range r.Attention {
+		thumb := ""
+		if c.Thumbnail != "" {
+			thumb = fmt.Sprintf("![%s](%s)", mdEscape(c.Slide), relativeLink(dir, c.Thumbnail))
+		}
+		fmt.Fprintf(out, "| %s | %s/%s | %s | %s | %s | %s |\n", thumb, mdEscape(c.Group), mdEscape(c.Slide),
+			mdEscape(dash(c.Label)), mdEscape(dash(c.True)), scoreText(c.Score, c.HasScore), mdEscape(strings.Join(c.Reasons, "; ")))
+	}
+	return out.Flush()
+}
+
+// thumbnailURI scales an image down to side pixels and returns it as a JPEG
+// data URI
+func thumbnailURI(path string, side int) (template.URL, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return "", err
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > side || h > side {
+		if w >= h {
+			w, h = side, h*side/w
+		} else {
+			w, h = w*side/h, side
+		}
+	}
+	if w < 1 || h < 1 {
+		return "", fmt.Errorf("%s: empty image", filepath.Base(path))
+	}
+
+	// Nearest pixel, over white where transparent
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, a := img.At(b.Min.X+x*b.Dx()/w, b.Min.Y+y*b.Dy()/h).RGBA()
+			white := 0xffff - a
+			out.SetRGBA(x, y, color.RGBA{uint8((r + white) >> 8), uint8((g + white) >> 8), uint8((bl + white) >> 8), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, out, &jpeg.Options{Quality: 85}); err != nil {
+		return "", err
+	}
+	return template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+}
+
+// relativeLink is path relative to dir with forward slashes, for a link from
+// a file in dir
+func relativeLink(dir, path string) string {
+	link := path
+	absDir, errDir := filepath.Abs(dir)
+	absPath, errPath := filepath.Abs(path)
+	if errDir == nil && errPath == nil {
+		if rel, err := filepath.Rel(absDir, absPath); err == nil {
+			link = rel
+		}
+	}
+	return strings.ReplaceAll(filepath.ToSlash(link), " ", "%20")
+}
+
+// evaluationLine is the first line of an evaluation's String
+func evaluationLine(e Evaluation) string {
+	line, _, _ := strings.Cut(e.String(), "\n")
+	return line
+}
+
+func labelCounts(labels []LabelCount) string {
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = fmt.Sprintf("%s %d", l.Class, l.Count)
+	}
+	return dash(strings.Join(parts, ", "))
+}
+
+func meanScore(g GroupSummary) string {
+	return scoreText(g.MeanScore, g.Scored > 0)
+}
+
+func scoreText(v float64, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.3f", v)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Characters that would break Markdown tables or start emphasis
+var mdEscaper = strings.NewReplacer("|", "\\|", "*", "\\*", "_", "\\_", "`", "\\`", "<", "&lt;", "\n", " ")
+
+// mdEscape keeps text from being read as Markdown
+func mdEscape(s string) string {
+	return mdEscaper.Replace(s)
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"labels": labelCounts,
+	"mean":   meanScore,
+	"score":  scoreText,
+	"dash":   dash,
+	"line":   evaluationLine,
+	"join":   strings.Join,
+	"evaluations": func(r *Report) []Evaluation {
+		return append([]Evaluation{r.Overall}, r.Groups...)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Predictions {{.Datadir}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+td.n { text-align: right; font-variant-numeric: tabular-nums; }
+th { background: #f4f4f4; }
+.muted { color: #777; }
+.reason { color: #a40000; }
+img { max-width: {{.ThumbSide}}px; }
+</style>
+</head>
+<body>
+<h1>Predictions {{.Datadir}}</h1>
+<p class="muted">{{.Slides}} slides in {{len .Groups}} groups, {{.Created.Format "2006-01-02 15:04"}}</p>
+
+<h2>Groups</h2>
+<table>
+<tr><th>Group</th><th>Slides</th><th>Incomplete</th><th>Unlabeled</th><th>Predicted</th><th>Mean score</th></tr>
+{{- range .Groups}}
+<tr><td>{{.Name}}</td><td class="n">{{.Slides}}</td><td class="n">{{.Incomplete}}</td><td class="n">{{.Unlabeled}}</td><td>{{labels .Labels}}</td><td class="n">{{mean .}}</td></tr>
+{{- end}}
+</table>
+
+{{- with .Evaluation}}
+<h2>Evaluation</h2>
+<p class="muted">Against {{.Truth}}</p>
+{{- range evaluations .}}
+<h3>{{.Name}}</h3>
+<p>{{line .}}</p>
+{{- if .Classes}}
+<table>
+<tr><th>Class</th><th>Precision</th><th>Recall</th><th>F1</th><th>Support</th></tr>
+{{- range .Classes}}
+<tr><td>{{.Class}}</td><td class="n">{{printf "%.3f" .Precision}}</td><td class="n">{{printf "%.3f" .Recall}}</td><td class="n">{{printf "%.3f" .F1}}</td><td class="n">{{.Support}}</td></tr>
+{{- end}}
+</table>
+<table>
+<tr><th>true \ predicted</th>{{range .Matrix.Classes}}<th>{{.}}</th>{{end}}</tr>
+{{- $counts := .Matrix.Counts}}
+{{- range $i, $c := .Matrix.Classes}}
+<tr><th>{{$c}}</th>{{range index $counts $i}}<td class="n">{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- end}}
+
+<h2>Needs attention</h2>
+{{- if not .Attention}}
+<p>Nothing flagged.</p>
+{{- else}}
+{{- if gt .AttentionTotal (len .Attention)}}
+<p class="muted">Top {{len .Attention}} of {{.AttentionTotal}} flagged slides.</p>
+{{- end}}
+<table>
+<tr><th></th><th>Slide</th><th>Predicted</th><th>True</th><th>Score</th><th>Why</th></tr>
+{{- $thumbs := .Thumbs}}
+{{- range .Attention}}
+<tr><td>{{with index $thumbs .Thumbnail}}<img src="{{.}}" alt="">{{end}}</td><td>{{.Group}}/{{.Slide}}</td><td>{{dash .Label}}</td><td>{{dash .True}}</td><td class="n">{{score .Score .HasScore}}</td><td class="reason">{{join .Reasons "; "}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,168 @@
+package predictions
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Scores this close to 0.5 count as uncertain
+const uncertainMargin = 0.15
+
+// RunReport gathers what a reviewer needs to sign off a run: a summary per
+// group, the evaluation when there is ground truth and the slides that most
+// need a look
+type RunReport struct {
+	Datadir string    `json:"datadir" yaml:"datadir"`
+	Created time.Time `json:"created" yaml:"created"`
+	Slides  int       `json:"slides" yaml:"slides"`
+
+	Groups []GroupSummary `json:"groups" yaml:"groups"`
+
+	// Evaluation against ground truth, nil without one
+	Evaluation *Report `json:"evaluation,omitempty" yaml:"evaluation,omitempty"`
+
+	// Slides needing attention, most urgent first, and how many there were
+	// before keeping the top ones
+	Attention      []Concern `json:"attention" yaml:"attention"`
+	AttentionTotal int       `json:"attention_total" yaml:"attention_total"`
+}
+
+// GroupSummary counts the slides of a group and their predicted labels
+type GroupSummary struct {
+	Name       string `json:"name" yaml:"name"`
+	Slides     int    `json:"slides" yaml:"slides"`
+	Incomplete int    `json:"incomplete" yaml:"incomplete"`
+	Unlabeled  int    `json:"unlabeled" yaml:"unlabeled"`
+
+	// Slides per predicted class, by class
+	Labels []LabelCount `json:"labels" yaml:"labels"`
+
+	// Mean score over scored slides
+	MeanScore float64 `json:"mean_score,omitempty" yaml:"mean_score,omitempty"`
+	Scored    int     `json:"scored" yaml:"scored"`
+}
+
+// LabelCount is the number of slides predicted as a class
+type LabelCount struct {
+	Class string `json:"class" yaml:"class"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+// Concern is a slide that needs a look, with the reasons why. Severity adds
+// up the reasons, misclassified slides weighing most.
+type Concern struct {
+	Group    string   `json:"group" yaml:"group"`
+	Slide    string   `json:"slide" yaml:"slide"`
+	Reasons  []string `json:"reasons" yaml:"reasons"`
+	Severity float64  `json:"severity" yaml:"severity"`
+
+	Label    string  `json:"label,omitempty" yaml:"label,omitempty"`
+	True     string  `json:"true,omitempty" yaml:"true,omitempty"`
+	Score    float64 `json:"score,omitempty" yaml:"score,omitempty"`
+	HasScore bool    `json:"has_score" yaml:"has_score"`
+
+	// Thumbnail artifact, empty if the slide has none
+	Thumbnail string `json:"thumbnail,omitempty" yaml:"thumbnail,omitempty"`
+}
+
+// NewRunReport summarizes m, with eval when not nil, keeping the top slides
+// needing attention, all of them when top is 0
+func NewRunReport(m *Predictions, eval *Report, top int) RunReport {
+	r := RunReport{Datadir: m.Datadir, Created: time.Now(), Evaluation: eval}
+
+	results := make(map[string]SlideResult)
+	if eval != nil {
+		for _, s := range eval.Overall.Slides {
+			results[s.Group+"/"+s.Slide] = s
+		}
+	}
+
+	for _, name := range m.GroupNames() {
+		g := m.Groups[name]
+		sum := GroupSummary{Name: name, Slides: len(g.Slides)}
+		counts := make(map[string]int)
+		for _, s := range g.Slides {
+			r.Slides++
+			label, ok := s.Label()
+			if ok {
+				counts[label.Class]++
+			} else {
+				sum.Unlabeled++
+			}
+			if ok && label.HasScore {
+				sum.MeanScore += label.Score
+				sum.Scored++
+			}
+			if !s.Complete() {
+				sum.Incomplete++
+			}
+
+			result, evaluated := results[name+"/"+s.Name]
+			if c, ok := concern(s, label, ok, result, evaluated); ok {
+				r.Attention = append(r.Attention, c)
+			}
+		}
+		if sum.Scored > 0 {
+			sum.MeanScore /= float64(sum.Scored)
+		}
+		for class, n := range counts {
+			sum.Labels = append(sum.Labels, LabelCount{Class: class, Count: n})
+		}
+		sort.Slice(sum.Labels, func(i, j int) bool { return sum.Labels[i].Class < sum.Labels[j].Class })
+		r.Groups = append(r.Groups, sum)
+	}
+
+	sort.SliceStable(r.Attention, func(i, j int) bool {
+		return r.Attention[i].Severity > r.Attention[j].Severity
+	})
+	r.AttentionTotal = len(r.Attention)
+	if top > 0 && len(r.Attention) > top {
+		r.Attention = r.Attention[:top]
+	}
+	return r
+}
+
+// concern lists why a slide needs a look, if it does
+func concern(s Slide, label Label, labeled bool, result SlideResult, evaluated bool) (Concern, bool) {
+	c := Concern{Group: s.Group, Slide: s.Name, Label: label.Class, Score: label.Score, HasScore: label.HasScore}
+	if thumbs := s.ByKind(KindThumbnail); len(thumbs) > 0 {
+		c.Thumbnail = filepath.Join(s.Path, filepath.FromSlash(thumbs[0].Name))
+	}
+	add := func(severity float64, reason string) {
+		c.Severity += severity
+		c.Reasons = append(c.Reasons, reason)
+	}
+
+	if evaluated {
+		c.True = result.True
+		c.Score, c.HasScore = result.Score, result.HasScore
+		if !result.Correct() {
+			add(4, fmt.Sprintf("predicted %s, true %s", result.Predicted, result.True))
+		}
+		if result.HasMask && result.Dice < DefaultAgreement {
+			add(2+DefaultAgreement-result.Dice, fmt.Sprintf("mask Dice %.3f", result.Dice))
+		}
+		if result.MaskErr != "" {
+			add(1, "mask: "+result.MaskErr)
+		}
+	}
+	if missing := s.Missing(); len(missing) > 0 {
+		kinds := make([]string, len(missing))
+		for i, k := range missing {
+			kinds[i] = string(k)
+		}
+		add(3, "missing "+strings.Join(kinds, ", "))
+	}
+	if !labeled {
+		add(3, "no predicted label")
+	}
+	if c.HasScore && math.Abs(c.Score-0.5) < uncertainMargin {
+		// Closer to 0.5 is more urgent, up to 1
+		add(1-math.Abs(c.Score-0.5)/uncertainMargin, fmt.Sprintf("uncertain score %.3f", c.Score))
+	}
+	return c, len(c.Reasons) > 0
+}
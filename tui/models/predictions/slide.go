@@ -0,0 +1,187 @@
+package predictions
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kind of artifact written for a slide
+type Kind string
+
+const (
+	KindMask      Kind = "mask"
+	KindHeatmap   Kind = "heatmap"
+	KindTiles     Kind = "tiles"
+	KindSummary   Kind = "summary"
+	KindThumbnail Kind = "thumbnail"
+	KindOther     Kind = "other"
+
+	// GeoJSON features, optional
+	KindAnnotations Kind = "annotations"
+)
+
+// Kinds every slide is expected to have in the default layout
+var ExpectedKinds = []Kind{KindMask, KindSummary}
+
+// Kinds the default layout lists when present, without reporting them
+// missing
+var OptionalKinds = []Kind{KindHeatmap, KindTiles, KindThumbnail}
+
+// Artifact is one file of a slide directory
+type Artifact struct {
+	// Relative to the slide directory, with forward slashes
+	Name    string    `json:"name" yaml:"name"`
+	Kind    Kind      `json:"kind" yaml:"kind"`
+	Size    int64     `json:"size" yaml:"size"`
+	ModTime time.Time `json:"mtime" yaml:"mtime"`
+}
+
+// Slide directory and its artifacts
+type Slide struct {
+	Name      string     `json:"name" yaml:"name"`
+	Group     string     `json:"group" yaml:"group"`
+	Path      string     `json:"path" yaml:"path"`
+	Artifacts []Artifact `json:"artifacts" yaml:"artifacts"`
+
+	// Captured by the layout's name patterns, such as patient or stain
+	Fields map[string]string `json:"fields,omitempty" yaml:"fields,omitempty"`
+
+	// Kinds the layout expects, ExpectedKinds when empty
+	Expected []Kind `json:"expected,omitempty" yaml:"expected,omitempty"`
+}
+
+var imageExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".tif": true, ".tiff": true, ".npy": true, ".npz": true,
+	".dzi": true,
+}
+
+// ClassifyArtifact guesses the kind of an artifact from its name
+func ClassifyArtifact(name string) Kind {
+	base := strings.ToLower(filepath.Base(name))
+	ext := filepath.Ext(base)
+	switch {
+	case imageExts[ext] && (strings.Contains(base, "thumb") || strings.Contains(base, "overview")):
+		return KindThumbnail
+	case imageExts[ext] && (strings.Contains(base, "heatmap") || strings.Contains(base, "prob")):
+		return KindHeatmap
+	case imageExts[ext] && (strings.Contains(base, "mask") || strings.Contains(base, "seg")):
+		return KindMask
+	case ext == ".geojson" || (ext == ".json" && (strings.Contains(base, "annotation") || strings.Contains(base, "geojson"))):
+		return KindAnnotations
+	case ext == ".csv" && strings.Contains(base, "tile"):
+		return KindTiles
+	case ext == ".json":
+		return KindSummary
+	}
+	return KindOther
+}
+
+// LoadSlide lists the artifacts under a slide directory of the default
+// layout
+func LoadSlide(path, group, name string) (Slide, error) {
+	return DefaultLayout().LoadSlide(path, group, name)
+}
+
+// ByKind returns the artifacts of one kind
+func (s Slide) ByKind(k Kind) []Artifact {
+	var out []Artifact
+	for _, a := range s.Artifacts {
+		if a.Kind == k {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// Has reports whether the slide has an artifact of kind k
+func (s Slide) Has(k Kind) bool {
+	for _, a := range s.Artifacts {
+		if a.Kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// expected returns the kinds the slide should have
+func (s Slide) expected() []Kind {
+	if len(s.Expected) > 0 {
+		return s.Expected
+	}
+	return ExpectedKinds
+}
+
+// Missing returns the expected kinds the slide has no artifact for
+func (s Slide) Missing() []Kind {
+	var missing []Kind
+	for _, k := range s.expected() {
+		if !s.Has(k) {
+			missing = append(missing, k)
+		}
+	}
+	return missing
+}
+
+// Kinds to list for the slide: the expected ones, then others found, then
+// KindOther
+func (s Slide) Kinds() []Kind {
+	kinds := append([]Kind{}, s.expected()...)
+	seen := map[Kind]bool{KindOther: true}
+	for _, k := range kinds {
+		seen[k] = true
+	}
+	for _, a := range s.Artifacts {
+		if !seen[a.Kind] {
+			seen[a.Kind] = true
+			kinds = append(kinds, a.Kind)
+		}
+	}
+	return append(kinds, KindOther)
+}
+
+// LastModified is the latest modification time of the slide's artifacts
+func (s Slide) LastModified() time.Time {
+	var latest time.Time
+	for _, a := range s.Artifacts {
+		if a.ModTime.After(latest) {
+			latest = a.ModTime
+		}
+	}
+	return latest
+}
+
+// Complete reports whether every expected kind is present
+func (s Slide) Complete() bool {
+	return len(s.Missing()) == 0
+}
+
+// Print
+func (s Slide) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Slide: %s/%s\n  Path: %s\n", s.Group, s.Name, s.Path)
+	keys := make([]string, 0, len(s.Fields))
+	for k := range s.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "  %s: %s\n", k, s.Fields[k])
+	}
+
+	for _, k := range s.Kinds() {
+		artifacts := s.ByKind(k)
+		if len(artifacts) == 0 {
+			if k != KindOther {
+				fmt.Fprintf(&b, "  %-11s missing\n", k)
+			}
+			continue
+		}
+		for _, a := range artifacts {
+			fmt.Fprintf(&b, "  %-11s %10d  %s  %s\n", k, a.Size, a.ModTime.Format("2006-01-02 15:04:05"), a.Name)
+		}
+	}
+	return b.String()
+}
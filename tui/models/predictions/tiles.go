@@ -0,0 +1,340 @@
+package predictions
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Defaults for tile score aggregates
+const (
+	DefaultTopK          = 10
+	DefaultTileThreshold = 0.5
+)
+
+// Columns recognized in tile CSVs. Any other numeric column is a score,
+// named after its class without a score prefix.
+var (
+	tileXColumns     = []string{"x", "tile_x", "x_coord", "col", "column", "left"}
+	tileYColumns     = []string{"y", "tile_y", "y_coord", "row", "top"}
+	tileIgnored      = []string{"w", "h", "width", "height", "level", "id", "tile", "tile_id", "index", "idx"}
+	tileScorePrefix  = []string{"score_", "prob_", "probability_", "p_"}
+	tileScoreDefault = "score"
+)
+
+// Tile of a slide and its score per class
+type Tile struct {
+	X      float64   `json:"x" yaml:"x"`
+	Y      float64   `json:"y" yaml:"y"`
+	Scores []float64 `json:"scores" yaml:"scores"`
+}
+
+// TileScores are the tiles of a slide with per-class scores, as written in
+// tiles.csv
+type TileScores struct {
+	Path    string   `json:"path" yaml:"path"`
+	Classes []string `json:"classes" yaml:"classes"`
+	Tiles   []Tile   `json:"-" yaml:"-"`
+
+	// Extent of the tile coordinates, and the distance between neighbouring
+	// tiles
+	MinX, MinY   float64 `json:"-" yaml:"-"`
+	MaxX, MaxY   float64 `json:"-" yaml:"-"`
+	StepX, StepY float64 `json:"-" yaml:"-"`
+}
+
+// TileAggregate sums up the scores of one class over the tiles of a slide
+type TileAggregate struct {
+	Class string  `json:"class" yaml:"class"`
+	Tiles int     `json:"tiles" yaml:"tiles"`
+	Mean  float64 `json:"mean" yaml:"mean"`
+	Max   float64 `json:"max" yaml:"max"`
+
+	// Mean of the K highest scores
+	TopK float64 `json:"top_k" yaml:"top_k"`
+	K    int     `json:"k" yaml:"k"`
+
+	// Fraction of tiles scoring at least Threshold
+	Above     float64 `json:"above" yaml:"above"`
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+}
+
+// ReadTileScores reads a CSV with a header naming x and y columns and one or
+// more score columns. Rows without coordinates are skipped; missing or
+// unparsable scores are NaN.
+func ReadTileScores(path string) (*TileScores, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	header = append([]string(nil), header...)
+	xCol, yCol := tileColumn(header, tileXColumns), tileColumn(header, tileYColumns)
+	if xCol < 0 || yCol < 0 {
+		return nil, fmt.Errorf("%s: need x and y columns, got %v", path, header)
+	}
+
+	// Score columns are decided on the first row, numbers being scores
+	t := &TileScores{Path: path}
+	var scoreCols []int
+	first := true
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if first {
+			first = false
+			for i, h := range header {
+				if i == xCol || i == yCol || i >= len(row) || isTileIgnored(h) {
+					continue
+				}
+				if _, err := strconv.ParseFloat(strings.TrimSpace(row[i]), 64); err == nil {
+					scoreCols = append(scoreCols, i)
+					t.Classes = append(t.Classes, tileClass(h))
+				}
+			}
+			if len(scoreCols) == 0 {
+				return nil, fmt.Errorf("%s: no score columns in %v", path, header)
+			}
+		}
+		if xCol >= len(row) || yCol >= len(row) {
+			continue
+		}
+		x, errX := strconv.ParseFloat(strings.TrimSpace(row[xCol]), 64)
+		y, errY := strconv.ParseFloat(strings.TrimSpace(row[yCol]), 64)
+		if errX != nil || errY != nil {
+			continue
+		}
+		tile := Tile{X: x, Y: y, Scores: make([]float64, len(scoreCols))}
+		for i, c := range scoreCols {
+			tile.Scores[i] = math.NaN()
+			if c < len(row) {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(row[c]), 64); err == nil {
+					tile.Scores[i] = v
+				}
+			}
+		}
+		t.Tiles = append(t.Tiles, tile)
+	}
+	t.extent()
+	return t, nil
+}
+
+// IsTileCSV reports whether path is a CSV of tile scores, named after tiles
+// or with a header naming x and y columns
+func IsTileCSV(path string) bool {
+	if !strings.EqualFold(filepath.Ext(path), ".csv") {
+		return false
+	}
+	if ClassifyArtifact(path) == KindTiles {
+		return true
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	header, err := csv.NewReader(f).Read()
+	if err != nil {
+		return false
+	}
+	return tileColumn(header, tileXColumns) >= 0 && tileColumn(header, tileYColumns) >= 0
+}
+
+// tileColumn returns the index of the first of names in header, -1 if none
+func tileColumn(header, names []string) int {
+	for _, n := range names {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), n) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isTileIgnored(name string) bool {
+	name = strings.TrimSpace(name)
+	for _, n := range tileIgnored {
+		if strings.EqualFold(name, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// tileClass is the class a score column is for, such as tumor for
+// prob_tumor
+func tileClass(name string) string {
+	name = strings.TrimSpace(name)
+	for _, p := range tileScorePrefix {
+		if len(name) > len(p) && strings.EqualFold(name[:len(p)], p) {
+			return name[len(p):]
+		}
+	}
+	if name == "" {
+		return tileScoreDefault
+	}
+	return name
+}
+
+// extent sets the bounds of the coordinates and the tile step, the smallest
+// gap between distinct coordinates
+func (t *TileScores) extent() {
+	if len(t.Tiles) == 0 {
+		return
+	}
+	xs := make([]float64, len(t.Tiles))
+	ys := make([]float64, len(t.Tiles))
+	for i, tile := range t.Tiles {
+		xs[i], ys[i] = tile.X, tile.Y
+	}
+	t.MinX, t.MaxX, t.StepX = spread(xs)
+	t.MinY, t.MaxY, t.StepY = spread(ys)
+}
+
+func spread(vs []float64) (lo, hi, step float64) {
+	sort.Float64s(vs)
+	lo, hi = vs[0], vs[len(vs)-1]
+	for i := 1; i < len(vs); i++ {
+		if gap := vs[i] - vs[i-1]; gap > 0 && (step == 0 || gap < step) {
+			step = gap
+		}
+	}
+	if step == 0 {
+		step = 1
+	}
+	return lo, hi, step
+}
+
+// Class returns the index of a score column by class name, -1 if none
+func (t *TileScores) Class(name string) int {
+	for i, c := range t.Classes {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Aggregate sums up the scores of class, the mean of the k best scores and
+// the fraction of tiles at or above threshold. NaN scores are left out, and
+// everything is 0 without any scores.
+func (t *TileScores) Aggregate(class, k int, threshold float64) TileAggregate {
+	a := TileAggregate{Class: t.Classes[class], Threshold: threshold}
+	scores := make([]float64, 0, len(t.Tiles))
+	for _, tile := range t.Tiles {
+		if v := tile.Scores[class]; !math.IsNaN(v) {
+			scores = append(scores, v)
+		}
+	}
+	a.Tiles = len(scores)
+	if len(scores) == 0 {
+		return a
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(scores)))
+	var sum, top float64
+	above := 0
+	for i, v := range scores {
+		sum += v
+		if i < k {
+			top += v
+		}
+		if v >= threshold {
+			above++
+		}
+	}
+	a.K = k
+	if a.K > len(scores) {
+		a.K = len(scores)
+	}
+	a.Mean = sum / float64(len(scores))
+	a.Max = scores[0]
+	if a.K > 0 {
+		a.TopK = top / float64(a.K)
+	}
+	a.Above = float64(above) / float64(len(scores))
+	return a
+}
+
+// Aggregates returns the aggregate of every class
+func (t *TileScores) Aggregates(k int, threshold float64) []TileAggregate {
+	out := make([]TileAggregate, len(t.Classes))
+	for i := range t.Classes {
+		out[i] = t.Aggregate(i, k, threshold)
+	}
+	return out
+}
+
+// Grid bins the tiles into cols by rows cells of the extent, returning the
+// mean score of class per cell row by row, NaN where there is no tile.
+// Cells are as coarse as needed for the slide to fit.
+func (t *TileScores) Grid(class, cols, rows int) []float64 {
+	grid := make([]float64, cols*rows)
+	counts := make([]int, cols*rows)
+	across, down := t.Size()
+	for _, tile := range t.Tiles {
+		v := tile.Scores[class]
+		if math.IsNaN(v) {
+			continue
+		}
+		c := int(math.Round((tile.X-t.MinX)/t.StepX)) * cols / across
+		r := int(math.Round((tile.Y-t.MinY)/t.StepY)) * rows / down
+		if c < 0 || c >= cols || r < 0 || r >= rows {
+			continue
+		}
+		grid[r*cols+c] += v
+		counts[r*cols+c]++
+	}
+	for i := range grid {
+		if counts[i] == 0 {
+			grid[i] = math.NaN()
+		} else {
+			grid[i] /= float64(counts[i])
+		}
+	}
+	return grid
+}
+
+// Size is the number of tiles across and down the extent
+func (t *TileScores) Size() (int, int) {
+	if len(t.Tiles) == 0 {
+		return 0, 0
+	}
+	return int(math.Round((t.MaxX-t.MinX)/t.StepX)) + 1, int(math.Round((t.MaxY-t.MinY)/t.StepY)) + 1
+}
+
+// TileScores reads the first tiles artifact of the slide, preferring one
+// named tiles.csv
+func (s Slide) TileScores() (*TileScores, error) {
+	artifacts := s.ByKind(KindTiles)
+	if len(artifacts) == 0 {
+		return nil, fmt.Errorf("%s/%s: no tiles", s.Group, s.Name)
+	}
+	best := artifacts[0]
+	for _, a := range artifacts {
+		if strings.EqualFold(filepath.Base(a.Name), "tiles.csv") {
+			best = a
+			break
+		}
+	}
+	return ReadTileScores(filepath.Join(s.Path, filepath.FromSlash(best.Name)))
+}
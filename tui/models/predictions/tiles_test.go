@@ -0,0 +1,173 @@
+package predictions
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadTileScores(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		classes []string
+		tiles   []Tile
+		wantErr bool
+	}{
+		{
+			name:    "single score",
+			csv:     "x,y,score\n0,0,0.1\n256,0,0.9\n",
+			classes: []string{"score"},
+			tiles:   []Tile{{X: 0, Y: 0, Scores: []float64{0.1}}, {X: 256, Y: 0, Scores: []float64{0.9}}},
+		},
+		{
+			name:    "classes by prefix, ignored and text columns",
+			csv:     "tile_id,Row,Col,width,prob_tumor,Score_Stroma,label\n1,2,3,256,0.7,0.2,tumor\n",
+			classes: []string{"tumor", "Stroma"},
+			tiles:   []Tile{{X: 3, Y: 2, Scores: []float64{0.7, 0.2}}},
+		},
+		{
+			name:    "missing scores and coordinates",
+			csv:     "x,y,a,b\n0,0,0.5,0.5\n1,1,,x\n,2,0.5,0.5\n3\n",
+			classes: []string{"a", "b"},
+			tiles:   []Tile{{X: 0, Y: 0, Scores: []float64{0.5, 0.5}}, {X: 1, Y: 1, Scores: []float64{math.NaN(), math.NaN()}}},
+		},
+		{
+			name:    "no coordinates",
+			csv:     "slide,score\ns1,0.5\n",
+			wantErr: true,
+		},
+		{
+			name:    "no scores",
+			csv:     "x,y,label\n0,0,tumor\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"tiles.csv": tt.csv})
+			ts, err := ReadTileScores(filepath.Join(dir, "tiles.csv"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(ts.Classes, tt.classes) {
+				t.Errorf("classes %v, want %v", ts.Classes, tt.classes)
+			}
+			if len(ts.Tiles) != len(tt.tiles) {
+				t.Fatalf("%d tiles, want %d", len(ts.Tiles), len(tt.tiles))
+			}
+			for i, got := range ts.Tiles {
+				want := tt.tiles[i]
+				if got.X != want.X || got.Y != want.Y || !sameScores(got.Scores, want.Scores) {
+					t.Errorf("tile %d is %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsTileCSV(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tiles.csv":       "anything\n",
+		"patches.csv":     "X,Y,prob\n0,0,1\n",
+		"labels.csv":      "slide,label\ns1,tumor\n",
+		"coords.txt":      "x,y,score\n",
+		"tile_scores.CSV": "",
+		"empty.csv":       "",
+	})
+	tests := map[string]bool{
+		"tiles.csv": true, "patches.csv": true, "labels.csv": false,
+		"coords.txt": false, "tile_scores.CSV": true, "empty.csv": false, "missing.csv": false,
+	}
+	for name, want := range tests {
+		if got := IsTileCSV(filepath.Join(dir, name)); got != want {
+			t.Errorf("IsTileCSV(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestTileAggregate(t *testing.T) {
+	ts := &TileScores{Classes: []string{"tumor", "empty"}}
+	for _, v := range []float64{0.9, 0.1, 0.5, math.NaN(), 0.7} {
+		ts.Tiles = append(ts.Tiles, Tile{Scores: []float64{v, math.NaN()}})
+	}
+
+	tests := []struct {
+		name      string
+		class, k  int
+		threshold float64
+		want      TileAggregate
+	}{
+		{
+			name: "top 2", class: 0, k: 2, threshold: 0.5,
+			want: TileAggregate{Class: "tumor", Tiles: 4, Mean: 0.55, Max: 0.9, TopK: 0.8, K: 2, Above: 0.75, Threshold: 0.5},
+		},
+		{
+			name: "k beyond tiles", class: 0, k: 10, threshold: 0.8,
+			want: TileAggregate{Class: "tumor", Tiles: 4, Mean: 0.55, Max: 0.9, TopK: 0.55, K: 4, Above: 0.25, Threshold: 0.8},
+		},
+		{
+			name: "no scores", class: 1, k: 2, threshold: 0.5,
+			want: TileAggregate{Class: "empty", Threshold: 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ts.Aggregate(tt.class, tt.k, tt.threshold)
+			w := tt.want
+			if got.Class != w.Class || got.Tiles != w.Tiles || got.K != w.K || got.Threshold != w.Threshold ||
+				!near(got.Mean, w.Mean) || !near(got.Max, w.Max) || !near(got.TopK, w.TopK) || !near(got.Above, w.Above) {
+				t.Errorf("aggregate %+v, want %+v", got, w)
+			}
+		})
+	}
+}
+
+func TestTileGrid(t *testing.T) {
+	// Three tiles across and two down, 256 pixels apart, one missing
+	dir := writeFiles(t, map[string]string{"tiles.csv": "x,y,score\n" +
+		"1000,500,0.1\n1256,500,0.2\n1512,500,0.3\n" +
+		"1000,756,0.4\n1512,756,0.6\n"})
+	ts, err := ReadTileScores(filepath.Join(dir, "tiles.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if across, down := ts.Size(); across != 3 || down != 2 {
+		t.Fatalf("size %dx%d, want 3x2", across, down)
+	}
+
+	nan := math.NaN()
+	tests := []struct {
+		name       string
+		cols, rows int
+		want       []float64
+	}{
+		{"one cell per tile", 3, 2, []float64{0.1, 0.2, 0.3, 0.4, nan, 0.6}},
+		{"coarser", 1, 2, []float64{0.2, 0.5}},
+		{"everything", 1, 1, []float64{0.32}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ts.Grid(0, tt.cols, tt.rows); !sameScores(got, tt.want) {
+				t.Errorf("grid %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// sameScores compares scores, NaN being equal to NaN
+func sameScores(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.IsNaN(a[i]) != math.IsNaN(b[i]) || (!math.IsNaN(a[i]) && !near(a[i], b[i])) {
+			return false
+		}
+	}
+	return true
+}
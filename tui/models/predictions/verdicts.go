@@ -0,0 +1,250 @@
+package predictions
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// VerdictsFile holds the reviews of a datadir, inside it
+const VerdictsFile = ".verdicts.json"
+
+// Verdict of a reviewer on a slide
+type Verdict string
+
+const (
+	VerdictAccepted    Verdict = "accepted"
+	VerdictRejected    Verdict = "rejected"
+	VerdictNeedsReview Verdict = "needs-review"
+)
+
+// Verdicts a slide can be given, in order
+var Verdicts = []Verdict{VerdictAccepted, VerdictRejected, VerdictNeedsReview}
+
+// ParseVerdict accepts a verdict by name
+func ParseVerdict(s string) (Verdict, error) {
+	for _, v := range Verdicts {
+		if string(v) == s {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("unknown verdict %q, expected accepted, rejected or needs-review", s)
+}
+
+// Review is the verdict and note given to a slide
+type Review struct {
+	Group    string    `json:"group" yaml:"group"`
+	Slide    string    `json:"slide" yaml:"slide"`
+	Verdict  Verdict   `json:"verdict" yaml:"verdict"`
+	Note     string    `json:"note,omitempty" yaml:"note,omitempty"`
+	Reviewer string    `json:"reviewer,omitempty" yaml:"reviewer,omitempty"`
+	Time     time.Time `json:"time" yaml:"time"`
+}
+
+// Bumped whenever the verdicts file changes incompatibly
+const verdictsVersion = 1
+
+// How long to wait for another session's lock on the verdicts file, and when
+// a lock left behind by a crash is taken over
+const (
+	verdictsLockWait  = 5 * time.Second
+	verdictsLockStale = 30 * time.Second
+)
+
+type verdictsDoc struct {
+	Version int      `json:"version"`
+	Reviews []Review `json:"reviews"`
+}
+
+// VerdictsPath is where the reviews of Datadir are stored
+func (m *Predictions) VerdictsPath() string {
+	return filepath.Join(m.Datadir, VerdictsFile)
+}
+
+// LoadVerdicts replaces Reviews with the verdicts file, leaving them empty
+// when there is none
+func (m *Predictions) LoadVerdicts() error {
+	reviews, err := readVerdicts(m.VerdictsPath())
+	m.Reviews = reviews
+	return err
+}
+
+// Review of a slide, if it has one
+func (m *Predictions) Review(group, slide string) (Review, bool) {
+	r, ok := m.Reviews[group+"/"+slide]
+	return r, ok
+}
+
+// SetReview records a verdict and note for a slide and saves the verdicts
+// file. Reviews saved meanwhile by another session are kept, so two
+// reviewers can work on different slides at once. An empty verdict removes
+// the review.
+func (m *Predictions) SetReview(group, slide string, verdict Verdict, note string) error {
+	return m.SetReviews([]Review{{Group: group, Slide: slide, Verdict: verdict, Note: note}})
+}
+
+// SetReviews records several reviews at once, filling in the reviewer and
+// time. The verdicts file is locked while it is read again and merged.
+func (m *Predictions) SetReviews(reviews []Review) error {
+	unlock, err := lockVerdicts(m.VerdictsPath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := readVerdicts(m.VerdictsPath())
+	if err != nil {
+		return err
+	}
+	reviewer := reviewerName()
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, r := range reviews {
+		key := r.Group + "/" + r.Slide
+		if r.Verdict == "" {
+			delete(current, key)
+			continue
+		}
+		r.Reviewer, r.Time = reviewer, now
+		current[key] = r
+	}
+	if err := writeVerdicts(m.VerdictsPath(), current); err != nil {
+		return err
+	}
+	m.Reviews = current
+	return nil
+}
+
+// SortedReviews returns Reviews by group then slide
+func (m *Predictions) SortedReviews() []Review {
+	return sortedReviews(m.Reviews)
+}
+
+func sortedReviews(reviews map[string]Review) []Review {
+	out := make([]Review, 0, len(reviews))
+	for _, r := range reviews {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Group != out[j].Group {
+			return out[i].Group < out[j].Group
+		}
+		return out[i].Slide < out[j].Slide
+	})
+	return out
+}
+
+// readVerdicts reads the reviews at path by group/slide, none if it does not
+// exist
+func readVerdicts(path string) (map[string]Review, error) {
+	reviews := make(map[string]Review)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return reviews, nil
+	}
+	if err != nil {
+		return reviews, err
+	}
+	defer f.Close()
+
+	var doc verdictsDoc
+	if err := json.NewDecoder(bufio.NewReader(f)).Decode(&doc); err != nil {
+		return reviews, fmt.Errorf("%s: %w", path, err)
+	}
+	if doc.Version != verdictsVersion {
+		return reviews, fmt.Errorf("%s: unsupported version %d", path, doc.Version)
+	}
+	for _, r := range doc.Reviews {
+		reviews[r.Group+"/"+r.Slide] = r
+	}
+	return reviews, nil
+}
+
+// lockVerdicts creates the lock file next to path, waiting while another
+// session holds it, and returns the function releasing it
+func lockVerdicts(path string) (func(), error) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(verdictsLockWait)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > verdictsLockStale {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another session", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// writeVerdicts writes through a temporary file so a crash never loses the
+// reviews already saved
+func writeVerdicts(path string, reviews map[string]Review) error {
+	doc := verdictsDoc{Version: verdictsVersion, Reviews: sortedReviews(reviews)}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	// Readable by the other reviewers, as os.Create would leave it
+	err = f.Chmod(0o644)
+	if err == nil {
+		err = enc.Encode(doc)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// reviewerName is the login of the current user
+func reviewerName() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// WriteReviewsCSV writes reviews with a header, one row per review
+func WriteReviewsCSV(w io.Writer, reviews []Review) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"group", "slide", "verdict", "note", "reviewer", "time"})
+	for _, r := range reviews {
+		t := ""
+		if !r.Time.IsZero() {
+			t = r.Time.Format(time.RFC3339)
+		}
+		out.Write([]string{r.Group, r.Slide, string(r.Verdict), r.Note, r.Reviewer, t})
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,154 @@
+package pyramid
+
+import (
+	"container/list"
+	"image"
+	"sync"
+)
+
+// TileKey identifies a tile of a pyramid
+type TileKey struct {
+	Level, Col, Row int
+}
+
+type cached struct {
+	key TileKey
+	img image.Image
+	err error
+}
+
+// Cache keeps the most recently used tiles of a pyramid in memory and loads
+// the ones asked for with Want in the background
+type Cache struct {
+	Source   Pyramid
+	Capacity int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	tiles   map[TileKey]*list.Element
+	lru     *list.List
+	wanted  []TileKey
+	loading map[TileKey]bool
+	closed  bool
+
+	// Tiles being read from Source, which Close waits for
+	reading sync.WaitGroup
+
+	// Called from a loader goroutine after each tile loads
+	loaded func()
+}
+
+// NewCache keeps up to capacity tiles of p, loading with workers goroutines
+// and calling loaded after each tile
+func NewCache(p Pyramid, capacity, workers int, loaded func()) *Cache {
+	c := &Cache{
+		Source:   p,
+		Capacity: capacity,
+		tiles:    make(map[TileKey]*list.Element),
+		lru:      list.New(),
+		loading:  make(map[TileKey]bool),
+		loaded:   loaded,
+	}
+	c.cond = sync.NewCond(&c.mu)
+	for i := 0; i < workers; i++ {
+		go c.work()
+	}
+	return c
+}
+
+// Get returns a loaded tile and whether it was loaded, with the error of
+// tiles that could not be read
+func (c *Cache) Get(k TileKey) (image.Image, error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.tiles[k]
+	if !ok {
+		return nil, nil, false
+	}
+	c.lru.MoveToFront(e)
+	t := e.Value.(*cached)
+	return t.img, t.err, true
+}
+
+// Want replaces the tiles to load, most needed first. Tiles wanted before
+// and not any more are dropped unless already loading.
+func (c *Cache) Want(keys []TileKey) {
+	c.mu.Lock()
+	c.wanted = append(c.wanted[:0], keys...)
+	c.mu.Unlock()
+	c.cond.Broadcast()
+}
+
+// Stats returns the number of tiles in memory and waiting to load
+func (c *Cache) Stats() (loaded, pending int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range c.wanted {
+		if _, ok := c.tiles[k]; !ok {
+			pending++
+		}
+	}
+	return c.lru.Len(), pending
+}
+
+// Close stops the loaders and closes the pyramid once no tile is being read
+// from it
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.cond.Broadcast()
+	c.reading.Wait()
+	return c.Source.Close()
+}
+
+func (c *Cache) work() {
+	for {
+		c.mu.Lock()
+		k, ok := c.next()
+		for !ok && !c.closed {
+			c.cond.Wait()
+			k, ok = c.next()
+		}
+		if c.closed {
+			c.mu.Unlock()
+			return
+		}
+		c.loading[k] = true
+		c.reading.Add(1)
+		c.mu.Unlock()
+
+		img, err := c.Source.Tile(k.Level, k.Col, k.Row)
+		c.reading.Done()
+
+		c.mu.Lock()
+		delete(c.loading, k)
+		if c.closed {
+			c.mu.Unlock()
+			return
+		}
+		c.tiles[k] = c.lru.PushFront(&cached{k, img, err})
+		for c.lru.Len() > c.Capacity {
+			last := c.lru.Back()
+			delete(c.tiles, last.Value.(*cached).key)
+			c.lru.Remove(last)
+		}
+		c.mu.Unlock()
+		if c.loaded != nil {
+			c.loaded()
+		}
+	}
+}
+
+// next pops the first wanted tile that is neither loaded nor loading
+func (c *Cache) next() (TileKey, bool) {
+	for len(c.wanted) > 0 {
+		k := c.wanted[0]
+		c.wanted = c.wanted[1:]
+		if _, ok := c.tiles[k]; ok || c.loading[k] {
+			continue
+		}
+		return k, true
+	}
+	return TileKey{}, false
+}
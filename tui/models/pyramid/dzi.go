@@ -0,0 +1,144 @@
+package pyramid
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// dziPyramid reads a DeepZoom image: a .dzi descriptor and a folder
+// <name>_files with one folder per level, of tiles <col>_<row>.<format>.
+// DeepZoom numbers levels from 1x1 pixel up, level 0 here is the largest.
+type dziPyramid struct {
+	dir     string
+	format  string
+	overlap int
+	top     int
+	levels  []Level
+}
+
+type dziImage struct {
+	TileSize int    `xml:"TileSize,attr"`
+	Overlap  int    `xml:"Overlap,attr"`
+	Format   string `xml:"Format,attr"`
+	Size     struct {
+		Width  int `xml:"Width,attr"`
+		Height int `xml:"Height,attr"`
+	} `xml:"Size"`
+}
+
+// OpenDZI reads the descriptor of a DeepZoom image
+func OpenDZI(path string) (Pyramid, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var desc dziImage
+	if err := xml.Unmarshal(data, &desc); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	w, h := desc.Size.Width, desc.Size.Height
+	if w <= 0 || h <= 0 || desc.TileSize <= 0 {
+		return nil, fmt.Errorf("%s: missing image or tile size", filepath.Base(path))
+	}
+	d := &dziPyramid{
+		dir:     strings.TrimSuffix(path, filepath.Ext(path)) + "_files",
+		format:  desc.Format,
+		overlap: desc.Overlap,
+		top:     int(math.Ceil(math.Log2(float64(maxInt(w, h))))),
+	}
+	if d.format == "" {
+		d.format = "jpeg"
+	}
+	if _, err := os.Stat(d.dir); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	// Halve down to the first level held in one tile
+	for i := 0; i <= d.top; i++ {
+		scale := math.Pow(2, float64(i))
+		l := Level{
+			Width:      int(math.Ceil(float64(w) / scale)),
+			Height:     int(math.Ceil(float64(h) / scale)),
+			TileWidth:  desc.TileSize,
+			TileHeight: desc.TileSize,
+			Downsample: scale,
+		}
+		d.levels = append(d.levels, l)
+		if l.Width <= desc.TileSize && l.Height <= desc.TileSize {
+			break
+		}
+	}
+	return d, nil
+}
+
+func (d *dziPyramid) Levels() []Level { return d.levels }
+
+func (d *dziPyramid) Describe() string {
+	l := d.levels[0]
+	return fmt.Sprintf("DeepZoom, %s, %dx%d tiles, %d levels", d.format, l.TileWidth, l.TileHeight, len(d.levels))
+}
+
+func (d *dziPyramid) Close() error { return nil }
+
+// Tile crops the overlap DeepZoom adds to tiles on sides with a neighbour
+func (d *dziPyramid) Tile(level, col, row int) (image.Image, error) {
+	if level < 0 || level >= len(d.levels) {
+		return nil, fmt.Errorf("no level %d", level)
+	}
+	l := d.levels[level]
+	name := strconv.Itoa(col) + "_" + strconv.Itoa(row) + "." + d.format
+	f, err := os.Open(filepath.Join(d.dir, strconv.Itoa(d.top-level), name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	b := img.Bounds()
+	min := b.Min
+	if col > 0 {
+		min.X += d.overlap
+	}
+	if row > 0 {
+		min.Y += d.overlap
+	}
+	w := minInt(l.TileWidth, l.Width-col*l.TileWidth)
+	h := minInt(l.TileHeight, l.Height-row*l.TileHeight)
+	crop := image.Rectangle{min, min.Add(image.Pt(w, h))}.Intersect(b)
+	if crop.Empty() {
+		return nil, errors.New(name + ": tile smaller than its overlap")
+	}
+	sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		return img, nil
+	}
+	return sub.SubImage(crop), nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,74 @@
+package pyramid
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+)
+
+// Level of a pyramid, level 0 being the full resolution
+type Level struct {
+	Width  int
+	Height int
+
+	// Size of every tile but those cut by the right and bottom edges
+	TileWidth  int
+	TileHeight int
+
+	// Full resolution pixels per pixel of this level
+	Downsample float64
+}
+
+// Across and Down are the number of tile columns and rows
+func (l Level) Across() int { return (l.Width + l.TileWidth - 1) / l.TileWidth }
+func (l Level) Down() int   { return (l.Height + l.TileHeight - 1) / l.TileHeight }
+
+// Pyramid is an image stored as tiles at several resolutions, read a tile
+// at a time
+type Pyramid interface {
+	// Levels from full resolution down
+	Levels() []Level
+
+	// Tile at col, row of a level. Pixel x, y of the tile is at Bounds().Min
+	// plus x, y of the returned image, which may be smaller than the tile
+	// size at the edges. Safe to call from several goroutines.
+	Tile(level, col, row int) (image.Image, error)
+
+	// Describe summarizes the format, such as "TIFF, jpeg, 3 levels"
+	Describe() string
+
+	Close() error
+}
+
+// Extensions opened as pyramids
+var pyramidExts = map[string]bool{".tif": true, ".tiff": true, ".svs": true, ".dzi": true}
+
+// IsPyramid reports whether path is a TIFF or DeepZoom image
+func IsPyramid(path string) bool {
+	return pyramidExts[strings.ToLower(filepath.Ext(path))]
+}
+
+// Open reads the levels of a TIFF, its reduced resolutions given as further
+// images or SubIFDs, or of a DeepZoom .dzi with its tile folder
+func Open(path string) (Pyramid, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".dzi":
+		return OpenDZI(path)
+	case ".tif", ".tiff", ".svs":
+		return OpenTIFF(path)
+	}
+	return nil, fmt.Errorf("%s: not a TIFF or DeepZoom image", filepath.Base(path))
+}
+
+// BestLevel returns the smallest level with at least one pixel per
+// full-resolution pixels per screen pixel, so zooming out reads fewer tiles
+func BestLevel(levels []Level, downsample float64) int {
+	best := 0
+	for i, l := range levels {
+		if l.Downsample <= downsample*1.01 {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,123 @@
+package pyramid
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/image/tiff"
+)
+
+// writeTIFF encodes a gray gradient as a single image stripped TIFF
+func writeTIFF(t *testing.T, w, h int) string {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8(x + y)})
+		}
+	}
+	path := filepath.Join(t.TempDir(), "slide.tif")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := tiff.Encode(f, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenTIFF(t *testing.T) {
+	tests := []struct {
+		name    string
+		w, h    int
+		wantErr bool
+	}{
+		{"small", 64, 48, false},
+		{"wide strip", 20000, 2, false},
+		{"tall strip", 1, maxTileSide + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Open(writeTIFF(t, tt.w, tt.h))
+			if tt.wantErr {
+				if err == nil {
+					p.Close()
+					t.Fatal("opened, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.Close()
+
+			l := p.Levels()[0]
+			if l.Width != tt.w || l.Height != tt.h {
+				t.Fatalf("level 0 is %dx%d, want %dx%d", l.Width, l.Height, tt.w, tt.h)
+			}
+			tile, err := p.Tile(0, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := tile.Bounds()
+			if got := color.GrayModel.Convert(tile.At(b.Min.X+1, b.Min.Y+1)).(color.Gray).Y; got != 2 {
+				t.Errorf("pixel 1,1 is %d, want 2", got)
+			}
+		})
+	}
+}
+
+// slowPyramid takes a while per tile and fails tiles read after Close
+type slowPyramid struct {
+	mu     sync.Mutex
+	closed bool
+	late   int
+}
+
+func (p *slowPyramid) Levels() []Level {
+	return []Level{{Width: 256, Height: 256, TileWidth: 16, TileHeight: 16, Downsample: 1}}
+}
+func (p *slowPyramid) Describe() string { return "slow" }
+func (p *slowPyramid) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+func (p *slowPyramid) Tile(level, col, row int) (image.Image, error) {
+	time.Sleep(5 * time.Millisecond)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		p.late++
+		return nil, errors.New("closed")
+	}
+	return image.NewGray(image.Rect(0, 0, 16, 16)), nil
+}
+
+func TestCacheClose(t *testing.T) {
+	p := &slowPyramid{}
+	c := NewCache(p, 64, 4, nil)
+	var keys []TileKey
+	for i := 0; i < 16; i++ {
+		keys = append(keys, TileKey{Col: i})
+	}
+	c.Want(keys)
+	time.Sleep(time.Millisecond)
+
+	// No tile is read from a closed pyramid
+	c.Close()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.late > 0 {
+		t.Errorf("%d tiles read after Close", p.late)
+	}
+}
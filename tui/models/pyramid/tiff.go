@@ -0,0 +1,661 @@
+package pyramid
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/image/tiff/lzw"
+)
+
+// TIFF tags read
+const (
+	tagNewSubfileType  = 254
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagPhotometric     = 262
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagPlanarConfig    = 284
+	tagPredictor       = 317
+	tagColorMap        = 320
+	tagTileWidth       = 322
+	tagTileLength      = 323
+	tagTileOffsets     = 324
+	tagTileByteCounts  = 325
+	tagSubIFDs         = 330
+	tagExtraSamples    = 338
+	tagSampleFormat    = 339
+	tagJPEGTables      = 347
+)
+
+// Compressions decoded
+const (
+	compressionNone     = 1
+	compressionLZW      = 5
+	compressionJPEG     = 7
+	compressionDeflate  = 8
+	compressionPackBits = 32773
+	compressionZlib     = 32946
+)
+
+var compressionNames = map[int]string{
+	compressionNone:     "uncompressed",
+	compressionLZW:      "lzw",
+	compressionJPEG:     "jpeg",
+	compressionDeflate:  "deflate",
+	compressionPackBits: "packbits",
+	compressionZlib:     "deflate",
+}
+
+// Photometric interpretations
+const (
+	photometricWhiteIsZero = 0
+	photometricBlackIsZero = 1
+	photometricRGB         = 2
+	photometricPalette     = 3
+	photometricYCbCr       = 6
+)
+
+// Largest tile or strip read or decoded, a guard against corrupt byte
+// counts and sizes
+const maxTileBytes = 256 << 20
+
+// Largest tile width or height, and strip height
+const maxTileSide = 16384
+
+// Levels whose aspect ratio differs more from the full resolution are
+// attached images such as slide labels, not reductions
+const aspectTolerance = 0.05
+
+// tiffPyramid reads the tiles, or strips, of the levels of a TIFF. The
+// levels are the images of the file and their SubIFDs with the aspect ratio
+// of the largest one, as written by slide scanners, libvips and OME tools.
+type tiffPyramid struct {
+	path   string
+	f      *os.File
+	order  binary.ByteOrder
+	big    bool
+	levels []Level
+	images []*tiffImage
+}
+
+type tiffImage struct {
+	width, height  int
+	tileW, tileH   int
+	tiled          bool
+	offsets        []uint64
+	counts         []uint64
+	bits           int
+	samples        int
+	float          bool
+	compression    int
+	photometric    int
+	predictor      int
+	associated     bool
+	colormap       []uint64
+	jpegTables     []byte
+	reducedOrMask  bool
+	planarSeparate bool
+}
+
+type ifdEntry struct {
+	typ   uint16
+	count uint64
+	data  []byte
+}
+
+// OpenTIFF reads the structure of a TIFF or BigTIFF, leaving the pixels on
+// disk until tiles are asked for
+func OpenTIFF(path string) (Pyramid, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &tiffPyramid{path: path, f: f}
+	if err := t.readImages(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return t, nil
+}
+
+func (t *tiffPyramid) readImages() error {
+	header := make([]byte, 16)
+	if _, err := t.f.ReadAt(header, 0); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	switch string(header[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return errors.New("not a TIFF file")
+	}
+	var next uint64
+	switch t.order.Uint16(header[2:]) {
+	case 42:
+		next = uint64(t.order.Uint32(header[4:]))
+	case 43:
+		t.big = true
+		next = t.order.Uint64(header[8:])
+	default:
+		return errors.New("not a TIFF file")
+	}
+
+	var all []*tiffImage
+	seen := make(map[uint64]bool)
+	var subs []uint64
+	for next != 0 && !seen[next] && len(seen) < 1000 {
+		seen[next] = true
+		entries, n, err := t.readIFD(next)
+		if err != nil {
+			if len(all) == 0 {
+				return err
+			}
+			break
+		}
+		next = n
+		img, err := t.parseImage(entries)
+		if err != nil {
+			if len(all) == 0 {
+				return err
+			}
+			continue
+		}
+		all = append(all, img)
+		if e, ok := entries[tagSubIFDs]; ok {
+			subs = append(subs, t.uints(e)...)
+		}
+	}
+	for _, off := range subs {
+		if seen[off] {
+			continue
+		}
+		seen[off] = true
+		if entries, _, err := t.readIFD(off); err == nil {
+			if img, err := t.parseImage(entries); err == nil {
+				all = append(all, img)
+			}
+		}
+	}
+	if len(all) == 0 {
+		return errors.New("no images")
+	}
+
+	// Reductions of the first image, largest first, one per width
+	base := all[0]
+	aspect := float64(base.width) / float64(base.height)
+	var images []*tiffImage
+	for i, img := range all {
+		a := float64(img.width) / float64(img.height)
+		if i > 0 && (img.width > base.width || img.reducedOrMask && img.width == base.width ||
+			math.Abs(a-aspect)/aspect > aspectTolerance) {
+			continue
+		}
+		images = append(images, img)
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		if images[i].width != images[j].width {
+			return images[i].width > images[j].width
+		}
+		return images[i].tiled && !images[j].tiled
+	})
+	for _, img := range images {
+		if n := len(t.images); n > 0 && t.images[n-1].width == img.width {
+			continue
+		}
+		t.images = append(t.images, img)
+		t.levels = append(t.levels, Level{
+			Width:      img.width,
+			Height:     img.height,
+			TileWidth:  img.tileW,
+			TileHeight: img.tileH,
+			Downsample: float64(base.width) / float64(img.width),
+		})
+	}
+	return nil
+}
+
+// readIFD returns the entries of the IFD at off by tag and the offset of the
+// next IFD
+func (t *tiffPyramid) readIFD(off uint64) (map[uint16]ifdEntry, uint64, error) {
+	countSize, entrySize, inline := 2, 12, 4
+	if t.big {
+		countSize, entrySize, inline = 8, 20, 8
+	}
+	head := make([]byte, countSize)
+	if _, err := t.f.ReadAt(head, int64(off)); err != nil {
+		return nil, 0, fmt.Errorf("IFD at %d: %w", off, err)
+	}
+	var n uint64
+	if t.big {
+		n = t.order.Uint64(head)
+	} else {
+		n = uint64(t.order.Uint16(head))
+	}
+	if n > 4096 {
+		return nil, 0, fmt.Errorf("IFD at %d: %d entries", off, n)
+	}
+	buf := make([]byte, int(n)*entrySize+inline)
+	if _, err := t.f.ReadAt(buf, int64(off)+int64(countSize)); err != nil {
+		return nil, 0, fmt.Errorf("IFD at %d: %w", off, err)
+	}
+
+	entries := make(map[uint16]ifdEntry, n)
+	for i := 0; i < int(n); i++ {
+		b := buf[i*entrySize : (i+1)*entrySize]
+		tag, typ := t.order.Uint16(b), t.order.Uint16(b[2:])
+		var count uint64
+		var value []byte
+		if t.big {
+			count, value = t.order.Uint64(b[4:]), b[12:20]
+		} else {
+			count, value = uint64(t.order.Uint32(b[4:])), b[8:12]
+		}
+		size := typeSize(typ)
+		if size == 0 {
+			continue
+		}
+		length := count * uint64(size)
+		if length > maxTileBytes {
+			return nil, 0, fmt.Errorf("IFD at %d: tag %d too long", off, tag)
+		}
+		data := make([]byte, length)
+		if length <= uint64(inline) {
+			copy(data, value)
+		} else {
+			at := uint64(t.order.Uint32(value))
+			if t.big {
+				at = t.order.Uint64(value)
+			}
+			if _, err := t.f.ReadAt(data, int64(at)); err != nil {
+				return nil, 0, fmt.Errorf("tag %d: %w", tag, err)
+			}
+		}
+		entries[tag] = ifdEntry{typ, count, data}
+	}
+
+	tail := buf[int(n)*entrySize:]
+	if t.big {
+		return entries, t.order.Uint64(tail), nil
+	}
+	return entries, uint64(t.order.Uint32(tail)), nil
+}
+
+// typeSize is the size in bytes of a TIFF field type, 0 if unknown
+func typeSize(typ uint16) int {
+	switch typ {
+	case 1, 2, 6, 7:
+		return 1
+	case 3, 8:
+		return 2
+	case 4, 9, 11, 13:
+		return 4
+	case 5, 10, 12, 16, 17, 18:
+		return 8
+	}
+	return 0
+}
+
+// uints decodes the integer values of an entry
+func (t *tiffPyramid) uints(e ifdEntry) []uint64 {
+	out := make([]uint64, 0, e.count)
+	for i := 0; i < int(e.count); i++ {
+		switch typeSize(e.typ) {
+		case 1:
+			out = append(out, uint64(e.data[i]))
+		case 2:
+			out = append(out, uint64(t.order.Uint16(e.data[i*2:])))
+		case 4:
+			out = append(out, uint64(t.order.Uint32(e.data[i*4:])))
+		case 8:
+			out = append(out, t.order.Uint64(e.data[i*8:]))
+		}
+	}
+	return out
+}
+
+// uint returns the first value of tag, or def when missing
+func (t *tiffPyramid) uint(entries map[uint16]ifdEntry, tag uint16, def int) int {
+	if e, ok := entries[tag]; ok {
+		if v := t.uints(e); len(v) > 0 {
+			return int(v[0])
+		}
+	}
+	return def
+}
+
+func (t *tiffPyramid) parseImage(entries map[uint16]ifdEntry) (*tiffImage, error) {
+	img := &tiffImage{
+		width:          t.uint(entries, tagImageWidth, 0),
+		height:         t.uint(entries, tagImageLength, 0),
+		bits:           t.uint(entries, tagBitsPerSample, 1),
+		samples:        t.uint(entries, tagSamplesPerPixel, 1),
+		float:          t.uint(entries, tagSampleFormat, 1) == 3,
+		compression:    t.uint(entries, tagCompression, compressionNone),
+		photometric:    t.uint(entries, tagPhotometric, photometricBlackIsZero),
+		predictor:      t.uint(entries, tagPredictor, 1),
+		associated:     t.uint(entries, tagExtraSamples, 0) == 1,
+		reducedOrMask:  t.uint(entries, tagNewSubfileType, 0)&5 != 0,
+		planarSeparate: t.uint(entries, tagPlanarConfig, 1) == 2,
+	}
+	if img.width <= 0 || img.height <= 0 {
+		return nil, errors.New("image without size")
+	}
+	if e, ok := entries[tagColorMap]; ok {
+		img.colormap = t.uints(e)
+	}
+	if e, ok := entries[tagJPEGTables]; ok {
+		img.jpegTables = e.data
+	}
+
+	if _, ok := entries[tagTileOffsets]; ok {
+		img.tiled = true
+		img.tileW = t.uint(entries, tagTileWidth, 0)
+		img.tileH = t.uint(entries, tagTileLength, 0)
+		img.offsets = t.uints(entries[tagTileOffsets])
+		img.counts = t.uints(entries[tagTileByteCounts])
+	} else {
+		img.tileW = img.width
+		img.tileH = t.uint(entries, tagRowsPerStrip, img.height)
+		if img.tileH > img.height {
+			img.tileH = img.height
+		}
+		img.offsets = t.uints(entries[tagStripOffsets])
+		img.counts = t.uints(entries[tagStripByteCounts])
+	}
+	if img.tileW <= 0 || img.tileH <= 0 {
+		return nil, errors.New("image without tile size")
+	}
+	if img.tileH > maxTileSide || (img.tiled && img.tileW > maxTileSide) {
+		return nil, fmt.Errorf("%dx%d tiles are too large", img.tileW, img.tileH)
+	}
+	across := (img.width + img.tileW - 1) / img.tileW
+	down := (img.height + img.tileH - 1) / img.tileH
+	if len(img.offsets) < across*down || len(img.counts) < across*down {
+		return nil, errors.New("missing tile offsets")
+	}
+	return img, nil
+}
+
+func (t *tiffPyramid) Levels() []Level { return t.levels }
+
+func (t *tiffPyramid) Describe() string {
+	base := t.images[0]
+	kind := "strips"
+	if base.tiled {
+		kind = fmt.Sprintf("%dx%d tiles", base.tileW, base.tileH)
+	}
+	name := "TIFF"
+	if t.big {
+		name = "BigTIFF"
+	}
+	compression, ok := compressionNames[base.compression]
+	if !ok {
+		compression = fmt.Sprintf("compression %d", base.compression)
+	}
+	return fmt.Sprintf("%s, %s, %s, %d levels", name, compression, kind, len(t.levels))
+}
+
+func (t *tiffPyramid) Close() error { return t.f.Close() }
+
+func (t *tiffPyramid) Tile(level, col, row int) (image.Image, error) {
+	if level < 0 || level >= len(t.images) {
+		return nil, fmt.Errorf("no level %d", level)
+	}
+	img := t.images[level]
+	across := (img.width + img.tileW - 1) / img.tileW
+	if col < 0 || col >= across || row < 0 || row*img.tileH >= img.height {
+		return nil, fmt.Errorf("no tile %d,%d at level %d", col, row, level)
+	}
+	if img.planarSeparate && img.samples > 1 {
+		return nil, errors.New("separate color planes are not supported")
+	}
+	i := row*across + col
+	if img.counts[i] > maxTileBytes {
+		return nil, fmt.Errorf("tile %d,%d: %d bytes", col, row, img.counts[i])
+	}
+	buf := make([]byte, img.counts[i])
+	n, err := t.f.ReadAt(buf, int64(img.offsets[i]))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	buf = buf[:n]
+
+	// Tiles are padded to the full size, the last strip is not
+	w, h := img.tileW, img.tileH
+	if !img.tiled && (row+1)*h > img.height {
+		h = img.height - row*h
+	}
+	if size := img.rowBytes(w) * h; size > maxTileBytes {
+		return nil, fmt.Errorf("tile %d,%d: %d bytes decoded", col, row, size)
+	}
+	if img.compression == compressionJPEG {
+		return img.decodeJPEG(buf)
+	}
+	raw, err := img.decompress(buf, img.rowBytes(w)*h)
+	if err != nil {
+		return nil, fmt.Errorf("tile %d,%d: %w", col, row, err)
+	}
+	return img.pixels(raw, w, h, t.order)
+}
+
+// rowBytes is the size of a row of w pixels
+func (img *tiffImage) rowBytes(w int) int {
+	return (w*img.bits*img.samples + 7) / 8
+}
+
+func (img *tiffImage) decompress(buf []byte, size int) ([]byte, error) {
+	var r io.Reader
+	switch img.compression {
+	case compressionNone:
+		if len(buf) < size {
+			return nil, fmt.Errorf("%d bytes, want %d", len(buf), size)
+		}
+		return buf, nil
+	case compressionPackBits:
+		return unpackBits(buf, size), nil
+	case compressionLZW:
+		lr := lzw.NewReader(bytes.NewReader(buf), lzw.MSB, 8)
+		defer lr.Close()
+		r = lr
+	case compressionDeflate, compressionZlib:
+		zr, err := zlib.NewReader(bytes.NewReader(buf))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("compression %d is not supported", img.compression)
+	}
+	raw := make([]byte, size)
+	n, err := io.ReadFull(r, raw)
+	if err != nil && n < size {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// unpackBits decodes PackBits run lengths up to size bytes
+func unpackBits(buf []byte, size int) []byte {
+	out := make([]byte, 0, size)
+	for i := 0; i < len(buf) && len(out) < size; {
+		n := int(int8(buf[i]))
+		i++
+		switch {
+		case n >= 0:
+			end := i + n + 1
+			if end > len(buf) {
+				end = len(buf)
+			}
+			out = append(out, buf[i:end]...)
+			i = end
+		case n != -128 && i < len(buf):
+			for j := 0; j < 1-n; j++ {
+				out = append(out, buf[i])
+			}
+			i++
+		}
+	}
+	for len(out) < size {
+		out = append(out, 0)
+	}
+	return out[:size]
+}
+
+// decodeJPEG decodes a tile sharing the quantization and Huffman tables of
+// the image, written once in JPEGTables
+func (img *tiffImage) decodeJPEG(buf []byte) (image.Image, error) {
+	if len(img.jpegTables) > 4 && len(buf) > 2 {
+		tables := img.jpegTables[:len(img.jpegTables)-2]
+		buf = append(append(make([]byte, 0, len(tables)+len(buf)), tables...), buf[2:]...)
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	// RGB data without the Adobe marker is taken for YCbCr by the decoder
+	if ycc, ok := decoded.(*image.YCbCr); ok && img.photometric == photometricRGB {
+		return rgbFromYCbCr(ycc), nil
+	}
+	return decoded, nil
+}
+
+func rgbFromYCbCr(ycc *image.YCbCr) image.Image {
+	b := ycc.Bounds()
+	out := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			yi, ci := ycc.YOffset(x, y), ycc.COffset(x, y)
+			out.SetNRGBA(x, y, color.NRGBA{ycc.Y[yi], ycc.Cb[ci], ycc.Cr[ci], 0xff})
+		}
+	}
+	return out
+}
+
+// pixels converts w by h decompressed pixels to an image
+func (img *tiffImage) pixels(raw []byte, w, h int, order binary.ByteOrder) (image.Image, error) {
+	samples := img.samples
+	stride := img.rowBytes(w)
+	if img.predictor == 2 {
+		undoDifferencing(raw, stride, h, samples, img.bits, order)
+	} else if img.predictor != 1 {
+		return nil, fmt.Errorf("predictor %d is not supported", img.predictor)
+	}
+
+	// Samples brought to 8 bits
+	var eight []byte
+	switch {
+	case img.bits == 8 && !img.float:
+		eight = raw
+	case img.bits == 16 && !img.float:
+		eight = make([]byte, w*h*samples)
+		for y := 0; y < h; y++ {
+			for i := 0; i < w*samples; i++ {
+				eight[y*w*samples+i] = byte(order.Uint16(raw[y*stride+i*2:]) >> 8)
+			}
+		}
+	case img.bits == 32 && img.float:
+		// Scores and probabilities, 0 to 1
+		eight = make([]byte, w*h*samples)
+		for y := 0; y < h; y++ {
+			for i := 0; i < w*samples; i++ {
+				v := math.Float32frombits(order.Uint32(raw[y*stride+i*4:]))
+				eight[y*w*samples+i] = byte(math.Max(0, math.Min(1, float64(v))) * 255)
+			}
+		}
+	case img.bits == 1 && samples == 1:
+		eight = make([]byte, w*h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				if raw[y*stride+x/8]&(0x80>>(x%8)) != 0 {
+					eight[y*w+x] = 0xff
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%d bit samples are not supported", img.bits)
+	}
+	if len(eight) < w*h*samples {
+		return nil, errors.New("short tile")
+	}
+
+	switch {
+	case img.photometric == photometricPalette && samples == 1 && img.bits == 8:
+		if len(img.colormap) < 3*256 {
+			return nil, errors.New("missing color map")
+		}
+		out := image.NewNRGBA(image.Rect(0, 0, w, h))
+		for i, v := range eight[:w*h] {
+			c := img.colormap
+			out.Pix[i*4] = byte(c[v] >> 8)
+			out.Pix[i*4+1] = byte(c[256+int(v)] >> 8)
+			out.Pix[i*4+2] = byte(c[512+int(v)] >> 8)
+			out.Pix[i*4+3] = 0xff
+		}
+		return out, nil
+	case samples == 1 || samples == 2:
+		out := image.NewNRGBA(image.Rect(0, 0, w, h))
+		for i := 0; i < w*h; i++ {
+			v := eight[i*samples]
+			if img.photometric == photometricWhiteIsZero {
+				v = 0xff - v
+			}
+			a := byte(0xff)
+			if samples == 2 {
+				a = eight[i*samples+1]
+			}
+			out.Pix[i*4], out.Pix[i*4+1], out.Pix[i*4+2], out.Pix[i*4+3] = v, v, v, a
+		}
+		return out, nil
+	case samples >= 3 && img.photometric == photometricRGB:
+		pix := make([]byte, w*h*4)
+		for i := 0; i < w*h; i++ {
+			copy(pix[i*4:i*4+3], eight[i*samples:i*samples+3])
+			pix[i*4+3] = 0xff
+			if samples >= 4 {
+				pix[i*4+3] = eight[i*samples+3]
+			}
+		}
+		r := image.Rect(0, 0, w, h)
+		if samples >= 4 && img.associated {
+			return &image.RGBA{Pix: pix, Stride: w * 4, Rect: r}, nil
+		}
+		return &image.NRGBA{Pix: pix, Stride: w * 4, Rect: r}, nil
+	}
+	return nil, fmt.Errorf("photometric %d with %d samples is not supported", img.photometric, samples)
+}
+
+// undoDifferencing reverses horizontal differencing, predictor 2
+func undoDifferencing(raw []byte, stride, h, samples, bits int, order binary.ByteOrder) {
+	for y := 0; y < h; y++ {
+		row := raw[y*stride : (y+1)*stride]
+		switch bits {
+		case 8:
+			for i := samples; i < len(row); i++ {
+				row[i] += row[i-samples]
+			}
+		case 16:
+			for i := samples * 2; i+1 < len(row); i += 2 {
+				order.PutUint16(row[i:], order.Uint16(row[i:])+order.Uint16(row[i-samples*2:]))
+			}
+		}
+	}
+}
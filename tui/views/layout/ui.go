@@ -21,9 +21,31 @@
 package layout
 
 import (
+	"context"
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/manyids2/go-tools/tui/components/breadcrumbs"
+	"github.com/manyids2/go-tools/tui/components/chart"
+	"github.com/manyids2/go-tools/tui/components/compareview"
+	"github.com/manyids2/go-tools/tui/components/confusion"
+	"github.com/manyids2/go-tools/tui/components/diffview"
 	"github.com/manyids2/go-tools/tui/components/filebrowser"
+	"github.com/manyids2/go-tools/tui/components/logview"
+	"github.com/manyids2/go-tools/tui/components/preview"
+	"github.com/manyids2/go-tools/tui/components/progress"
+	"github.com/manyids2/go-tools/tui/components/slidetable"
+	"github.com/manyids2/go-tools/tui/components/tilemap"
+	"github.com/manyids2/go-tools/tui/components/tileview"
+	"github.com/manyids2/go-tools/tui/models/logger"
+	"github.com/manyids2/go-tools/tui/models/predictions"
+	"github.com/manyids2/go-tools/tui/models/pyramid"
 	"github.com/rivo/tview"
 )
 
@@ -35,9 +57,66 @@ type UI struct {
 	Status  *breadcrumbs.Breadcrumbs
 	Sidebar *filebrowser.Filebrowser
 	Content *tview.TextArea
+	Logview *logview.Logview
+	Chart   *chart.Chart
+	Stats   *tview.TextView
+	Diff    *diffview.Diffview
+	Compare *compareview.Compareview
+	Slide   *tview.TextView
+	Preview *preview.Preview
+	Tiles   *tileview.Tileview
+	Tilemap *tilemap.Tilemap
+	Pages   *tview.Pages
+
+	// Predictions page, a progress bar above the summary, and every slide
+	// of the loaded predictions in a table
+	Progress    *progress.Progress
+	Predictions *tview.TextView
+	Slides      *slidetable.Slidetable
+
+	// Metrics of the loaded predictions against ground truth, with the
+	// slides of the selected confusion matrix cell
+	Eval       *tview.TextView
+	Confusion  *confusion.Confusion
+	ROC        *chart.Curve
+	PR         *chart.Curve
+	CellSlides *tview.List
 
 	// Basic info
-	Datadir string
+	Datadir   string
+	FileExts  []string
+	Recursive bool
+	App       *tview.Application
+
+	// Run marked with d in the sidebar, compared against the next one
+	DiffBase string
+
+	// Predictions marked with c in the sidebar, compared against the next
+	CompareBase string
+
+	// Stops merging the logs of a directory opened with m
+	cancelMerge context.CancelFunc
+
+	// Logs being summarized; results of earlier loads are dropped
+	loadingStats *logger.Logger
+
+	// First of the runs being compared; results of earlier diffs are dropped
+	loadingDiff *logger.Logger
+
+	// Stops loading predictions
+	cancelLoad context.CancelFunc
+
+	// Predictions being loaded; events of earlier loads are dropped
+	loading *predictions.Predictions
+
+	// Predictions last loaded with p, evaluated with e
+	loaded *predictions.Predictions
+
+	// Evaluations shown on the eval page, overall then per group, and the
+	// predictions they were made on
+	evaluations []predictions.Evaluation
+	evalIndex   int
+	evaluated   *predictions.Predictions
 
 	// Views
 	State  string
@@ -46,7 +125,7 @@ type UI struct {
 
 	// Focused
 	FocusedChild int
-	Children     []*tview.Box
+	Children     []tview.Primitive
 }
 
 func (p *UI) Focus(delegate func(p tview.Primitive)) {
@@ -58,26 +137,137 @@ func (p *UI) Focus(delegate func(p tview.Primitive)) {
 }
 
 func (p *UI) HasFocus() bool {
-	if p.FocusedChild >= 0 {
-		return p.Children[p.FocusedChild].HasFocus()
-	} else {
-		return p.Box.HasFocus()
+	for _, child := range p.Children {
+		if child.HasFocus() {
+			return true
+		}
 	}
+	return p.Box.HasFocus()
 }
 
 func (p *UI) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
 	return p.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		switch event.Key() {
+		// Cycle focus
+		case tcell.KeyTab:
+			p.FocusedChild = (p.FocusedChild + 1) % len(p.Children)
+			setFocus(p.Children[p.FocusedChild])
+			return
+		case tcell.KeyBacktab:
+			p.FocusedChild = (p.FocusedChild + len(p.Children) - 1) % len(p.Children)
+			setFocus(p.Children[p.FocusedChild])
+			return
+		}
+
+		// Sidebar actions on the current node
+		if p.Sidebar.Tree.HasFocus() && event.Key() == tcell.KeyRune {
+			switch event.Rune() {
+			case 'm':
+				p.OpenMerged(p.Sidebar.CurrentPath())
+				return
+			case 'S':
+				p.OpenStats(p.Sidebar.CurrentDir())
+				return
+			case 's':
+				p.OpenSlide(p.Sidebar.CurrentPath())
+				return
+			case 'p':
+				p.OpenPredictions(p.Sidebar.CurrentPath())
+				return
+			case 'P':
+				p.RescanPredictions(p.Sidebar.CurrentPath())
+				return
+			case 'e':
+				p.OpenEval(p.Sidebar.CurrentPath())
+				return
+			case 'c':
+				if p.CompareBase == "" {
+					p.CompareBase = p.Sidebar.CurrentPath()
+					p.Status.Crumbs = append(strings.Split(filepath.Clean(p.CompareBase), string(filepath.Separator)), "compare with ...")
+				} else {
+					p.OpenCompare(p.CompareBase, p.Sidebar.CurrentPath())
+					p.CompareBase = ""
+				}
+				return
+			case 'd':
+				if p.DiffBase == "" {
+					p.DiffBase = p.Sidebar.CurrentPath()
+					p.Status.Crumbs = append(strings.Split(filepath.Clean(p.DiffBase), string(filepath.Separator)), "diff against ...")
+				} else {
+					p.OpenDiff(p.DiffBase, p.Sidebar.CurrentPath())
+					p.DiffBase = ""
+				}
+				return
+			}
+		}
+
+		// Toggle between log records and their metrics
+		if p.Pages.HasFocus() && event.Key() == tcell.KeyRune && event.Rune() == 'c' {
+			switch name, _ := p.Pages.GetFrontPage(); name {
+			case "log":
+				if !p.Logview.Prompting() {
+					p.Pages.SwitchToPage("chart")
+					setFocus(p.Pages)
+					return
+				}
+			case "chart":
+				p.Pages.SwitchToPage("log")
+				setFocus(p.Pages)
+				return
+			}
+		}
+
+		// Toggle between the predictions summary and its slides
+		if p.Pages.HasFocus() && event.Key() == tcell.KeyRune && event.Rune() == 't' {
+			switch name, _ := p.Pages.GetFrontPage(); name {
+			case "predictions":
+				p.Pages.SwitchToPage("slides")
+				setFocus(p.Pages)
+				return
+			case "slides":
+				if !p.Slides.Prompting() {
+					p.Pages.SwitchToPage("predictions")
+					setFocus(p.Pages)
+					return
+				}
+			}
+		}
+
+		// Tile scores of the current slide as a heatmap
+		if p.Pages.HasFocus() && event.Key() == tcell.KeyRune && event.Rune() == 'm' {
+			if name, _ := p.Pages.GetFrontPage(); name == "slides" && !p.Slides.Prompting() {
+				if i, ok := p.Slides.Current(); ok {
+					p.OpenTilemap(p.Slides.Entries[i].Slide)
+					return
+				}
+			}
+		}
+
+		// Cycle between overall and per group evaluations
+		if p.Confusion.HasFocus() && event.Key() == tcell.KeyRune && len(p.evaluations) > 0 {
+			switch event.Rune() {
+			case '[':
+				p.showEvaluation((p.evalIndex + len(p.evaluations) - 1) % len(p.evaluations))
+				return
+			case ']':
+				p.showEvaluation((p.evalIndex + 1) % len(p.evaluations))
+				return
+			}
+		}
+
+		// Stop loading predictions
+		if p.Pages.HasFocus() && event.Key() == tcell.KeyEscape && p.cancelLoad != nil {
+			if name, _ := p.Pages.GetFrontPage(); name == "predictions" {
+				p.cancelLoad()
+				return
+			}
+		}
+
+		// Forward everything else to the focused child
 		if p.FocusedChild >= 0 {
 			if handler := p.Children[p.FocusedChild].InputHandler(); handler != nil {
 				handler(event, setFocus)
 			}
-			return
-		} else {
-			switch event.Key() {
-			// ...handle key events not forwarded to the child primitive...
-			case tcell.KeyTab:
-				p.FocusedChild = (p.FocusedChild + 1) % len(p.Children)
-			}
 		}
 	})
 }
@@ -85,22 +275,709 @@ func (p *UI) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Pr
 func (r *UI) Draw(screen tcell.Screen) {
 	switch r.State {
 	case "without-sidebar":
-		r.DrawForSubclass(screen, r.Views["without-sidebar"])
+		r.Layout = r.Views["without-sidebar"]
 	default:
-		r.DrawForSubclass(screen, r.Views["with-sidebar"])
+		r.Layout = r.Views["with-sidebar"]
+	}
+	r.Layout.SetRect(r.GetRect())
+	r.Layout.Draw(screen)
+}
+
+// OpenFile opens the file in the log viewer, following appended lines
+// unless it is compressed. Images are shown in the preview, TIFF and
+// DeepZoom images a tile at a time, tile scores as a heatmap and other CSVs
+// as text.
+func (r *UI) OpenFile(path string) {
+	r.stopMerge()
+	r.Status.Crumbs = strings.Split(filepath.Clean(path), string(filepath.Separator))
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		if predictions.IsTileCSV(path) {
+			if t, err := predictions.ReadTileScores(path); err == nil {
+				r.showTilemap(t, filepath.Base(filepath.Dir(path)))
+				return
+			}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			r.Content.SetText(err.Error(), false)
+		} else {
+			r.Content.SetText(string(data), false)
+		}
+		r.Pages.SwitchToPage("content")
+		return
+	}
+	if pyramid.IsPyramid(path) {
+		r.OpenPyramid(path)
+		return
+	}
+	if preview.IsImage(path) {
+		r.OpenImage(path)
+		return
+	}
+	r.Logview.ShowFile = false
+
+	if logger.IsCompressed(path) {
+		records, err := logger.ParseFile(path)
+		if err != nil {
+			r.Content.SetText(err.Error(), false)
+			r.Pages.SwitchToPage("content")
+			return
+		}
+		r.Logview.Stop()
+		r.Logview.SetRecords(records)
+		r.Pages.SwitchToPage("log")
+		r.focusChild(r.Pages)
+		return
+	}
+
+	f := logger.NewFileFollower(path)
+	f.FromStart = true
+	r.Logview.Follow(f, r.queueUpdateDraw)
+	r.Pages.SwitchToPage("log")
+	r.focusChild(r.Pages)
+}
+
+// OpenImage shows an image in the preview. Masks and heatmaps are overlaid
+// on the thumbnail next to them, if there is one.
+func (r *UI) OpenImage(path string) {
+	img, err := preview.LoadImage(path)
+	if err != nil {
+		r.Content.SetText(err.Error(), false)
+		r.Pages.SwitchToPage("content")
+		return
+	}
+	r.Preview.SetImage(img)
+
+	thumb := ""
+	switch predictions.ClassifyArtifact(path) {
+	case predictions.KindThumbnail:
+		thumb = path
+	case predictions.KindMask, predictions.KindHeatmap:
+		thumb = siblingThumbnail(path)
+		if thumb == "" {
+			break
+		}
+		base, err := preview.LoadImage(thumb)
+		if err != nil {
+			log.Println("Could not load thumbnail: ", thumb, err)
+			thumb = ""
+			break
+		}
+		r.Preview.SetImage(base).SetOverlay(img)
+		r.Status.Crumbs = append(r.Status.Crumbs, "over "+filepath.Base(thumb))
+	}
+	if thumb != "" {
+		b := r.Preview.Image.Bounds()
+		r.Preview.SetOutlines(annotationOutlines(filepath.Dir(thumb), b.Dx(), b.Dy()))
+	}
+	r.Pages.SwitchToPage("image")
+	r.focusChild(r.Pages)
+}
+
+// OpenPyramid shows a TIFF or DeepZoom image too large to load at once,
+// reading the tiles in view as it is panned and zoomed
+func (r *UI) OpenPyramid(path string) {
+	p, err := pyramid.Open(path)
+	if err != nil {
+		r.Content.SetText(err.Error(), false)
+		r.Pages.SwitchToPage("content")
+		return
+	}
+	r.Tiles.SetPyramid(p, filepath.Base(path))
+	r.Pages.SwitchToPage("tiles")
+	r.focusChild(r.Pages)
+}
+
+// OpenTilemap shows the tile scores of a slide as a heatmap
+func (r *UI) OpenTilemap(s predictions.Slide) {
+	r.Status.Crumbs = append(strings.Split(filepath.Clean(s.Path), string(filepath.Separator)), "tiles")
+	t, err := s.TileScores()
+	if err != nil {
+		r.Content.SetText(err.Error(), false)
+		r.Pages.SwitchToPage("content")
+		return
+	}
+	r.showTilemap(t, s.Group+"/"+s.Name)
+}
+
+func (r *UI) showTilemap(t *predictions.TileScores, name string) {
+	r.Tilemap.SetScores(t, name)
+	r.Pages.SwitchToPage("tilemap")
+	r.focusChild(r.Pages)
+}
+
+// annotationOutlines reads the annotations of the slide in dir and scales
+// them to a thumbnail of width by height pixels. The slide size comes from
+// its summary; without one, annotations within the thumbnail are taken to be
+// in its pixels, others are fitted to the thumbnail's aspect ratio.
+func annotationOutlines(dir string, width, height int) []preview.Outline {
+	layout, err := predictions.FindLayout(dir)
+	if err != nil {
+		layout = predictions.DefaultLayout()
+	}
+	slide, err := layout.LoadSlide(dir, filepath.Base(filepath.Dir(dir)), filepath.Base(dir))
+	if err != nil {
+		return nil
+	}
+	features, err := slide.Annotations()
+	if err != nil {
+		log.Println("Could not read annotations: ", dir, err)
+	}
+	if len(features) == 0 {
+		return nil
+	}
+
+	w, h := float64(width), float64(height)
+	if sw, sh, ok := slide.Dimensions(); ok {
+		w, h = sw, sh
+	} else if maxX, maxY := predictions.FeatureBounds(features); maxX > w || maxY > h {
+		w, h = maxX, maxX*float64(height)/float64(width)
+		if h < maxY {
+			w, h = maxY*float64(width)/float64(height), maxY
+		}
+	}
+	sx, sy := float64(width)/w, float64(height)/h
+
+	classes := make(map[string]int)
+	var outlines []preview.Outline
+	for _, f := range features {
+		if _, ok := classes[f.Class]; !ok {
+			classes[f.Class] = len(classes)
+		}
+		cr, cg, cb := chart.Palette[classes[f.Class]%len(chart.Palette)].RGB()
+		c := color.RGBA{uint8(cr), uint8(cg), uint8(cb), 255}
+		if f.HasColor {
+			c = color.RGBA{f.Color[0], f.Color[1], f.Color[2], 255}
+		}
+		closed := f.Geometry == "Polygon" || f.Geometry == "MultiPolygon" || f.Geometry == "GeometryCollection"
+		for _, path := range f.Paths {
+			o := preview.Outline{Closed: closed && len(path) > 2, Color: c}
+			for _, p := range path {
+				o.Points = append(o.Points, [2]float64{p[0] * sx, p[1] * sy})
+			}
+			outlines = append(outlines, o)
+		}
+	}
+	return outlines
+}
+
+// siblingThumbnail returns the thumbnail in the directory of path
+func siblingThumbnail(path string) string {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		if !e.IsDir() && preview.IsImage(e.Name()) && predictions.ClassifyArtifact(e.Name()) == predictions.KindThumbnail {
+			return filepath.Join(filepath.Dir(path), e.Name())
+		}
+	}
+	return ""
+}
+
+// Records merged before they are shown, or sooner after mergeInterval
+const (
+	mergeBatch    = 1024
+	mergeInterval = 100 * time.Millisecond
+)
+
+// OpenMerged shows the log files of a directory as one timeline, appending
+// records as they are merged in the background
+func (r *UI) OpenMerged(dir string) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return
+	}
+	r.stopMerge()
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancelMerge = cancel
+
+	crumbs := append(strings.Split(filepath.Clean(dir), string(filepath.Separator)), "merged")
+	r.Status.Crumbs = append(crumbs, "loading ...")
+	r.Logview.Stop()
+	r.Logview.ShowFile = true
+	r.Logview.SetRecords(nil)
+	r.Pages.SwitchToPage("log")
+	r.focusChild(r.Pages)
+
+	go func() {
+		m := logger.New(dir, r.FileExts)
+		m.Recursive = r.Recursive
+
+		// Show batches while merging, dropped once another file is opened
+		var batch []logger.Record
+		flushed := time.Now()
+		flush := func() {
+			if len(batch) == 0 {
+				return
+			}
+			records := batch
+			batch = nil
+			flushed = time.Now()
+			r.queueUpdateDraw(func() {
+				if ctx.Err() == nil {
+					r.Logview.Append(records...)
+				}
+			})
+		}
+
+		files, err := m.ListLogFiles()
+		if err == nil {
+			m.LogFiles = files
+			err = m.Merge(func(rec logger.Record) bool {
+				batch = append(batch, rec)
+				if len(batch) >= mergeBatch || time.Since(flushed) >= mergeInterval {
+					flush()
+				}
+				return ctx.Err() == nil
+			})
+		}
+		flush()
+		r.queueUpdateDraw(func() {
+			if ctx.Err() != nil {
+				return
+			}
+			r.Status.Crumbs = crumbs
+			if err != nil {
+				r.Status.Crumbs = append(crumbs, "[red]"+tview.Escape(err.Error()))
+			}
+		})
+	}()
+}
+
+// stopMerge cancels the merge started by OpenMerged, if any
+func (r *UI) stopMerge() {
+	if r.cancelMerge != nil {
+		r.cancelMerge()
+		r.cancelMerge = nil
+	}
+}
+
+// OpenStats summarizes all log files under dir, loading in the background
+func (r *UI) OpenStats(dir string) {
+	r.Status.Crumbs = append(strings.Split(filepath.Clean(dir), string(filepath.Separator)), "stats")
+	r.Stats.SetText("Loading " + dir + " ...")
+	r.Pages.SwitchToPage("stats")
+
+	m := logger.New(dir, r.FileExts)
+	m.Recursive = r.Recursive
+	r.loadingStats = m
+	go func() {
+		go m.SetLogFiles()
+		<-m.Loaded
+		m.SetRecords()
+		m.SetStats(20, 10)
+		r.queueUpdateDraw(func() {
+			if r.loadingStats != m {
+				return
+			}
+			r.loadingStats = nil
+			r.Stats.SetText(m.Stats.String()).ScrollToBeginning()
+		})
+	}()
+}
+
+// OpenDiff compares the logs of two runs side by side, loading in the
+// background
+func (r *UI) OpenDiff(a, b string) {
+	r.Status.Crumbs = []string{filepath.Base(a), "diff", filepath.Base(b)}
+	r.Diff.SetDiff(logger.Diff{A: a + " ...", B: b + " ..."})
+	r.Pages.SwitchToPage("diff")
+	r.focusChild(r.Pages)
+
+	ma, mb := logger.New(a, r.FileExts), logger.New(b, r.FileExts)
+	r.loadingDiff = ma
+	go func() {
+		load := func(m *logger.Logger) {
+			m.Recursive = r.Recursive
+			go m.SetLogFiles()
+			<-m.Loaded
+			m.Merge(func(rec logger.Record) bool {
+				m.Records = append(m.Records, rec)
+				return true
+			})
+		}
+		load(ma)
+		load(mb)
+		extractor, _ := logger.NewExtractor(nil, nil, logger.XIndex)
+		d := logger.DiffRuns(ma, mb, extractor)
+		r.queueUpdateDraw(func() {
+			if r.loadingDiff != ma {
+				return
+			}
+			r.loadingDiff = nil
+			r.Diff.SetDiff(d)
+		})
+	}()
+}
+
+// OpenCompare matches the slides of two prediction runs, loading both in
+// the background
+func (r *UI) OpenCompare(a, b string) {
+	r.Status.Crumbs = []string{filepath.Base(a), "compare", filepath.Base(b)}
+	r.Compare.SetComparison(predictions.Comparison{A: a, B: b})
+	r.Compare.SetTitle(" loading ... ")
+	r.Pages.SwitchToPage("compare")
+	r.focusChild(r.Pages)
+
+	go func() {
+		load := func(dir string) *predictions.Predictions {
+			m := predictions.NewPredictions(dir)
+			if layout, err := predictions.FindLayout(dir); err != nil {
+				log.Println("Could not read layout: ", dir, err)
+			} else {
+				m.Layout = layout
+			}
+			for e := range m.Load(context.Background(), 0) {
+				if e.Err != nil {
+					log.Println("Could not load predictions: ", dir, e.Err)
+				}
+			}
+			return m
+		}
+		ma, mb := load(a), load(b)
+		c := predictions.Compare(ma, mb, predictions.DefaultAgreement)
+		r.queueUpdateDraw(func() {
+			r.Compare.SetComparison(c)
+			r.Compare.SetSelectedFunc(func(s predictions.SlideChange) {
+				if s.InB {
+					r.OpenSlide(mb.SlidePath(s.Group, s.Slide))
+				} else {
+					r.OpenSlide(ma.SlidePath(s.Group, s.Slide))
+				}
+			})
+		})
+	}()
+}
+
+// OpenSlide lists the artifacts of a slide directory, marking expected
+// kinds that are missing
+func (r *UI) OpenSlide(dir string) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return
+	}
+	dir = filepath.Clean(dir)
+	r.Status.Crumbs = append(strings.Split(dir, string(filepath.Separator)), "slide")
+
+	var b strings.Builder
+	layout, err := predictions.FindLayout(dir)
+	if err != nil {
+		fmt.Fprintf(&b, "[red]%s[-]\n\n", tview.Escape(err.Error()))
+		layout = predictions.DefaultLayout()
+	}
+	slide, err := layout.LoadSlide(dir, filepath.Base(filepath.Dir(dir)), filepath.Base(dir))
+	fmt.Fprintf(&b, "[::b]%s/%s[::-]\n\n", tview.Escape(slide.Group), tview.Escape(slide.Name))
+	if err != nil {
+		fmt.Fprintf(&b, "[red]%s[-]\n\n", tview.Escape(err.Error()))
+	}
+	for _, k := range slide.Kinds() {
+		artifacts := slide.ByKind(k)
+		if len(artifacts) == 0 && k != predictions.KindOther {
+			fmt.Fprintf(&b, "[red]%-11s missing[-]\n", k)
+		}
+		for _, a := range artifacts {
+			fmt.Fprintf(&b, "[green]%-11s[-] %10d  %s  %s\n", k, a.Size, a.ModTime.Format("2006-01-02 15:04:05"), tview.Escape(a.Name))
+		}
+	}
+
+	// Counts and areas of annotated classes
+	features, err := slide.Annotations()
+	if err != nil {
+		fmt.Fprintf(&b, "\n[red]%s[-]\n", tview.Escape(err.Error()))
+	}
+	if len(features) > 0 {
+		fmt.Fprintf(&b, "\n[::b]Annotations[::-]  %d features\n", len(features))
+		for _, c := range predictions.SummarizeFeatures(features) {
+			fmt.Fprintf(&b, "  %-20s %6d  %14.1f px²\n", tview.Escape(c.Class), c.Count, c.Area)
+		}
+	}
+	// Tile score aggregates per class
+	if slide.Has(predictions.KindTiles) {
+		if t, err := slide.TileScores(); err != nil {
+			fmt.Fprintf(&b, "\n[red]%s[-]\n", tview.Escape(err.Error()))
+		} else {
+			fmt.Fprintf(&b, "\n[::b]Tiles[::-]  %d tiles, top-%d, threshold %g\n", len(t.Tiles),
+				predictions.DefaultTopK, predictions.DefaultTileThreshold)
+			for _, a := range t.Aggregates(predictions.DefaultTopK, predictions.DefaultTileThreshold) {
+				fmt.Fprintf(&b, "  %-20s mean %.3f  max %.3f  top-k %.3f  %5.1f%% above\n",
+					tview.Escape(a.Class), a.Mean, a.Max, a.TopK, 100*a.Above)
+			}
+		}
+	}
+	r.Slide.SetText(b.String()).ScrollToBeginning()
+	r.Pages.SwitchToPage("slide")
+	r.focusChild(r.Pages)
+}
+
+// OpenPredictions loads the groups and slides of dir in the background,
+// showing progress as groups finish. Esc stops loading.
+func (r *UI) OpenPredictions(dir string) {
+	r.loadPredictions(dir, false)
+}
+
+// RescanPredictions is OpenPredictions ignoring the cached manifest
+func (r *UI) RescanPredictions(dir string) {
+	r.loadPredictions(dir, true)
+}
+
+func (r *UI) loadPredictions(dir string, rescan bool) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return
+	}
+	if r.cancelLoad != nil {
+		r.cancelLoad()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancelLoad = cancel
+
+	r.Status.Crumbs = append(strings.Split(filepath.Clean(dir), string(filepath.Separator)), "predictions")
+	r.Progress.SetLabel("Loading").SetProgress(0, 0).SetMessage("")
+	r.Predictions.SetText("")
+	r.Pages.SwitchToPage("predictions")
+	r.focusChild(r.Pages)
+
+	m := predictions.NewPredictions(dir)
+	layout, err := predictions.FindLayout(dir)
+	if err != nil {
+		r.Progress.SetMessage(err.Error())
+	} else {
+		m.Layout = layout
+	}
+	m.Rescan = rescan
+	r.loading, r.loaded = m, nil
+	r.Slides.SetEntries(nil, nil)
+	events := m.Load(ctx, 0)
+	go func() {
+		defer cancel()
+		for e := range events {
+			e := e
+
+			// Labels are read from the summaries off the UI thread
+			var entries []slidetable.Entry
+			if e.Kind == predictions.EventDone {
+				entries = slidetable.Entries(m)
+			}
+			r.queueUpdateDraw(func() {
+				if r.loading != m {
+					return
+				}
+				r.Progress.SetProgress(e.Done, e.Total)
+				switch e.Kind {
+				case predictions.EventGroup:
+					line := fmt.Sprintf("%s\n", tview.Escape(e.Group))
+					if e.Err != nil {
+						line = fmt.Sprintf("[red]%s: %s[-]\n", tview.Escape(e.Group), tview.Escape(e.Err.Error()))
+					}
+					fmt.Fprint(r.Predictions, line)
+				case predictions.EventDone:
+					r.loaded = m
+					r.Progress.SetLabel("Loaded")
+					if m.Reused > 0 {
+						r.Progress.SetLabel(fmt.Sprintf("Loaded, %d cached", m.Reused))
+					}
+					r.Predictions.SetText(predictionsSummary(m)).ScrollToBeginning()
+					r.Slides.SetEntries(m, entries)
+				case predictions.EventError:
+					r.Progress.SetLabel("Stopped").SetMessage(e.Err.Error())
+				}
+			})
+		}
+	}()
+}
+
+// predictionsSummary lists groups with their incomplete slides
+func predictionsSummary(m *predictions.Predictions) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[::b]%s[::-]  %d groups\n", tview.Escape(m.Datadir), len(m.Groups))
+	for _, name := range m.GroupNames() {
+		g := m.Groups[name]
+		incomplete := g.Incomplete()
+		color := "green"
+		if len(incomplete) > 0 {
+			color = "yellow"
+		}
+		fmt.Fprintf(&b, "\n[%s]%s[-]  %d slides, %d incomplete\n", color, tview.Escape(name), len(g.Slides), len(incomplete))
+		for _, s := range incomplete {
+			fmt.Fprintf(&b, "  %s  [red]missing %s[-]\n", tview.Escape(s.Name), tview.Escape(fmt.Sprint(s.Missing())))
+		}
+	}
+	return b.String()
+}
+
+// OpenEval compares the predictions loaded with p against a labels CSV or
+// ground-truth directory, evaluating in the background
+func (r *UI) OpenEval(truth string) {
+	m := r.loaded
+	r.evaluations, r.evaluated = nil, nil
+	r.Confusion.SetMatrix(nil, nil)
+	r.ROC.SetLines()
+	r.PR.SetLines()
+	r.CellSlides.Clear()
+	r.Pages.SwitchToPage("eval")
+	r.focusChild(r.Pages)
+	if m == nil {
+		r.Eval.SetText("[yellow]Load predictions with p first, then press e on a labels CSV or ground-truth directory[-]")
+		return
+	}
+	r.Status.Crumbs = []string{filepath.Base(m.Datadir), "eval", filepath.Base(truth)}
+	r.Eval.SetText("Evaluating against " + tview.Escape(truth) + " ...")
+
+	go func() {
+		gt, err := predictions.LoadGroundTruth(truth)
+		if err != nil {
+			r.queueUpdateDraw(func() {
+				r.Eval.SetText("[red]" + tview.Escape(err.Error()) + "[-]")
+			})
+			return
+		}
+		report := predictions.Evaluate(m, gt, "")
+		r.queueUpdateDraw(func() {
+			r.evaluations = append([]predictions.Evaluation{report.Overall}, report.Groups...)
+			r.evaluated = m
+			r.showEvaluation(0)
+			if r.App != nil {
+				r.App.SetFocus(r.Confusion)
+			}
+		})
+	}()
+}
+
+// showEvaluation fills the eval page with one of evaluations
+func (r *UI) showEvaluation(i int) {
+	r.evalIndex = i
+	e := r.evaluations[i]
+	r.Confusion.SetMatrix(e.Matrix.Classes, e.Matrix.Counts)
+	r.Confusion.SetTitle(fmt.Sprintf(" %s [%d/%d] ", e.Name, i+1, len(r.evaluations)))
+
+	var roc, pr chart.Line
+	roc.Name, pr.Name = "positive "+e.Positive, "positive "+e.Positive
+	for _, p := range e.ROC {
+		roc.X, roc.Y = append(roc.X, p.X), append(roc.Y, p.Y)
+	}
+	for _, p := range e.PR {
+		pr.X, pr.Y = append(pr.X, p.X), append(pr.Y, p.Y)
+	}
+	title := "ROC"
+	if e.HasAUC {
+		title = fmt.Sprintf("ROC  AUC %.3f", e.AUC)
+	}
+	r.ROC.SetLabels(title, "FPR", "TPR").SetLines(roc)
+	r.PR.SetLabels("PR", "recall", "prec").SetLines(pr)
+
+	r.Eval.SetText(tview.Escape(e.String())).ScrollToBeginning()
+	if row, col, ok := r.Confusion.Current(); ok {
+		r.listCell(r.evaluated, row, col)
+	}
+}
+
+// listCell lists the slides of m with true class row and predicted class
+// col
+func (r *UI) listCell(m *predictions.Predictions, row, col string) {
+	r.CellSlides.Clear()
+	r.CellSlides.SetTitle(fmt.Sprintf(" true %s, predicted %s ", row, col))
+	if m == nil || r.evalIndex >= len(r.evaluations) {
+		return
+	}
+	e := r.evaluations[r.evalIndex]
+	for _, s := range e.Slides {
+		if s.True != row || s.Predicted != col {
+			continue
+		}
+		text := s.Group + "/" + s.Slide
+		if s.HasScore {
+			text += fmt.Sprintf("  %.3f", s.Score)
+		}
+		path := m.SlidePath(s.Group, s.Slide)
+		r.CellSlides.AddItem(tview.Escape(text), "", 0, func() {
+			r.OpenSlide(path)
+		})
+	}
+}
+
+// focusChild moves focus to one of Children
+func (r *UI) focusChild(child tview.Primitive) {
+	for i, c := range r.Children {
+		if c == child {
+			r.FocusedChild = i
+			if r.App != nil {
+				r.App.SetFocus(child)
+			}
+			return
+		}
+	}
+}
+
+func (r *UI) queueUpdateDraw(f func()) {
+	if r.App != nil {
+		r.App.QueueUpdateDraw(f)
 	}
 }
 
 func NewUI(datadir string) *UI {
 	ui := UI{
+		Grid:         tview.NewGrid(),
 		Datadir:      datadir,
+		FileExts:     []string{".log"},
+		Recursive:    true,
 		Status:       breadcrumbs.NewBreadcrumbs([]string{"hi", "hello"}),
 		Sidebar:      filebrowser.NewFilebrowser(datadir),
 		Content:      tview.NewTextArea(),
+		Logview:      logview.NewLogview(),
+		Chart:        chart.NewChart(),
+		Stats:        tview.NewTextView(),
+		Diff:         diffview.NewDiffview(),
+		Compare:      compareview.NewCompareview(),
+		Slide:        tview.NewTextView(),
+		Preview:      preview.NewPreview(),
+		Tiles:        tileview.NewTileview(),
+		Tilemap:      tilemap.NewTilemap(),
+		Progress:     progress.NewProgress(),
+		Predictions:  tview.NewTextView(),
+		Slides:       slidetable.NewSlidetable(),
+		Eval:         tview.NewTextView(),
+		Confusion:    confusion.NewConfusion(),
+		ROC:          chart.NewCurve().SetDiagonal(true),
+		PR:           chart.NewCurve(),
+		CellSlides:   tview.NewList(),
+		Pages:        tview.NewPages(),
 		FocusedChild: 0,
 		State:        "with-sidebar",
 	}
 
+	ui.Pages.AddPage("content", ui.Content, true, true).
+		AddPage("log", ui.Logview, true, false).
+		AddPage("chart", ui.Chart, true, false).
+		AddPage("stats", ui.Stats, true, false).
+		AddPage("diff", ui.Diff, true, false).
+		AddPage("compare", ui.Compare, true, false).
+		AddPage("slide", ui.Slide, true, false).
+		AddPage("image", ui.Preview, true, false).
+		AddPage("tiles", ui.Tiles, true, false).
+		AddPage("tilemap", ui.Tilemap, true, false).
+		AddPage("predictions", tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(ui.Progress, 1, 0, false).
+			AddItem(ui.Predictions, 0, 1, true), true, false).
+		AddPage("slides", ui.Slides, true, false).
+		AddPage("eval", tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(tview.NewFlex().
+				AddItem(ui.Confusion, 0, 1, true).
+				AddItem(ui.ROC, 0, 1, false).
+				AddItem(ui.PR, 0, 1, false), 0, 1, true).
+			AddItem(tview.NewFlex().
+				AddItem(ui.Eval, 0, 2, false).
+				AddItem(ui.CellSlides, 0, 1, false), 0, 1, false), true, false)
+
+	// Metrics of the records in the log viewer, re-extracted as they grow
+	extractor, _ := logger.NewExtractor(nil, nil, logger.XIndex)
+	var extracted []logger.Series
+	count := -1
+	ui.Chart.Source = func() []logger.Series {
+		if len(ui.Logview.Records) != count {
+			count = len(ui.Logview.Records)
+			extracted = extractor.Series(ui.Logview.Records)
+		}
+		return extracted
+	}
+
 	// AddItem(p Primitive, row, column, rowSpan, colSpan, minGridHeight, minGridWidth int, focus bool)
 	ui.Views = make(map[string]*tview.Grid, 2)
 
@@ -110,7 +987,7 @@ func NewUI(datadir string) *UI {
 		SetColumns(0).
 		SetBorders(false)
 	LayoutWithoutSidebar.AddItem(ui.Status, 0, 0, 1, 1, 0, 0, false).
-		AddItem(ui.Content, 1, 0, 1, 1, 0, 0, false)
+		AddItem(ui.Pages, 1, 0, 1, 1, 0, 0, false)
 	ui.Views["without-sidebar"] = LayoutWithoutSidebar
 
 	// With sidebar
@@ -120,13 +997,58 @@ func NewUI(datadir string) *UI {
 		SetBorders(false)
 	LayoutWithSidebar.AddItem(ui.Status, 0, 1, 1, 1, 0, 0, false).
 		AddItem(ui.Sidebar.Tree, 0, 0, 2, 1, 0, 0, false).
-		AddItem(ui.Content, 1, 1, 1, 1, 0, 0, false)
+		AddItem(ui.Pages, 1, 1, 1, 1, 0, 0, false)
 	ui.Views["with-sidebar"] = LayoutWithSidebar
 
-	ui.Children = []*tview.Box{
-		ui.Sidebar.SetBorder(false),
-		ui.Status.SetBorder(false),
-		ui.Content.SetBorder(false),
+	ui.Sidebar.SetFileSelectedFunc(ui.OpenFile)
+	ui.Sidebar.SetRootSelectedFunc(ui.OpenStats)
+
+	ui.Sidebar.Tree.SetBorder(false)
+	ui.Status.SetBorder(false)
+	ui.Content.SetBorder(false)
+	ui.Logview.SetBorder(false)
+	ui.Chart.SetBorder(false)
+	ui.Stats.SetScrollable(true).SetBorder(false)
+	ui.Diff.SetBorder(false)
+	ui.Compare.SetBorder(true)
+	ui.Preview.SetBorder(false)
+	ui.Tiles.SetBorder(false)
+	ui.Tilemap.SetBorder(false)
+	ui.Slide.SetDynamicColors(true).SetScrollable(true).SetBorder(false)
+	ui.Predictions.SetDynamicColors(true).SetScrollable(true).SetBorder(false)
+	ui.Slides.SetBorder(true)
+	ui.Eval.SetDynamicColors(true).SetScrollable(true).SetWrap(false).SetBorder(false)
+	ui.Confusion.SetBorder(true)
+	ui.CellSlides.ShowSecondaryText(false).SetBorder(true)
+
+	// Draw again as the tiles in view load
+	ui.Tiles.SetChangedFunc(func() {
+		ui.queueUpdateDraw(func() {})
+	})
+
+	// Enter on a row of the slides table opens the slide
+	ui.Slides.SetSelectedFunc(func(e slidetable.Entry) {
+		ui.OpenSlide(e.Path)
+	})
+
+	// Slides of a confusion matrix cell, enter opens one, esc goes back
+	ui.Confusion.SetChangedFunc(func(row, col string) {
+		ui.listCell(ui.evaluated, row, col)
+	})
+	ui.Confusion.SetSelectedFunc(func(row, col string) {
+		if ui.CellSlides.GetItemCount() > 0 && ui.App != nil {
+			ui.App.SetFocus(ui.CellSlides)
+		}
+	})
+	ui.CellSlides.SetDoneFunc(func() {
+		if ui.App != nil {
+			ui.App.SetFocus(ui.Confusion)
+		}
+	})
+	ui.Children = []tview.Primitive{
+		ui.Sidebar.Tree,
+		ui.Status,
+		ui.Pages,
 	}
 
 	return &ui